@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/ln80/privacy-engine/core"
@@ -13,6 +14,9 @@ import (
 
 const (
 	aES265KeySize = 32
+
+	// ID256GCM is the algorithm identifier of the 'AES 256 GCM' Encryptor.
+	ID256GCM = "A256GCM"
 )
 
 func Key256GenFn(ctx context.Context, namespace, subID string) (string, error) {
@@ -26,6 +30,8 @@ func Key256GenFn(ctx context.Context, namespace, subID string) (string, error) {
 type aes256gcm struct{}
 
 var _ core.Encryptor = &aes256gcm{}
+var _ core.IdentifiedEncryptor = &aes256gcm{}
+var _ core.AADEncryptor = &aes256gcm{}
 
 func New256GCMEncryptor() core.Encryptor {
 	return &aes256gcm{}
@@ -35,14 +41,39 @@ func (e *aes256gcm) KeyGen() core.KeyGen {
 	return Key256GenFn
 }
 
-func prepareAdditionalData(namespace string) []byte {
-	if namespace == "" {
-		return nil
+// ID implements core.IdentifiedEncryptor
+func (e *aes256gcm) ID() string {
+	return ID256GCM
+}
+
+// prepareAdditionalData returns the additional data authenticated along with the cipher text.
+// Values are length-prefixed to avoid ambiguity, except the namespace to remain compatible
+// with cipher texts encrypted without AAD.
+func prepareAdditionalData(namespace string, ad core.AAD) []byte {
+	var data []byte
+	if namespace != "" {
+		data = append([]byte("ns:"), []byte(namespace)...)
+	}
+	if ad.IsZero() {
+		return data
 	}
-	return append([]byte("ns:"), []byte(namespace)...)
+	data = fmt.Appendf(data, "|sub:%d:%s", len(ad.SubjectID), ad.SubjectID)
+	if ad.Field != "" {
+		data = fmt.Appendf(data, "|field:%d:%s", len(ad.Field), ad.Field)
+	}
+	return data
 }
 
 func (e *aes256gcm) Encrypt(namespace string, key core.Key, plainTxt string) (cipherTxt []byte, err error) {
+	return e.EncryptWithAAD(namespace, key, plainTxt, core.AAD{})
+}
+
+func (e *aes256gcm) Decrypt(namespace string, key core.Key, cipherTxt []byte) (plainTxt string, err error) {
+	return e.DecryptWithAAD(namespace, key, cipherTxt, core.AAD{})
+}
+
+// EncryptWithAAD implements core.AADEncryptor
+func (e *aes256gcm) EncryptWithAAD(namespace string, key core.Key, plainTxt string, ad core.AAD) (cipherTxt []byte, err error) {
 	defer func() {
 		if err != nil {
 			err = errors.Join(core.ErrEncryptionFailure, err)
@@ -64,7 +95,7 @@ func (e *aes256gcm) Encrypt(namespace string, key core.Key, plainTxt string) (ci
 	if err != nil {
 		return
 	}
-	aad := prepareAdditionalData(namespace)
+	aad := prepareAdditionalData(namespace, ad)
 	cTxt, err := aesgcm.Seal(nil, nonce, []byte(plainTxt), aad), nil
 	if err != nil {
 		return
@@ -75,7 +106,8 @@ func (e *aes256gcm) Encrypt(namespace string, key core.Key, plainTxt string) (ci
 	return cTxt, nil
 }
 
-func (e *aes256gcm) Decrypt(namespace string, key core.Key, cipherTxt []byte) (plainTxt string, err error) {
+// DecryptWithAAD implements core.AADEncryptor
+func (e *aes256gcm) DecryptWithAAD(namespace string, key core.Key, cipherTxt []byte, ad core.AAD) (plainTxt string, err error) {
 	defer func() {
 		if err != nil {
 			err = errors.Join(core.ErrDecryptionFailure, err)
@@ -92,7 +124,12 @@ func (e *aes256gcm) Decrypt(namespace string, key core.Key, cipherTxt []byte) (p
 		return
 	}
 
-	aad := prepareAdditionalData(namespace)
+	if len(cipherTxt) < aesgcm.NonceSize() {
+		err = errors.New("cipher text too short")
+		return
+	}
+
+	aad := prepareAdditionalData(namespace, ad)
 	plnTxt, err := aesgcm.Open(nil, cipherTxt[:aesgcm.NonceSize()], cipherTxt[aesgcm.NonceSize():], aad) // #nosec G407
 	if err != nil {
 		return
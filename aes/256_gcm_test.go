@@ -0,0 +1,66 @@
+package aes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+func TestAES256GCM_AAD(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-g4c0d1"
+
+	enc := New256GCMEncryptor().(core.AADEncryptor)
+
+	k, err := enc.KeyGen()(ctx, nspace, "sub-1")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	key := core.Key(k)
+
+	t.Run("empty aad is equivalent to no aad", func(t *testing.T) {
+		cipherTxt, err := enc.Encrypt(nspace, key, "Idir Moore")
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		plainTxt, err := enc.DecryptWithAAD(nspace, key, cipherTxt, core.AAD{})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := "Idir Moore", plainTxt; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("cipher text is bound to aad", func(t *testing.T) {
+		ad := core.AAD{SubjectID: "sub-1", Field: "Fullname"}
+		cipherTxt, err := enc.EncryptWithAAD(nspace, key, "Idir Moore", ad)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		plainTxt, err := enc.DecryptWithAAD(nspace, key, cipherTxt, ad)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := "Idir Moore", plainTxt; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		for _, other := range []core.AAD{
+			{},
+			{SubjectID: "sub-1"},
+			{SubjectID: "sub-2", Field: "Fullname"},
+			{SubjectID: "sub-1", Field: "Gender"},
+			// length-prefixed values prevent ambiguous concatenations
+			{SubjectID: "sub-1|field:8:Fullname"},
+		} {
+			if _, err := enc.DecryptWithAAD(nspace, key, cipherTxt, other); !errors.Is(err, core.ErrDecryptionFailure) {
+				t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+const (
+	// ID256GCMSIV is the algorithm identifier of the 'AES 256 GCM SIV' Encryptor.
+	ID256GCMSIV = "A256GCMSIV"
+
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+)
+
+var (
+	ErrGCMSIVIntegrityCheck = errors.New("AES-GCM-SIV integrity check failure")
+)
+
+type aes256gcmsiv struct{}
+
+var _ core.Encryptor = &aes256gcmsiv{}
+var _ core.IdentifiedEncryptor = &aes256gcmsiv{}
+var _ core.AADEncryptor = &aes256gcmsiv{}
+
+// New256GCMSIVEncryptor returns a nonce-misuse-resistant Encryptor based on AES-256-GCM-SIV as defined by RFC 8452.
+// Unlike 'AES 256 GCM', a repeated nonce only reveals whether the same value was encrypted,
+// which removes the birthday-bound risk of random nonces for subjects with a high write volume.
+//
+// It uses the same 256 bits keys as the 'AES 256 GCM' Encryptor.
+func New256GCMSIVEncryptor() core.Encryptor {
+	return &aes256gcmsiv{}
+}
+
+// KeyGen implements core.Encryptor
+func (e *aes256gcmsiv) KeyGen() core.KeyGen {
+	return Key256GenFn
+}
+
+// ID implements core.IdentifiedEncryptor
+func (e *aes256gcmsiv) ID() string {
+	return ID256GCMSIV
+}
+
+// Encrypt implements core.Encryptor
+func (e *aes256gcmsiv) Encrypt(namespace string, key core.Key, plainTxt string) (cipherTxt []byte, err error) {
+	return e.EncryptWithAAD(namespace, key, plainTxt, core.AAD{})
+}
+
+// Decrypt implements core.Encryptor
+func (e *aes256gcmsiv) Decrypt(namespace string, key core.Key, cipherTxt []byte) (plainTxt string, err error) {
+	return e.DecryptWithAAD(namespace, key, cipherTxt, core.AAD{})
+}
+
+// EncryptWithAAD implements core.AADEncryptor
+func (e *aes256gcmsiv) EncryptWithAAD(namespace string, key core.Key, plainTxt string, ad core.AAD) (cipherTxt []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrEncryptionFailure, err)
+		}
+	}()
+
+	nonce := make([]byte, gcmSIVNonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return
+	}
+
+	cTxt, err := SealGCMSIV([]byte(key), nonce, []byte(plainTxt), prepareAdditionalData(namespace, ad))
+	if err != nil {
+		return
+	}
+
+	return append(nonce, cTxt...), nil
+}
+
+// DecryptWithAAD implements core.AADEncryptor
+func (e *aes256gcmsiv) DecryptWithAAD(namespace string, key core.Key, cipherTxt []byte, ad core.AAD) (plainTxt string, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDecryptionFailure, err)
+		}
+	}()
+
+	if len(cipherTxt) < gcmSIVNonceSize {
+		err = errors.New("cipher text too short")
+		return
+	}
+
+	plnTxt, err := OpenGCMSIV([]byte(key), cipherTxt[:gcmSIVNonceSize], cipherTxt[gcmSIVNonceSize:], prepareAdditionalData(namespace, ad))
+	if err != nil {
+		return
+	}
+
+	return string(plnTxt), nil
+}
+
+// SealGCMSIV encrypts and authenticates the given plain text along with the given additional data
+// using AES-256-GCM-SIV as defined by RFC 8452. It returns the cipher text followed by the tag.
+//
+// The key size must be 32 bytes, and the nonce size 12 bytes.
+func SealGCMSIV(key, nonce, plainTxt, ad []byte) ([]byte, error) {
+	authKey, encBlock, err := gcmSIVKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := gcmSIVTag(authKey, encBlock, nonce, plainTxt, ad)
+
+	out := make([]byte, len(plainTxt), len(plainTxt)+gcmSIVTagSize)
+	gcmSIVCTR(encBlock, tag, out, plainTxt)
+
+	return append(out, tag...), nil
+}
+
+// OpenGCMSIV decrypts and verifies the given cipher text, sealed by SealGCMSIV, along with the given additional data.
+func OpenGCMSIV(key, nonce, cipherTxt, ad []byte) ([]byte, error) {
+	if len(cipherTxt) < gcmSIVTagSize {
+		return nil, errors.New("cipher text too short")
+	}
+	authKey, encBlock, err := gcmSIVKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := cipherTxt[len(cipherTxt)-gcmSIVTagSize:]
+	plainTxt := make([]byte, len(cipherTxt)-gcmSIVTagSize)
+	gcmSIVCTR(encBlock, tag, plainTxt, cipherTxt[:len(plainTxt)])
+
+	if subtle.ConstantTimeCompare(tag, gcmSIVTag(authKey, encBlock, nonce, plainTxt, ad)) != 1 {
+		return nil, ErrGCMSIVIntegrityCheck
+	}
+	return plainTxt, nil
+}
+
+// gcmSIVKeys derives the per-nonce message authentication and encryption keys, see RFC 8452 section 4.
+func gcmSIVKeys(key, nonce []byte) (authKey []byte, encBlock cipher.Block, err error) {
+	if len(key) != aES265KeySize {
+		return nil, nil, errors.New("invalid AES-GCM-SIV key size")
+	}
+	if len(nonce) != gcmSIVNonceSize {
+		return nil, nil, errors.New("invalid AES-GCM-SIV nonce size")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+
+	derived := make([]byte, 0, 48)
+	in, out := make([]byte, aes.BlockSize), make([]byte, aes.BlockSize)
+	copy(in[4:], nonce)
+	for i := uint32(0); i < 6; i++ {
+		binary.LittleEndian.PutUint32(in, i)
+		block.Encrypt(out, in)
+		derived = append(derived, out[:8]...)
+	}
+
+	encBlock, err = aes.NewCipher(derived[16:])
+	return derived[:16], encBlock, err
+}
+
+// gcmSIVTag computes the tag of the given plain text and additional data.
+func gcmSIVTag(authKey []byte, encBlock cipher.Block, nonce, plainTxt, ad []byte) []byte {
+	lengths := make([]byte, aes.BlockSize)
+	binary.LittleEndian.PutUint64(lengths, uint64(len(ad))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plainTxt))*8)
+
+	p := newPolyval(authKey)
+	p.update(ad)
+	p.update(plainTxt)
+	p.update(lengths)
+
+	s := p.sum()
+	subtle.XORBytes(s[:gcmSIVNonceSize], s[:gcmSIVNonceSize], nonce)
+	s[15] &= 0x7f
+
+	encBlock.Encrypt(s, s)
+	return s
+}
+
+// gcmSIVCTR applies the AES-GCM-SIV counter mode, whose counter is the little-endian first 32 bits of the block.
+func gcmSIVCTR(encBlock cipher.Block, tag, dst, src []byte) {
+	counter := make([]byte, aes.BlockSize)
+	copy(counter, tag)
+	counter[15] |= 0x80
+
+	keyStream := make([]byte, aes.BlockSize)
+	for i := 0; i < len(src); i += aes.BlockSize {
+		encBlock.Encrypt(keyStream, counter)
+		end := min(i+aes.BlockSize, len(src))
+		subtle.XORBytes(dst[i:end], src[i:end], keyStream)
+		binary.LittleEndian.PutUint32(counter, binary.LittleEndian.Uint32(counter)+1)
+	}
+}
+
+// polyval implements the POLYVAL universal hash function defined by RFC 8452, section 3.
+type polyval struct {
+	hLo, hHi uint64
+	sLo, sHi uint64
+}
+
+func newPolyval(h []byte) *polyval {
+	return &polyval{
+		hLo: binary.LittleEndian.Uint64(h),
+		hHi: binary.LittleEndian.Uint64(h[8:]),
+	}
+}
+
+// update hashes the given data, padded with zeros to a multiple of the block size.
+func (p *polyval) update(data []byte) {
+	block := make([]byte, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		clear(block)
+		copy(block, data[i:])
+		p.sLo ^= binary.LittleEndian.Uint64(block)
+		p.sHi ^= binary.LittleEndian.Uint64(block[8:])
+		p.sLo, p.sHi = polyvalDot(p.sLo, p.sHi, p.hLo, p.hHi)
+	}
+}
+
+func (p *polyval) sum() []byte {
+	out := make([]byte, aes.BlockSize)
+	binary.LittleEndian.PutUint64(out, p.sLo)
+	binary.LittleEndian.PutUint64(out[8:], p.sHi)
+	return out
+}
+
+// polyvalDot returns a*b*x^-128 in GF(2^128) defined by x^128 + x^127 + x^126 + x^121 + 1,
+// using a constant-time shift-and-add multiplication.
+func polyvalDot(aLo, aHi, bLo, bHi uint64) (rLo, rHi uint64) {
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = (bLo >> i) & 1
+		} else {
+			bit = (bHi >> (i - 64)) & 1
+		}
+		mask := -bit
+		rLo ^= aLo & mask
+		rHi ^= aHi & mask
+
+		// divide by x: add the polynomial if the constant term is set, then shift right
+		mask = -(rLo & 1)
+		rLo ^= 1 & mask
+		rHi ^= (1<<57 | 1<<62 | 1<<63) & mask
+		rLo = rLo>>1 | rHi<<63
+		rHi = rHi>>1 | (1<<63)&mask
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package aes
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+func TestGCMSIV(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	t.Run("polyval", func(t *testing.T) {
+		// test vector from RFC 8452, appendix A
+		p := newPolyval(unhex("25629347589242761d31f826ba4b757b"))
+		p.update(unhex("4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362"))
+		if want, got := unhex("f7a3b47b846119fae5b7866cf5e5b77e"), p.sum(); !bytes.Equal(want, got) {
+			t.Fatalf("expect %x, %x be equals", want, got)
+		}
+	})
+
+	t.Run("seal and open", func(t *testing.T) {
+		// test vectors from RFC 8452, appendix C.2
+		tcs := []struct {
+			key, nonce, plainTxt, ad, result string
+		}{
+			{
+				key:      "0100000000000000000000000000000000000000000000000000000000000000",
+				nonce:    "030000000000000000000000",
+				plainTxt: "",
+				result:   "07f5f4169bbf55a8400cd47ea6fd400f",
+			},
+			{
+				key:      "0100000000000000000000000000000000000000000000000000000000000000",
+				nonce:    "030000000000000000000000",
+				plainTxt: "0100000000000000",
+				result:   "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28",
+			},
+		}
+
+		for _, tc := range tcs {
+			key, nonce, plainTxt, ad, want := unhex(tc.key), unhex(tc.nonce), unhex(tc.plainTxt), unhex(tc.ad), unhex(tc.result)
+
+			got, err := SealGCMSIV(key, nonce, plainTxt, ad)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if !bytes.Equal(want, got) {
+				t.Fatalf("expect %x, %x be equals", want, got)
+			}
+
+			opened, err := OpenGCMSIV(key, nonce, got, ad)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if !bytes.Equal(plainTxt, opened) {
+				t.Fatalf("expect %x, %x be equals", plainTxt, opened)
+			}
+
+			got[0] ^= 1
+			if _, err := OpenGCMSIV(key, nonce, got, ad); !errors.Is(err, ErrGCMSIVIntegrityCheck) {
+				t.Fatalf("expect err be %v, got %v", ErrGCMSIVIntegrityCheck, err)
+			}
+		}
+	})
+
+	t.Run("encryptor", func(t *testing.T) {
+		nspace := "tenant-gcms1v"
+
+		enc := New256GCMSIVEncryptor()
+
+		// assert keys generated for 'AES 256 GCM' remain usable
+		k, err := New256GCMEncryptor().KeyGen()(context.Background(), nspace, "sub-1")
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		key := core.Key(k)
+
+		c1, err := enc.Encrypt(nspace, key, "Idir Moore")
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		c2, err := enc.Encrypt(nspace, key, "Idir Moore")
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if bytes.Equal(c1, c2) {
+			t.Fatal("expect randomized cipher texts be different")
+		}
+
+		plainTxt, err := enc.Decrypt(nspace, key, c1)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := "Idir Moore", plainTxt; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		if _, err := enc.Decrypt("tenant-other", key, c1); !errors.Is(err, core.ErrDecryptionFailure) {
+			t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package aes
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+var (
+	ErrInvalidHKDFLength = errors.New("invalid HKDF output length")
+)
+
+// HKDF derives a key of the given length from the given secret using
+// the HMAC-based Key Derivation Function as defined by RFC 5869, with SHA-256.
+//
+// The salt is optional, and the info binds the derived key to a specific context.
+func HKDF(secret, salt, info []byte, length int) ([]byte, error) {
+	if length <= 0 || length > 255*sha256.Size {
+		return nil, ErrInvalidHKDFLength
+	}
+	if len(salt) == 0 {
+		salt = make([]byte, sha256.Size)
+	}
+
+	// extract
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	// expand
+	expander := hmac.New(sha256.New, prk)
+	out := make([]byte, 0, length+sha256.Size)
+	var t []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		expander.Reset()
+		expander.Write(t)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		t = expander.Sum(nil)
+		out = append(out, t...)
+	}
+
+	return out[:length], nil
+}
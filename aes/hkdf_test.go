@@ -0,0 +1,50 @@
+package aes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHKDF(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// test vectors from RFC 5869, appendix A
+	tcs := []struct {
+		ikm, salt, info, okm string
+	}{
+		{
+			ikm:  "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			salt: "000102030405060708090a0b0c",
+			info: "f0f1f2f3f4f5f6f7f8f9",
+			okm:  "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+		},
+		{
+			ikm:  "0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+			salt: "",
+			info: "",
+			okm:  "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+		},
+	}
+
+	for _, tc := range tcs {
+		want := unhex(tc.okm)
+		got, err := HKDF(unhex(tc.ikm), unhex(tc.salt), unhex(tc.info), len(want))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(want, got) {
+			t.Fatalf("expect %x, %x be equals", want, got)
+		}
+	}
+
+	if _, err := HKDF([]byte("secret"), nil, nil, 0); err != ErrInvalidHKDFLength {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidHKDFLength, err)
+	}
+}
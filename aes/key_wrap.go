@@ -0,0 +1,90 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrInvalidKeyWrapInput = errors.New("invalid AES key wrap input")
+	ErrKeyUnwrapFailure    = errors.New("failed to unwrap key: integrity check failure")
+)
+
+// keyWrapIV is the default initial value defined by RFC 3394.
+var keyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// WrapKey wraps the given key using the AES Key Wrap algorithm as defined by RFC 3394.
+//
+// The key-encryption key must be a valid AES key, and the wrapped key's size
+// must be a multiple of 8 bytes and at least 16 bytes.
+func WrapKey(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, ErrInvalidKeyWrapInput
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out[8:], key)
+
+	a := make([]byte, 8)
+	copy(a, keyWrapIV)
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], a)
+			copy(buf[8:], out[i*8:i*8+8])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	copy(out[:8], a)
+
+	return out, nil
+}
+
+// UnwrapKey unwraps the given wrapped key using the AES Key Wrap algorithm as defined by RFC 3394.
+//
+// It returns ErrKeyUnwrapFailure if the integrity check fails, e.g., due to a wrong key-encryption key.
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, ErrInvalidKeyWrapInput
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped)-8)
+	copy(out, wrapped[8:])
+
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], out[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+
+			copy(a, buf[:8])
+			copy(out[(i-1)*8:], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, ErrKeyUnwrapFailure
+	}
+
+	return out, nil
+}
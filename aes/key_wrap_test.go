@@ -0,0 +1,74 @@
+package aes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestKeyWrap(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// test vectors from RFC 3394, section 4
+	tcs := []struct {
+		kek, key, wrapped string
+	}{
+		{
+			kek:     "000102030405060708090A0B0C0D0E0F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			kek:     "000102030405060708090A0B0C0D0E0F1011121314151617",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D",
+		},
+		{
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7",
+		},
+		{
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+
+	for _, tc := range tcs {
+		kek, key, want := unhex(tc.kek), unhex(tc.key), unhex(tc.wrapped)
+
+		wrapped, err := WrapKey(kek, key)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(want, wrapped) {
+			t.Fatalf("expect %x, %x be equals", want, wrapped)
+		}
+
+		unwrapped, err := UnwrapKey(kek, wrapped)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(key, unwrapped) {
+			t.Fatalf("expect %x, %x be equals", key, unwrapped)
+		}
+
+		// assert integrity check
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err := UnwrapKey(kek, wrapped); !errors.Is(err, ErrKeyUnwrapFailure) {
+			t.Fatalf("expect err be %v, got %v", ErrKeyUnwrapFailure, err)
+		}
+	}
+
+	if _, err := WrapKey(unhex(tcs[0].kek), []byte("short")); !errors.Is(err, ErrInvalidKeyWrapInput) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidKeyWrapInput, err)
+	}
+}
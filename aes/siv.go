@@ -0,0 +1,236 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+const (
+	// IDSIV is the algorithm identifier of the 'AES SIV' Encryptor.
+	IDSIV = "A256SIV"
+
+	sivBlockSize = aes.BlockSize
+)
+
+var (
+	ErrInvalidSIVKey     = errors.New("invalid AES-SIV key size")
+	ErrSIVIntegrityCheck = errors.New("AES-SIV integrity check failure")
+)
+
+// sivKeyInfo binds the AES-SIV key derived from the subject key to its purpose.
+var sivKeyInfo = []byte("privacy-engine/aes-siv-cmac-512")
+
+type aesSIV struct{}
+
+var _ core.Encryptor = &aesSIV{}
+var _ core.IdentifiedEncryptor = &aesSIV{}
+var _ core.AADEncryptor = &aesSIV{}
+
+// NewSIVEncryptor returns a deterministic Encryptor based on AES-SIV as defined by RFC 5297.
+// The same plain text always results in the same cipher text under the same key and additional data,
+// which allows equality searches on encrypted values.
+//
+// It uses the same 256 bits keys as the 'AES 256 GCM' Encryptor, from which an AES-SIV-CMAC-512 key is derived
+// using HKDF. Therefore, values are crypto-erased along with the subject key.
+func NewSIVEncryptor() core.Encryptor {
+	return &aesSIV{}
+}
+
+// KeyGen implements core.Encryptor
+func (e *aesSIV) KeyGen() core.KeyGen {
+	return Key256GenFn
+}
+
+// ID implements core.IdentifiedEncryptor
+func (e *aesSIV) ID() string {
+	return IDSIV
+}
+
+// Encrypt implements core.Encryptor
+func (e *aesSIV) Encrypt(namespace string, key core.Key, plainTxt string) (cipherTxt []byte, err error) {
+	return e.EncryptWithAAD(namespace, key, plainTxt, core.AAD{})
+}
+
+// Decrypt implements core.Encryptor
+func (e *aesSIV) Decrypt(namespace string, key core.Key, cipherTxt []byte) (plainTxt string, err error) {
+	return e.DecryptWithAAD(namespace, key, cipherTxt, core.AAD{})
+}
+
+// EncryptWithAAD implements core.AADEncryptor
+func (e *aesSIV) EncryptWithAAD(namespace string, key core.Key, plainTxt string, ad core.AAD) (cipherTxt []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrEncryptionFailure, err)
+		}
+	}()
+
+	sivKey, err := HKDF([]byte(key), nil, sivKeyInfo, 64)
+	if err != nil {
+		return
+	}
+
+	return SealSIV(sivKey, []byte(plainTxt), prepareAdditionalData(namespace, ad))
+}
+
+// DecryptWithAAD implements core.AADEncryptor
+func (e *aesSIV) DecryptWithAAD(namespace string, key core.Key, cipherTxt []byte, ad core.AAD) (plainTxt string, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDecryptionFailure, err)
+		}
+	}()
+
+	sivKey, err := HKDF([]byte(key), nil, sivKeyInfo, 64)
+	if err != nil {
+		return
+	}
+
+	plnTxt, err := OpenSIV(sivKey, cipherTxt, prepareAdditionalData(namespace, ad))
+	if err != nil {
+		return
+	}
+	return string(plnTxt), nil
+}
+
+// SealSIV encrypts and authenticates the given plain text along with the given additional data
+// using AES-SIV as defined by RFC 5297. It returns the synthetic IV followed by the cipher text.
+//
+// The key size must be 32, 48, or 64 bytes; its first half is used by S2V and the second one by CTR.
+func SealSIV(key, plainTxt []byte, ad ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := sivCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := s2v(macBlock, plainTxt, ad...)
+
+	out := make([]byte, sivBlockSize+len(plainTxt))
+	copy(out, v)
+	cipher.NewCTR(ctrBlock, sivCounter(v)).XORKeyStream(out[sivBlockSize:], plainTxt)
+
+	return out, nil
+}
+
+// OpenSIV decrypts and verifies the given cipher text, sealed by SealSIV, along with the given additional data.
+func OpenSIV(key, cipherTxt []byte, ad ...[]byte) ([]byte, error) {
+	if len(cipherTxt) < sivBlockSize {
+		return nil, errors.New("cipher text too short")
+	}
+	macBlock, ctrBlock, err := sivCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := cipherTxt[:sivBlockSize]
+	plainTxt := make([]byte, len(cipherTxt)-sivBlockSize)
+	cipher.NewCTR(ctrBlock, sivCounter(v)).XORKeyStream(plainTxt, cipherTxt[sivBlockSize:])
+
+	if subtle.ConstantTimeCompare(v, s2v(macBlock, plainTxt, ad...)) != 1 {
+		return nil, ErrSIVIntegrityCheck
+	}
+	return plainTxt, nil
+}
+
+func sivCiphers(key []byte) (macBlock, ctrBlock cipher.Block, err error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, nil, ErrInvalidSIVKey
+	}
+	half := len(key) / 2
+	if macBlock, err = aes.NewCipher(key[:half]); err != nil {
+		return
+	}
+	ctrBlock, err = aes.NewCipher(key[half:])
+	return
+}
+
+// sivCounter returns the CTR initial counter by clearing the 31st and 63rd bits of the synthetic IV.
+func sivCounter(v []byte) []byte {
+	q := make([]byte, sivBlockSize)
+	copy(q, v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	return q
+}
+
+// s2v implements the S2V pseudo-random function defined by RFC 5297, section 2.4.
+func s2v(block cipher.Block, plainTxt []byte, ad ...[]byte) []byte {
+	k1, k2 := cmacSubkeys(block)
+
+	d := cmac(block, k1, k2, make([]byte, sivBlockSize))
+	for _, s := range ad {
+		d = dbl(d)
+		subtle.XORBytes(d, d, cmac(block, k1, k2, s))
+	}
+
+	var t []byte
+	if len(plainTxt) >= sivBlockSize {
+		t = make([]byte, len(plainTxt))
+		copy(t, plainTxt)
+		end := t[len(t)-sivBlockSize:]
+		subtle.XORBytes(end, end, d)
+	} else {
+		t = dbl(d)
+		padded := make([]byte, sivBlockSize)
+		copy(padded, plainTxt)
+		padded[len(plainTxt)] = 0x80
+		subtle.XORBytes(t, t, padded)
+	}
+
+	return cmac(block, k1, k2, t)
+}
+
+// cmacSubkeys generates the CMAC subkeys as defined by RFC 4493, section 2.3.
+func cmacSubkeys(block cipher.Block) (k1, k2 []byte) {
+	l := make([]byte, sivBlockSize)
+	block.Encrypt(l, l)
+	k1 = dbl(l)
+	k2 = dbl(k1)
+	return
+}
+
+// cmac computes the AES-CMAC of the given message as defined by RFC 4493.
+func cmac(block cipher.Block, k1, k2, msg []byte) []byte {
+	n := (len(msg) + sivBlockSize - 1) / sivBlockSize
+	complete := n > 0 && len(msg)%sivBlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	last := make([]byte, sivBlockSize)
+	if complete {
+		subtle.XORBytes(last, msg[(n-1)*sivBlockSize:], k1)
+	} else {
+		copy(last, msg[(n-1)*sivBlockSize:])
+		last[len(msg)-(n-1)*sivBlockSize] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+
+	x := make([]byte, sivBlockSize)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*sivBlockSize:(i+1)*sivBlockSize])
+		block.Encrypt(x, x)
+	}
+	subtle.XORBytes(x, x, last)
+	block.Encrypt(x, x)
+
+	return x
+}
+
+// dbl multiplies the given block by x in GF(2^128).
+func dbl(b []byte) []byte {
+	out := make([]byte, sivBlockSize)
+	var carry byte
+	for i := sivBlockSize - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	// constant-time conditional reduction
+	out[sivBlockSize-1] ^= 0x87 & -carry
+	return out
+}
@@ -0,0 +1,119 @@
+package aes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+func TestSIV(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	// test vectors from RFC 5297, appendix A
+	tcs := []struct {
+		key, plainTxt, cipherTxt string
+		ad                       []string
+	}{
+		{
+			key:       "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			ad:        []string{"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627"},
+			plainTxt:  "11223344 55667788 99aabbcc ddee",
+			cipherTxt: "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{
+			key: "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			ad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			plainTxt:  "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			cipherTxt: "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+
+	for _, tc := range tcs {
+		key, plainTxt, want := unhex(tc.key), unhex(tc.plainTxt), unhex(tc.cipherTxt)
+		ad := make([][]byte, 0, len(tc.ad))
+		for _, s := range tc.ad {
+			ad = append(ad, unhex(s))
+		}
+
+		cipherTxt, err := SealSIV(key, plainTxt, ad...)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(want, cipherTxt) {
+			t.Fatalf("expect %x, %x be equals", want, cipherTxt)
+		}
+
+		got, err := OpenSIV(key, cipherTxt, ad...)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(plainTxt, got) {
+			t.Fatalf("expect %x, %x be equals", plainTxt, got)
+		}
+
+		cipherTxt[len(cipherTxt)-1] ^= 1
+		if _, err := OpenSIV(key, cipherTxt, ad...); !errors.Is(err, ErrSIVIntegrityCheck) {
+			t.Fatalf("expect err be %v, got %v", ErrSIVIntegrityCheck, err)
+		}
+	}
+
+	if _, err := SealSIV(make([]byte, 16), nil); !errors.Is(err, ErrInvalidSIVKey) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidSIVKey, err)
+	}
+}
+
+func TestSIVEncryptor(t *testing.T) {
+	nspace := "tenant-s1vd3t"
+
+	enc := NewSIVEncryptor()
+
+	key := core.Key(bytes.Repeat([]byte{0x42}, aES265KeySize))
+
+	c1, err := enc.Encrypt(nspace, key, "idir@example.com")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	c2, err := enc.Encrypt(nspace, key, "idir@example.com")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert encryption is deterministic
+	if !bytes.Equal(c1, c2) {
+		t.Fatalf("expect %x, %x be equals", c1, c2)
+	}
+
+	// assert cipher texts depend on the key and the namespace
+	otherKey := core.Key(bytes.Repeat([]byte{0x43}, aES265KeySize))
+	if c3, _ := enc.Encrypt(nspace, otherKey, "idir@example.com"); bytes.Equal(c1, c3) {
+		t.Fatal("expect cipher texts be different")
+	}
+	if c4, _ := enc.Encrypt("tenant-other", key, "idir@example.com"); bytes.Equal(c1, c4) {
+		t.Fatal("expect cipher texts be different")
+	}
+
+	plainTxt, err := enc.Decrypt(nspace, key, c1)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "idir@example.com", plainTxt; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if _, err := enc.Decrypt(nspace, otherKey, c1); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+}
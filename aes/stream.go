@@ -0,0 +1,225 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// StreamChunkSize is the size of the plain text chunks of encrypted streams.
+	StreamChunkSize = 64 * 1024
+
+	streamSaltSize        = 32
+	streamNoncePrefixSize = 7
+	streamTagSize         = 16
+)
+
+var (
+	ErrStreamTruncated      = errors.New("encrypted stream truncated")
+	ErrStreamIntegrityCheck = errors.New("encrypted stream integrity check failure")
+	ErrStreamTooLong        = errors.New("stream too long")
+)
+
+// streamKeyInfo binds the stream key derived from the given key to its purpose.
+var streamKeyInfo = []byte("privacy-engine/stream-a256gcm")
+
+// NewStreamEncrypter returns a reader of the given reader's content encrypted using the STREAM construction,
+// i.e., a chunked online AEAD built on top of AES-256-GCM, which allows encrypting large contents
+// without loading them in memory.
+//
+// Each stream uses a key derived from the given key and a random salt using HKDF. Chunk nonces are made of
+// a random prefix, the chunk counter, and a flag marking the final chunk, which prevents reordering,
+// dropping, and truncating chunks. The additional data is authenticated along with every chunk.
+//
+// The encrypted stream starts with the salt and the nonce prefix, followed by the sealed chunks.
+func NewStreamEncrypter(key, ad []byte, r io.Reader) io.Reader {
+	return &streamEncrypter{
+		stream: stream{key: key, ad: ad, src: r},
+		buf:    make([]byte, StreamChunkSize+1),
+	}
+}
+
+// NewStreamDecrypter returns a reader of the given encrypted stream's plain text content, see NewStreamEncrypter.
+//
+// Chunks are verified before being returned, while the stream's integrity is only guaranteed once
+// the reader returns io.EOF; callers must discard the content read so far if another error occurs.
+func NewStreamDecrypter(key, ad []byte, r io.Reader) io.Reader {
+	return &streamDecrypter{
+		stream: stream{key: key, ad: ad, src: r},
+		buf:    make([]byte, StreamChunkSize+streamTagSize+1),
+	}
+}
+
+type stream struct {
+	key, ad []byte
+	src     io.Reader
+
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint64
+
+	out  []byte
+	done bool
+	err  error
+}
+
+func (s *stream) init(salt, prefix []byte) error {
+	streamKey, err := HKDF(s.key, salt, streamKeyInfo, aES265KeySize)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(streamKey)
+	if err != nil {
+		return err
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return err
+	}
+	s.prefix = prefix
+	return nil
+}
+
+// nonce returns the nonce of the current chunk, and moves the counter to the next one.
+func (s *stream) nonce(last bool) ([]byte, error) {
+	if s.counter > math.MaxUint32 {
+		return nil, ErrStreamTooLong
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	copy(nonce, s.prefix)
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixSize:], uint32(s.counter))
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	s.counter++
+	return nonce, nil
+}
+
+// read copies the pending output to the given buffer, and calls next to fill it once consumed.
+func (s *stream) read(b []byte, next func() error) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		if s.err = next(); s.err != nil {
+			return 0, s.err
+		}
+	}
+	n := copy(b, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// fill reads the next chunk into the given buffer after the carried bytes of the previous read.
+// It reports whether the chunk is the last one, otherwise the buffer's last byte belongs to the next chunk.
+func (s *stream) fill(buf []byte, carried int) (n int, last bool, err error) {
+	n, err = io.ReadFull(s.src, buf[carried:])
+	n += carried
+	switch {
+	case err == nil:
+		return n - 1, false, nil
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return n, true, nil
+	}
+	return 0, false, err
+}
+
+type streamEncrypter struct {
+	stream
+
+	buf     []byte
+	carried int
+}
+
+// Read implements io.Reader
+func (e *streamEncrypter) Read(b []byte) (int, error) {
+	return e.read(b, e.next)
+}
+
+func (e *streamEncrypter) next() error {
+	if e.aead == nil {
+		header, err := getRandomBytes(streamSaltSize + streamNoncePrefixSize)
+		if err != nil {
+			return err
+		}
+		if err := e.init(header[:streamSaltSize], header[streamSaltSize:]); err != nil {
+			return err
+		}
+		e.out = header
+		return nil
+	}
+
+	n, last, err := e.fill(e.buf, e.carried)
+	if err != nil {
+		return err
+	}
+	nonce, err := e.nonce(last)
+	if err != nil {
+		return err
+	}
+	e.out = e.aead.Seal(nil, nonce, e.buf[:n], e.ad)
+
+	if last {
+		e.done = true
+	} else {
+		e.buf[0] = e.buf[n]
+		e.carried = 1
+	}
+	return nil
+}
+
+type streamDecrypter struct {
+	stream
+
+	buf     []byte
+	carried int
+}
+
+// Read implements io.Reader
+func (d *streamDecrypter) Read(b []byte) (int, error) {
+	return d.read(b, d.next)
+}
+
+func (d *streamDecrypter) next() error {
+	if d.aead == nil {
+		header := make([]byte, streamSaltSize+streamNoncePrefixSize)
+		if _, err := io.ReadFull(d.src, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return ErrStreamTruncated
+			}
+			return err
+		}
+		return d.init(header[:streamSaltSize], header[streamSaltSize:])
+	}
+
+	n, last, err := d.fill(d.buf, d.carried)
+	if err != nil {
+		return err
+	}
+	if n < streamTagSize {
+		return ErrStreamTruncated
+	}
+	nonce, err := d.nonce(last)
+	if err != nil {
+		return err
+	}
+	plainTxt, err := d.aead.Open(nil, nonce, d.buf[:n], d.ad)
+	if err != nil {
+		return ErrStreamIntegrityCheck
+	}
+	d.out = plainTxt
+
+	if last {
+		d.done = true
+	} else {
+		d.buf[0] = d.buf[n]
+		d.carried = 1
+	}
+	return nil
+}
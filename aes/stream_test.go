@@ -0,0 +1,103 @@
+package aes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStream(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	ad := []byte("ns:tenant-str34m")
+
+	encrypt := func(t *testing.T, plainTxt []byte) []byte {
+		t.Helper()
+		cipherTxt, err := io.ReadAll(NewStreamEncrypter(key, ad, bytes.NewReader(plainTxt)))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		return cipherTxt
+	}
+
+	t.Run("encrypt and decrypt", func(t *testing.T) {
+		for _, size := range []int{0, 1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 2*StreamChunkSize + 5} {
+			plainTxt := make([]byte, size)
+			if _, err := rand.Read(plainTxt); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			cipherTxt, err := io.ReadAll(NewStreamEncrypter(key, ad, iotest.HalfReader(bytes.NewReader(plainTxt))))
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			chunks := max(1, (size+StreamChunkSize-1)/StreamChunkSize)
+			if want, got := streamSaltSize+streamNoncePrefixSize+size+chunks*streamTagSize, len(cipherTxt); want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			got, err := io.ReadAll(iotest.OneByteReader(NewStreamDecrypter(key, ad, iotest.HalfReader(bytes.NewReader(cipherTxt)))))
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if !bytes.Equal(plainTxt, got) {
+				t.Fatalf("expect decrypted content of size %d be equal to the original one", size)
+			}
+		}
+	})
+
+	t.Run("randomized", func(t *testing.T) {
+		if bytes.Equal(encrypt(t, []byte("Idir Moore")), encrypt(t, []byte("Idir Moore"))) {
+			t.Fatal("expect randomized cipher texts be different")
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		plainTxt := make([]byte, 2*StreamChunkSize+5)
+		cipherTxt := encrypt(t, plainTxt)
+
+		header := streamSaltSize + streamNoncePrefixSize
+		fullChunk := StreamChunkSize + streamTagSize
+
+		swapped := bytes.Clone(cipherTxt)
+		copy(swapped[header:], cipherTxt[header+fullChunk:header+2*fullChunk])
+		copy(swapped[header+fullChunk:], cipherTxt[header:header+fullChunk])
+
+		flipped := bytes.Clone(cipherTxt)
+		flipped[header+fullChunk+1] ^= 1
+
+		tcs := map[string]struct {
+			key, ad, cipherTxt []byte
+			err                error
+		}{
+			"truncated at chunk boundary": {key, ad, cipherTxt[:header+2*fullChunk], ErrStreamIntegrityCheck},
+			"truncated within chunk":      {key, ad, cipherTxt[:header+fullChunk+20], ErrStreamIntegrityCheck},
+			"truncated tag":               {key, ad, cipherTxt[:header+fullChunk+5], ErrStreamTruncated},
+			"truncated header":            {key, ad, cipherTxt[:header-1], ErrStreamTruncated},
+			"empty":                       {key, ad, nil, ErrStreamTruncated},
+			"swapped chunks":              {key, ad, swapped, ErrStreamIntegrityCheck},
+			"flipped bit":                 {key, ad, flipped, ErrStreamIntegrityCheck},
+			"extended":                    {key, ad, append(bytes.Clone(cipherTxt), 0), ErrStreamIntegrityCheck},
+			"other additional data":       {key, []byte("ns:tenant-other"), cipherTxt, ErrStreamIntegrityCheck},
+			"other key":                   {bytes.Repeat([]byte{2}, 32), ad, cipherTxt, ErrStreamIntegrityCheck},
+		}
+		for name, tc := range tcs {
+			t.Run(name, func(t *testing.T) {
+				_, err := io.ReadAll(NewStreamDecrypter(tc.key, tc.ad, bytes.NewReader(tc.cipherTxt)))
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expect err be %v, got %v", tc.err, err)
+				}
+			})
+		}
+	})
+
+	t.Run("source failure", func(t *testing.T) {
+		errSource := errors.New("source failure")
+		_, err := io.ReadAll(NewStreamEncrypter(key, ad, iotest.ErrReader(errSource)))
+		if !errors.Is(err, errSource) {
+			t.Fatalf("expect err be %v, got %v", errSource, err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ package aes
 import (
 	"crypto/rand"
 	"io"
+	"strconv"
 )
 
 func getRandomBytes(size uint16) ([]byte, error) {
@@ -15,3 +16,13 @@ func getRandomBytes(size uint16) ([]byte, error) {
 
 	return data, nil
 }
+
+// KeyAlgorithm returns the name of the AES variant matching the given key's size,
+// e.g., "AES-256". It returns an empty string if the size doesn't match any AES variant.
+func KeyAlgorithm(key []byte) string {
+	switch len(key) {
+	case 16, 24, 32:
+		return "AES-" + strconv.Itoa(len(key)*8)
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+const (
+	// IDX25519 is the algorithm identifier of the 'X25519 AES 256 GCM' hybrid Encryptor.
+	IDX25519 = "X25519A256GCM"
+
+	x25519KeySize = 32
+)
+
+var (
+	ErrInvalidX25519Key = errors.New("invalid X25519 key")
+)
+
+var (
+	// x25519KeyInfo binds the X25519 private key derived from the subject key to its purpose.
+	x25519KeyInfo = []byte("privacy-engine/x25519")
+
+	// x25519KEKInfo binds the key-encryption key derived from the shared secret to its purpose.
+	x25519KEKInfo = []byte("privacy-engine/x25519-a256gcm")
+)
+
+type x25519 struct{}
+
+var _ core.Encryptor = &x25519{}
+var _ core.IdentifiedEncryptor = &x25519{}
+var _ core.AADEncryptor = &x25519{}
+var _ core.PublicKeyEncryptor = &x25519{}
+
+// NewX25519Encryptor returns a hybrid public-key Encryptor: values are encrypted using a public key,
+// and can only be decrypted using the key it's derived from.
+//
+// It uses the same 256 bits keys as the 'AES 256 GCM' Encryptor, from which an X25519 private key is derived
+// using HKDF. Each value is encrypted using AES-256-GCM and a key derived from the ECDH shared secret
+// of an ephemeral key pair and the public key. Therefore, values are crypto-erased along with the subject key.
+func NewX25519Encryptor() core.Encryptor {
+	return &x25519{}
+}
+
+// KeyGen implements core.Encryptor
+func (e *x25519) KeyGen() core.KeyGen {
+	return Key256GenFn
+}
+
+// ID implements core.IdentifiedEncryptor
+func (e *x25519) ID() string {
+	return IDX25519
+}
+
+// PublicKey implements core.PublicKeyEncryptor
+func (e *x25519) PublicKey(key core.Key) (core.Key, error) {
+	priv, err := x25519PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return core.Key(priv.PublicKey().Bytes()), nil
+}
+
+// Encrypt implements core.Encryptor
+//
+// The given key must be a public key derived using PublicKey.
+func (e *x25519) Encrypt(namespace string, publicKey core.Key, plainTxt string) (cipherTxt []byte, err error) {
+	return e.EncryptWithAAD(namespace, publicKey, plainTxt, core.AAD{})
+}
+
+// Decrypt implements core.Encryptor
+func (e *x25519) Decrypt(namespace string, key core.Key, cipherTxt []byte) (plainTxt string, err error) {
+	return e.DecryptWithAAD(namespace, key, cipherTxt, core.AAD{})
+}
+
+// EncryptWithAAD implements core.AADEncryptor
+//
+// The given key must be a public key derived using PublicKey.
+// The cipher text is made of the ephemeral public key, the nonce, then the AES-256-GCM cipher text.
+func (e *x25519) EncryptWithAAD(namespace string, publicKey core.Key, plainTxt string, ad core.AAD) (cipherTxt []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrEncryptionFailure, err)
+		}
+	}()
+
+	pub, err := ecdh.X25519().NewPublicKey([]byte(publicKey))
+	if err != nil {
+		err = errors.Join(ErrInvalidX25519Key, err)
+		return
+	}
+
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return
+	}
+
+	shared, err := eph.ECDH(pub)
+	if err != nil {
+		return
+	}
+	aesgcm, err := x25519AEAD(shared, eph.PublicKey(), pub)
+	if err != nil {
+		return
+	}
+
+	nonce, err := getRandomBytes(uint16(aesgcm.NonceSize()))
+	if err != nil {
+		return
+	}
+
+	cipherTxt = append(eph.PublicKey().Bytes(), nonce...)
+	return aesgcm.Seal(cipherTxt, nonce, []byte(plainTxt), prepareAdditionalData(namespace, ad)), nil
+}
+
+// DecryptWithAAD implements core.AADEncryptor
+func (e *x25519) DecryptWithAAD(namespace string, key core.Key, cipherTxt []byte, ad core.AAD) (plainTxt string, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(core.ErrDecryptionFailure, err)
+		}
+	}()
+
+	priv, err := x25519PrivateKey(key)
+	if err != nil {
+		return
+	}
+
+	if len(cipherTxt) < x25519KeySize {
+		err = errors.New("cipher text too short")
+		return
+	}
+	eph, err := ecdh.X25519().NewPublicKey(cipherTxt[:x25519KeySize])
+	if err != nil {
+		return
+	}
+
+	shared, err := priv.ECDH(eph)
+	if err != nil {
+		return
+	}
+	aesgcm, err := x25519AEAD(shared, eph, priv.PublicKey())
+	if err != nil {
+		return
+	}
+
+	cipherTxt = cipherTxt[x25519KeySize:]
+	if len(cipherTxt) < aesgcm.NonceSize() {
+		err = errors.New("cipher text too short")
+		return
+	}
+
+	plnTxt, err := aesgcm.Open(nil, cipherTxt[:aesgcm.NonceSize()], cipherTxt[aesgcm.NonceSize():], prepareAdditionalData(namespace, ad))
+	if err != nil {
+		return
+	}
+
+	return string(plnTxt), nil
+}
+
+// x25519PrivateKey derives the X25519 private key of the given key.
+func x25519PrivateKey(key core.Key) (*ecdh.PrivateKey, error) {
+	if len(key) != aES265KeySize {
+		return nil, ErrInvalidX25519Key
+	}
+	scalar, err := HKDF([]byte(key), nil, x25519KeyInfo, x25519KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPrivateKey(scalar)
+}
+
+// x25519AEAD returns the AES-256-GCM cipher keyed by the given ECDH shared secret,
+// and bound to both the ephemeral and the recipient public keys.
+func x25519AEAD(shared []byte, eph, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(eph.Bytes(), recipient.Bytes()...)
+	kek, err := HKDF(shared, salt, x25519KEKInfo, aES265KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,105 @@
+package aes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+func TestX25519(t *testing.T) {
+	nspace := "tenant-x25519"
+
+	enc := NewX25519Encryptor().(core.PublicKeyEncryptor)
+
+	k, err := enc.KeyGen()(context.Background(), nspace, "sub-1")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	key := core.Key(k)
+
+	pub, err := enc.PublicKey(key)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if pub == key {
+		t.Fatal("expect public key be different from the key")
+	}
+
+	// assert the public key is deterministic
+	pub2, err := enc.PublicKey(key)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := pub, pub2; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	c1, err := enc.Encrypt(nspace, pub, "Idir Moore")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	c2, err := enc.Encrypt(nspace, pub, "Idir Moore")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if bytes.Equal(c1, c2) {
+		t.Fatal("expect randomized cipher texts be different")
+	}
+
+	plainTxt, err := enc.Decrypt(nspace, key, c1)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "Idir Moore", plainTxt; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the public key can't decrypt
+	if _, err := enc.Decrypt(nspace, pub, c1); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+
+	// assert another key can't decrypt
+	k2, err := enc.KeyGen()(context.Background(), nspace, "sub-2")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if _, err := enc.Decrypt(nspace, core.Key(k2), c1); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+
+	// assert cipher texts are bound to their namespace and additional data
+	if _, err := enc.Decrypt("tenant-other", key, c1); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+
+	aadEnc := enc.(core.AADEncryptor)
+	ad := core.AAD{SubjectID: "sub-1", Field: "Fullname"}
+	c3, err := aadEnc.EncryptWithAAD(nspace, pub, "Idir Moore", ad)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if plainTxt, err := aadEnc.DecryptWithAAD(nspace, key, c3, ad); err != nil || plainTxt != "Idir Moore" {
+		t.Fatalf("expect %v, %v be equals, err: %v", "Idir Moore", plainTxt, err)
+	}
+	if _, err := aadEnc.DecryptWithAAD(nspace, key, c3, core.AAD{SubjectID: "sub-2", Field: "Fullname"}); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+
+	// assert tampered and truncated cipher texts are refused
+	tampered := bytes.Clone(c1)
+	tampered[len(tampered)-1] ^= 1
+	for _, ct := range [][]byte{tampered, c1[:x25519KeySize+4], nil} {
+		if _, err := enc.Decrypt(nspace, key, ct); !errors.Is(err, core.ErrDecryptionFailure) {
+			t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+		}
+	}
+
+	// assert invalid public keys are refused
+	if _, err := enc.Encrypt(nspace, "invalid", "Idir Moore"); !errors.Is(err, ErrInvalidX25519Key) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidX25519Key, err)
+	}
+}
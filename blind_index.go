@@ -0,0 +1,203 @@
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ln80/privacy-engine/aes"
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+// Errors related to blind indexes
+var (
+	ErrBlindIndexFailure = newErr("failed to compute blind index")
+
+	ErrInvalidBlindIndex = errors.New("invalid blind index config")
+	ErrNormalizeFailure  = errors.New("failed to normalize value")
+)
+
+const (
+	// tagOptionIndex is the 'pii' tag option that names the companion field filled with the field's blind index.
+	tagOptionIndex = "index"
+
+	// tagOptionNormalize is the 'pii' tag option that lists the normalizers applied before computing the blind index,
+	// separated by '|', e.g., "trim|lower".
+	tagOptionNormalize = "normalize"
+
+	defaultBlindIndexLength = 16
+)
+
+var (
+	e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// BlindIndexer is implemented by Protector services that compute blind indexes.
+type BlindIndexer interface {
+
+	// BlindIndex returns the blind index of the given value normalized using the given normalizers,
+	// separated by '|', which allows searching by encrypted fields without decrypting them.
+	//
+	// Fields tagged with the 'index' option, e.g., `pii:"data,index=EmailIdx,normalize=trim|lower"`,
+	// have their blind index filled in the named companion field by Protector.Encrypt.
+	// Note that blind indexes are not crypto-erased when subjects are forgotten.
+	//
+	// It requires the BlindIndexKey config.
+	BlindIndex(ctx context.Context, value, normalize string) (string, error)
+}
+
+var _ BlindIndexer = &protector{}
+
+// Normalizer normalizes a value before computing its blind index, so that equivalent values
+// result in the same index, e.g., "Idir@Example.com" and "idir@example.com".
+type Normalizer func(val string) (string, error)
+
+// builtinNormalizers are the normalizers available without configuration.
+var builtinNormalizers = map[string]Normalizer{
+	"trim": func(val string) (string, error) {
+		return strings.TrimSpace(val), nil
+	},
+	"lower": func(val string) (string, error) {
+		return strings.ToLower(val), nil
+	},
+	"e164": NormalizeE164,
+}
+
+// NormalizeE164 normalizes the given phone number to the E.164 format, e.g., "+212 (6) 12-34-56-78" to "+212612345678".
+// The number must include the country code, prefixed either by '+' or '00'.
+func NormalizeE164(val string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(val) {
+		switch {
+		case r >= '0' && r <= '9', r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.', r == '(', r == ')':
+		default:
+			return "", fmt.Errorf("%w: invalid phone number character '%c'", ErrNormalizeFailure, r)
+		}
+	}
+
+	n := b.String()
+	if strings.HasPrefix(n, "00") {
+		n = "+" + n[2:]
+	}
+	if !e164Regex.MatchString(n) {
+		return "", fmt.Errorf("%w: invalid E.164 phone number", ErrNormalizeFailure)
+	}
+	return n, nil
+}
+
+// BlindIndex implements BlindIndexer
+func (p *protector) BlindIndex(ctx context.Context, value, normalize string) (index string, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrBlindIndexFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	key, err := p.blindIndexKey()
+	if err != nil {
+		return
+	}
+	return p.blindIndex(key, value, normalize)
+}
+
+// blindIndexKey derives the namespace's blind index HMAC key from the configured secret.
+func (p *protector) blindIndexKey() ([]byte, error) {
+	if len(p.BlindIndexKey) == 0 {
+		return nil, fmt.Errorf("%w: missing key", ErrInvalidBlindIndex)
+	}
+	return aes.HKDF(p.BlindIndexKey, nil, []byte("privacy-engine/blind-index:"+p.namespace), sha256.Size)
+}
+
+// blindIndex returns the hex-encoded truncated HMAC of the given value, normalized using the given normalizers.
+func (p *protector) blindIndex(key []byte, value, normalize string) (string, error) {
+	if normalize != "" {
+		for _, name := range strings.Split(normalize, "|") {
+			fn, ok := p.Normalizers[name]
+			if !ok {
+				fn, ok = builtinNormalizers[name]
+			}
+			if !ok {
+				return "", fmt.Errorf("%w: unknown normalizer '%s'", ErrInvalidBlindIndex, name)
+			}
+			var err error
+			if value, err = fn(value); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	length := p.BlindIndexLength
+	if length == 0 {
+		length = defaultBlindIndexLength
+	}
+	if length < 0 || length > sha256.Size {
+		return "", fmt.Errorf("%w: length must be between 1 and %d bytes", ErrInvalidBlindIndex, sha256.Size)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:length]), nil
+}
+
+// index fills the companion fields of the given structs' fields tagged with the 'index' option,
+// and records the changes in the given journal.
+// Fields that are already encrypted are skipped, and their companion fields are left untouched.
+func (p *protector) index(structs []sensitiveStruct, j *journal) error {
+	var key []byte
+	for idx, s := range structs {
+		err := s.walk(func(parent reflect.Value, fr sensitive.FieldReplace, elem reflect.Value) (err error) {
+			companion := fr.Options[tagOptionIndex]
+			if companion == "" || isWireFormatted(elem.String()) {
+				return nil
+			}
+			field, ok := companionField(parent, companion)
+			if !ok {
+				return fmt.Errorf("%w: companion field '%s' of '%s' not found", ErrInvalidBlindIndex, companion, fr.Name)
+			}
+			if key == nil {
+				if key, err = p.blindIndexKey(); err != nil {
+					return
+				}
+			}
+			index, err := p.blindIndex(key, elem.String(), fr.Options[tagOptionNormalize])
+			if err != nil {
+				return
+			}
+			j.setString(field, index)
+			return
+		}, j)
+		if err != nil {
+			return fmt.Errorf("%w at #%d", err, idx)
+		}
+	}
+	return nil
+}
+
+// companionField returns the settable string field of the given struct named by its Go or JSON name.
+func companionField(parent reflect.Value, name string) (reflect.Value, bool) {
+	rt := parent.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if sf.Name != name && jsonName != name {
+			continue
+		}
+		field := parent.Field(i)
+		if field.Kind() != reflect.String || !field.CanSet() {
+			return reflect.Value{}, false
+		}
+		return field, true
+	}
+	return reflect.Value{}, false
+}
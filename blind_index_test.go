@@ -0,0 +1,146 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ln80/privacy-engine/memory"
+)
+
+type Contact struct {
+	UserID   string `pii:"subjectID"`
+	Email    string `pii:"data,index=EmailIdx,normalize=trim|lower"`
+	EmailIdx string
+	Phone    string `pii:"data,index=phone_idx,normalize=e164"`
+	PhoneIdx string `json:"phone_idx"`
+}
+
+func TestProtector_BlindIndex(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-bl1nd1"
+
+	engine := memory.NewKeyEngine()
+
+	withKey := func(pc *ProtectorConfig) {
+		pc.BlindIndexKey = []byte("blind-index-secret")
+	}
+
+	p := NewProtector(nspace, engine, withKey)
+
+	c1 := Contact{UserID: "kal5430", Email: "Idir@Example.com", Phone: "+212 (6) 12-34-56-78"}
+	c2 := Contact{UserID: "aze6590", Email: " idir@example.com", Phone: "00212612345678"}
+
+	if err := p.Encrypt(ctx, &c1, &c2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert equivalent values have the same index, regardless of their subject
+	if c1.EmailIdx == "" || c1.EmailIdx != c2.EmailIdx {
+		t.Fatalf("expect %v, %v be equals", c1.EmailIdx, c2.EmailIdx)
+	}
+	if c1.PhoneIdx == "" || c1.PhoneIdx != c2.PhoneIdx {
+		t.Fatalf("expect %v, %v be equals", c1.PhoneIdx, c2.PhoneIdx)
+	}
+	if want, got := 2*defaultBlindIndexLength, len(c1.EmailIdx); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert search terms can be indexed
+	idx, err := p.(BlindIndexer).BlindIndex(ctx, "IDIR@example.com", "trim|lower")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := c1.EmailIdx, idx; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert indexes of already encrypted fields are left untouched
+	ec1 := c1
+	if err := p.Encrypt(ctx, &c1); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := ec1, c1; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the index key is derived per namespace
+	otherIdx, err := NewProtector("tenant-other", engine, withKey).(BlindIndexer).BlindIndex(ctx, "idir@example.com", "")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if otherIdx == idx {
+		t.Fatal("expect blind indexes be different")
+	}
+
+	// assert the truncation length is configurable
+	idx, err = NewProtector(nspace, engine, withKey, func(pc *ProtectorConfig) {
+		pc.BlindIndexLength = 4
+	}).(BlindIndexer).BlindIndex(ctx, "idir@example.com", "")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 8, len(idx); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert custom normalizers
+	idx, err = NewProtector(nspace, engine, withKey, func(pc *ProtectorConfig) {
+		pc.Normalizers = map[string]Normalizer{
+			"gmail": func(val string) (string, error) {
+				return val + "@gmail.com", nil
+			},
+		}
+	}).(BlindIndexer).BlindIndex(ctx, "idir", "gmail")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, err := p.(BlindIndexer).BlindIndex(ctx, "idir@gmail.com", ""); err != nil || want != idx {
+		t.Fatalf("expect %v, %v be equals, err: %v", want, idx, err)
+	}
+
+	// assert invalid configs
+	if _, err := p.(BlindIndexer).BlindIndex(ctx, "idir", "unknown"); !errors.Is(err, ErrBlindIndexFailure) || !errors.Is(err, ErrInvalidBlindIndex) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidBlindIndex, err)
+	}
+	c3 := Contact{UserID: "kal5430", Email: "idir@example.com"}
+	if err := NewProtector(nspace, engine).Encrypt(ctx, &c3); !errors.Is(err, ErrInvalidBlindIndex) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidBlindIndex, err)
+	}
+	// assert nothing is encrypted if indexing fails
+	if want, got := "idir@example.com", c3.Email; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestNormalizeE164(t *testing.T) {
+	tcs := []struct {
+		val, want string
+		ok        bool
+	}{
+		{val: "+212612345678", want: "+212612345678", ok: true},
+		{val: " +1 (415) 555-2671 ", want: "+14155552671", ok: true},
+		{val: "0033.6.12.34.56.78", want: "+33612345678", ok: true},
+		{val: "0612345678", ok: false},
+		{val: "+0612345678", ok: false},
+		{val: "+1234567890123456", ok: false},
+		{val: "+1 415 555 CALL", ok: false},
+	}
+
+	for _, tc := range tcs {
+		got, err := NormalizeE164(tc.val)
+		if !tc.ok {
+			if !errors.Is(err, ErrNormalizeFailure) {
+				t.Fatalf("expect err be %v, got %v", ErrNormalizeFailure, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want := tc.want; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
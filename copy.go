@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"context"
+	"reflect"
+)
+
+// DecryptCopy returns a decrypted copy of the given struct pointer's value, while the original one remains encrypted,
+// which prevents shared or cached values from holding plain text Personal data.
+//
+// The value is deep copied before decryption, including nested structs, pointers, slices, and maps,
+// see Protector.Decrypt. The copy is returned along with the error, e.g., in case of partial results, see ErrorPolicy.
+func DecryptCopy[T any](ctx context.Context, p Protector, structPtr *T) (T, error) {
+	var cp T
+	if structPtr != nil {
+		cp = copyOf(*structPtr)
+	}
+
+	err := p.Decrypt(ctx, &cp)
+	return cp, err
+}
+
+// DecryptCopies is similar to DecryptCopy, and returns decrypted copies of the given struct pointers' values
+// using a single call to Protector.Decrypt.
+func DecryptCopies[T any](ctx context.Context, p Protector, structPtrs []*T) ([]T, error) {
+	cps := make([]T, len(structPtrs))
+	ptrs := make([]any, 0, len(structPtrs))
+	for i, structPtr := range structPtrs {
+		if structPtr != nil {
+			cps[i] = copyOf(*structPtr)
+		}
+		ptrs = append(ptrs, &cps[i])
+	}
+
+	err := p.Decrypt(ctx, ptrs...)
+	return cps, err
+}
+
+// copyOf returns a deep copy of the given value, see deepCopy.
+func copyOf[T any](v T) T {
+	return *deepCopy(reflect.ValueOf(&v)).Interface().(*T)
+}
+
+// deepCopy returns a deep copy of the given value. Structs are copied as a whole before their exported fields
+// are deep copied, therefore unexported fields are shallow copied. Interfaces, channels, and functions are shallow copied too.
+func deepCopy(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(deepCopy(v.Elem()))
+		return cp
+
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			cp.Field(i).Set(deepCopy(v.Field(i)))
+		}
+		return cp
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return cp
+	}
+
+	return v
+}
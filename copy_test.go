@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/memory"
+)
+
+type Customer struct {
+	UserID    string             `pii:"subjectID"`
+	Profile   *Profile           `pii:"dive"`
+	Addresses []Address          `pii:"dive"`
+	Contacts  map[string]Address `pii:"dive"`
+	Tags      []string
+}
+
+func TestDecryptCopy(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-c0py"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	newCustomer := func(subjectID string) Customer {
+		return Customer{
+			UserID:    subjectID,
+			Profile:   &Profile{UserID: subjectID, Fullname: "Idir Moore", Address: Address{Street: "Baker Street"}},
+			Addresses: []Address{{Street: "Main Street"}},
+			Contacts:  map[string]Address{"work": {Street: "Wall Street"}},
+			Tags:      []string{"vip"},
+		}
+	}
+
+	c1, c2 := newCustomer("sub-1"), newCustomer("sub-2")
+	if err := p.Encrypt(ctx, &c1, &c2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	e1, e2 := copyOf(c1), copyOf(c2)
+
+	got, err := DecryptCopy(ctx, p, &c1)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want := newCustomer("sub-1"); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the original value, including nested pointers, slices and maps, remains encrypted
+	if want, got := e1, c1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if c1.Profile == got.Profile {
+		t.Fatal("expect nested pointers be copied")
+	}
+
+	gots, err := DecryptCopies(ctx, p, []*Customer{&c1, &c2, nil})
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []Customer{newCustomer("sub-1"), newCustomer("sub-2"), {}}, gots; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := e1, c1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := e2, c2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -22,3 +22,65 @@ type Encryptor interface {
 	// according to the implemented algorithm.
 	KeyGen() KeyGen
 }
+
+// IdentifiedEncryptor is an optional interface implemented by Encryptors that expose an algorithm identifier,
+// e.g., "A256GCM".
+//
+// The identifier is recorded along with the cipher text, which allows decrypting it using the matching
+// Encryptor. Therefore, it must be stable, unique, and only contain [A-Za-z0-9._-] characters.
+type IdentifiedEncryptor interface {
+	Encryptor
+
+	// ID returns the algorithm identifier of the Encryptor.
+	ID() string
+}
+
+// EncryptorID returns the algorithm identifier of the given Encryptor,
+// or an empty string if it doesn't implement IdentifiedEncryptor.
+func EncryptorID(enc Encryptor) string {
+	if ie, ok := enc.(IdentifiedEncryptor); ok {
+		return ie.ID()
+	}
+	return ""
+}
+
+// AAD presents the additional data a cipher text is bound to, in addition to the namespace.
+type AAD struct {
+
+	// SubjectID is the ID of the subject the plain text belongs to.
+	SubjectID string
+
+	// Field is the path of the field that holds the plain text, e.g., "Addresses.Street". It's optional.
+	Field string
+}
+
+// IsZero reports whether the AAD is empty.
+func (ad AAD) IsZero() bool {
+	return ad == AAD{}
+}
+
+// AADEncryptor is an optional interface implemented by Encryptors that authenticate additional data,
+// which prevents moving a cipher text from a subject or a field to another one within the same namespace.
+//
+// Encrypting/Decrypting using an empty AAD must be equivalent to Encrypt/Decrypt methods.
+type AADEncryptor interface {
+	Encryptor
+
+	// EncryptWithAAD encrypts the given plain text value and binds the cipher text to the given additional data.
+	EncryptWithAAD(namespace string, key Key, plainTxt string, ad AAD) (cipher []byte, err error)
+
+	// DecryptWithAAD decrypts the given cipher text and returns the original value.
+	// It fails if the cipher text is not bound to the given additional data.
+	DecryptWithAAD(namespace string, key Key, cipher []byte, ad AAD) (plainTxt string, err error)
+}
+
+// PublicKeyEncryptor is an optional interface implemented by asymmetric Encryptors, which allows services
+// holding only public keys to encrypt data without being able to decrypt it.
+//
+// Encrypt methods expect the public key derived from a key using PublicKey, while Decrypt methods expect the key itself.
+type PublicKeyEncryptor interface {
+	Encryptor
+
+	// PublicKey derives the public key of the given key.
+	PublicKey(key Key) (Key, error)
+}
@@ -14,7 +14,14 @@ var (
 	ErrReEnableKeyFailure = errors.New("failed to renable encryption key(s)")
 	ErrDisableKeyFailure  = errors.New("failed to disable encryption key")
 	ErrDeleteKeyFailure   = errors.New("failed to delete encryption key")
+	ErrRewriteKeyFailure  = errors.New("failed to rewrite encryption key(s)")
+	ErrRotateKeyFailure   = errors.New("failed to rotate encryption key(s)")
+	ErrScheduleKeyFailure = errors.New("failed to schedule encryption key expiry")
+	ErrHoldKeyFailure     = errors.New("failed to place/release encryption key hold")
+	ErrKeyOnHold          = errors.New("encryption key is on hold")
 	ErrKeyNotFound        = errors.New("encryption key not found")
+	ErrInvalidCursor      = errors.New("invalid list keys cursor")
+	ErrUnsupported        = errors.New("operation not supported by the key engine")
 )
 
 // Encryption key lifecycle states.
@@ -73,6 +80,18 @@ func (ik IDKey) Key() Key {
 	return ik.key
 }
 
+// VersionedKey presents a Key and its version.
+type VersionedKey struct {
+	Version int
+	Key     Key
+}
+
+// VersionedKeyMap presents a map of VersionedKeys indexed by keyID.
+type VersionedKeyMap map[string]VersionedKey
+
+// KeyVersionsMap presents a map of Keys indexed by keyID then by version.
+type KeyVersionsMap map[string]map[int]Key
+
 // KeyGen presents a function used by Key engines to generate keys
 type KeyGen func(ctx context.Context, namespace, keyID string) (string, error)
 
@@ -121,6 +140,209 @@ type KeyEngine interface {
 	DeleteUnusedKeys(ctx context.Context, namespace string) error
 }
 
+// VersionedKeyEngine is an optional interface implemented by Key engines that support
+// multiple versions of a key, i.e., key rotation.
+//
+// The first version of a key is the one returned by KeyEngine methods, which keeps data encrypted
+// before a rotation decryptable. Disable, ReEnable, and Delete operations apply to all versions together.
+type VersionedKeyEngine interface {
+	KeyEngine
+
+	// GetOrCreateLatestKeys is similar to GetOrCreateKeys, except that it returns
+	// the latest version of keys. New keys start at version 1.
+	GetOrCreateLatestKeys(ctx context.Context, namespace string, keyIDs []string, keyGen KeyGen) (VersionedKeyMap, error)
+
+	// GetKeyVersions returns all versions of the keys of the given keyIDs within the given namespace.
+	// Similarly to GetKeys, it doesn't return disabled or deleted keys.
+	GetKeyVersions(ctx context.Context, namespace string, keyIDs []string) (KeyVersionsMap, error)
+
+	// RotateKeys creates a new version for each key of the given keyIDs, and returns the new versions.
+	// It returns ErrKeyNotFound error if a key doesn't exist, or is disabled or deleted.
+	RotateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen KeyGen) (VersionedKeyMap, error)
+}
+
+// NamespaceKeyEngine is an optional interface implemented by Key engines that manage
+// all keys of a namespace at once, e.g., to crypto-shred a tenant's data.
+type NamespaceKeyEngine interface {
+	KeyEngine
+
+	// DisableNamespace disables all active keys of the given namespace.
+	//
+	// Keys disabled this way are tracked, so that ReEnableNamespace doesn't reenable
+	// keys of subjects that were individually disabled.
+	DisableNamespace(ctx context.Context, namespace string) error
+
+	// ReEnableNamespace reenables the keys disabled by DisableNamespace, unless they are hard deleted.
+	ReEnableNamespace(ctx context.Context, namespace string) error
+
+	// DeleteNamespace deletes all keys of the given namespace.
+	DeleteNamespace(ctx context.Context, namespace string) error
+}
+
+// ExpiringKeyEngine is an optional interface implemented by Key engines that persist
+// a scheduled expiry deadline per key, e.g., to fulfill data retention rules.
+//
+// Note that the engine doesn't disable nor delete expired keys by itself.
+type ExpiringKeyEngine interface {
+	KeyEngine
+
+	// ScheduleKeyExpiry sets the deadline after which the key of the given keyID expires.
+	// A zero deadline cancels the scheduled one.
+	//
+	// It returns ErrKeyNotFound error if the key doesn't exist or is already deleted.
+	ScheduleKeyExpiry(ctx context.Context, namespace, keyID string, at time.Time) error
+
+	// ExpiredKeys returns the IDs of the active keys of the given namespace which expired at the given time.
+	//
+	// A key without a scheduled deadline expires once the given retention has elapsed since its creation,
+	// unless the retention is zero.
+	ExpiredKeys(ctx context.Context, namespace string, at time.Time, retention time.Duration) ([]string, error)
+
+	// KeyExpired reports whether the key of the given keyID expired at the given time, similarly to ExpiredKeys.
+	// It returns false if the key doesn't exist.
+	KeyExpired(ctx context.Context, namespace, keyID string, at time.Time, retention time.Duration) (bool, error)
+}
+
+// KeyHold presents a hold placed on a key, e.g., a legal hold due to a litigation.
+type KeyHold struct {
+	Reason   string
+	PlacedAt time.Time
+
+	// ForgetRequested reports whether the key was requested to be disabled or deleted while on hold.
+	ForgetRequested bool
+}
+
+// HoldKeyEngine is an optional interface implemented by Key engines that support holds on keys.
+//
+// A held key can't be disabled nor deleted: DisableKey and DeleteKey return ErrKeyOnHold error
+// and record the request, while DeleteUnusedKeys, DisableNamespace, DeleteNamespace,
+// and ExpiredKeys of ExpiringKeyEngine skip it.
+// Note that an already disabled key can be held, which prevents it from being hard deleted,
+// and that ReEnableKey cancels a recorded request.
+type HoldKeyEngine interface {
+	KeyEngine
+
+	// PlaceHold places a hold with the given reason on the key of the given keyID.
+	// It updates the reason if the key is already held.
+	//
+	// It returns ErrKeyNotFound error if the key doesn't exist or is already deleted.
+	PlaceHold(ctx context.Context, namespace, keyID, reason string) error
+
+	// ReleaseHold releases the hold of the given keyID, and returns it.
+	// It returns a zero KeyHold if the key is not held.
+	//
+	// It returns ErrKeyNotFound error if the key doesn't exist or is already deleted.
+	ReleaseHold(ctx context.Context, namespace, keyID string) (KeyHold, error)
+}
+
+// KeyPairGen presents a function used by Key engines to generate a new key along with its public key.
+type KeyPairGen func(ctx context.Context, namespace, keyID string) (key Key, publicKey Key, err error)
+
+// PublicKeyEngine is an optional interface implemented by Key engines that persist a public key along with each key,
+// which allows encrypting data using asymmetric encryption without being able to decrypt it.
+//
+// Public keys derive from the first version of keys. Similarly to keys, they are not returned
+// if keys are disabled or deleted, and they are removed once keys are deleted.
+type PublicKeyEngine interface {
+	KeyEngine
+
+	// GetOrCreatePublicKeys returns the public keys of the given keyIDs within the given namespace.
+	// It only reads persisted public keys, and never returns keys themselves.
+	//
+	// It creates new key pairs for the fresh new keyIDs using the given KeyPairGen, and persists the keys
+	// along with their public keys. Existing keys without a public key are omitted, see PutPublicKeys.
+	// Similarly to GetOrCreateKeys, it doesn't create a new key for a deleted keyID.
+	GetOrCreatePublicKeys(ctx context.Context, namespace string, keyIDs []string, keyPairGen KeyPairGen) (KeyMap, error)
+
+	// PutPublicKeys persists the given public keys of the active keys that don't have one yet,
+	// e.g., created using GetOrCreateKeys. It's meant to be called by services holding keys.
+	PutPublicKeys(ctx context.Context, namespace string, publicKeys KeyMap) error
+}
+
+// KeyMetadata presents the non-sensitive information of an encryption key.
+// It never contains the key's value.
+type KeyMetadata struct {
+	ID    string
+	State KeyState
+
+	// Version is the latest version of the key, it's always 1 if the engine is not versioned.
+	Version int
+
+	// Algorithm is the algorithm the key is meant for, e.g., "AES-256".
+	// It's inferred from the key's size, and may be empty if the key is deleted or unknown.
+	Algorithm string
+
+	CreatedAt  time.Time
+	DisabledAt time.Time
+	DeletedAt  time.Time
+
+	// PurgeAt is the time after which a disabled key is hard deleted by DeleteUnusedKeys.
+	// It's zero if the key is not disabled.
+	PurgeAt time.Time
+
+	// ExpiresAt is the scheduled expiry deadline of the key, see ExpiringKeyEngine.
+	// It's zero if no deadline is scheduled.
+	ExpiresAt time.Time
+
+	// Hold is the hold placed on the key, see HoldKeyEngine. It's nil if the key is not held.
+	Hold *KeyHold
+}
+
+// KeyFilter presents the criteria used to list keys.
+type KeyFilter struct {
+	// States filters keys by states. Keys of all states are listed if empty.
+	States []KeyState
+
+	// Limit is the max number of keys per page. A default limit is used if it's zero.
+	Limit int
+}
+
+// Match reports whether the given key metadata matches the filter.
+func (f KeyFilter) Match(m KeyMetadata) bool {
+	if len(f.States) == 0 {
+		return true
+	}
+	for _, state := range f.States {
+		if m.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyInspector is an optional interface implemented by Key engines that expose keys' metadata.
+type KeyInspector interface {
+	KeyEngine
+
+	// DescribeKeys returns the metadata of the given keyIDs within the given namespace, indexed by keyIDs.
+	// In contrast to GetKeys, it returns the metadata of disabled and deleted keys.
+	DescribeKeys(ctx context.Context, namespace string, keyIDs []string) (map[string]KeyMetadata, error)
+
+	// ListKeys returns a page of the keys' metadata of the given namespace matching the given filter.
+	// Keys are ordered by ID, and the returned cursor allows fetching the next page. The cursor is empty
+	// for the first page, and the returned one is empty if there are no more pages.
+	ListKeys(ctx context.Context, namespace string, filter KeyFilter, cursor string) (keys []KeyMetadata, next string, err error)
+}
+
+// KeyRewriteFunc presents a function used by KeyRewriter to compute the new value of a key.
+// It receives the key's current value and returns the new one.
+type KeyRewriteFunc func(keyID string, key Key) (Key, error)
+
+// KeyRewriter is an optional interface implemented by Key engines that allow
+// rewriting the stored values of existing keys.
+//
+// It's meant for wrappers that change how keys' values are encoded, e.g., envelope encryption,
+// without changing the underlying encryption key. Otherwise, existing data may no longer be decryptable.
+type KeyRewriter interface {
+	KeyEngine
+
+	// RewriteKeys applies the given function to the active and disabled keys of the given keyIDs,
+	// including all their versions if the engine is versioned, and persists the returned values. It rewrites all keys of the namespace if keyIDs is nil.
+	//
+	// Deleted keys are ignored, and a key is not persisted if its value doesn't change.
+	RewriteKeys(ctx context.Context, namespace string, keyIDs []string, fn KeyRewriteFunc) error
+}
+
 // KeyEngineWrapper presents a wrapper on top of an existing Key engine.
 // It overrides and enhances behaviors such as caching and
 // client-side encryption of keys' values.
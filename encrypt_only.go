@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"context"
+
+	"github.com/ln80/privacy-engine/core"
+)
+
+// EncryptOnlyProtector presents the service's interface used by producer services that encrypt subjects'
+// Personal data without being able to decrypt it, e.g., to limit the blast radius of their compromise.
+//
+// Personal data is encrypted using subjects' public keys, and is decrypted by a regular Protector
+// of the same namespace. Forgetting a subject crypto-erases it as its public key derives from its key.
+type EncryptOnlyProtector interface {
+
+	// Encrypt encrypts Personal data fields of the given structs pointers using subjects' public keys.
+	// It ensures idempotency and only encrypts fields once.
+	//
+	// Fields tagged with the 'deterministic' option are not supported, and make it fail.
+	// It also fills the blind index of fields tagged with the 'index' option, see BlindIndexer.BlindIndex.
+	Encrypt(ctx context.Context, structPts ...any) error
+
+	// EncryptValues encrypts the given standalone values using the given subject's public key,
+	// see ValuesProtector.EncryptValues.
+	EncryptValues(ctx context.Context, subjectID string, values ...string) ([]string, error)
+
+	// EncryptJSON encrypts the Personal data fields of the given JSON document using subjects' public keys,
+	// see JSONProtector.EncryptJSON.
+	EncryptJSON(ctx context.Context, doc []byte, spec JSONSpec) ([]byte, error)
+
+	// BlindIndex returns the blind index of the given value, see BlindIndexer.BlindIndex.
+	BlindIndex(ctx context.Context, value, normalize string) (string, error)
+}
+
+// PublicKeyPublisher is implemented by Protector services created using NewProtector.
+//
+// Key pairs of subjects are created by EncryptOnlyProtector services along with their keys,
+// while keys created by regular Protectors don't have a public key until it's published.
+type PublicKeyPublisher interface {
+
+	// PublishPublicKeys derives the public keys of the given subjects from their keys, and persists those missing,
+	// which allows EncryptOnlyProtector services to encrypt their Personal data.
+	//
+	// It requires a Key engine that implements core.PublicKeyEngine.
+	PublishPublicKeys(ctx context.Context, subjectIDs ...string) error
+}
+
+var _ PublicKeyPublisher = &protector{}
+
+// PublishPublicKeys implements PublicKeyPublisher
+func (p *protector) PublishPublicKeys(ctx context.Context, subjectIDs ...string) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrPublishPublicKeyFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	pke, ok := p.KeyEngine.(core.PublicKeyEngine)
+	if !ok || p.encryptOnly {
+		return core.ErrUnsupported
+	}
+
+	keys, err := p.KeyEngine.GetKeys(ctx, p.namespace, subjectIDs)
+	if err != nil {
+		return err
+	}
+
+	enc := p.PublicKeyEncryptor.(core.PublicKeyEncryptor)
+	pubs := core.NewKeyMap()
+	for subID, key := range keys {
+		if pubs[subID], err = enc.PublicKey(key); err != nil {
+			return err
+		}
+	}
+
+	return pke.PutPublicKeys(ctx, p.namespace, pubs)
+}
+
+// NewEncryptOnlyProtector returns an EncryptOnlyProtector service instance.
+// It requires a Key engine that implements core.PublicKeyEngine, and accepts the same options as NewProtector.
+//
+// It uses the 'X25519 AES 256 GCM' public key Encryptor by default, see ProtectorConfig.PublicKeyEncryptor.
+// It panics if the given engine is nil or doesn't support public keys.
+//
+// Note that it only fetches public keys from the Key engine, and that subjects' keys created by regular Protectors
+// must be published first, see PublicKeyPublisher. Still, restricting the access of producer services
+// to the persisted keys is up to the Key engine's deployment.
+func NewEncryptOnlyProtector(namespace string, engine core.KeyEngine, opts ...func(*ProtectorConfig)) EncryptOnlyProtector {
+	p := newProtector(namespace, engine, opts...)
+
+	origin := p.KeyEngine
+	if c, ok := origin.(core.KeyEngineCache); ok && c.Origin() != nil {
+		origin = c.Origin()
+	}
+	if _, ok := origin.(core.PublicKeyEngine); !ok {
+		panic("invalid Key Engine service, public keys not supported")
+	}
+
+	p.encryptOnly = true
+	return p
+}
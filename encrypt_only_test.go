@@ -0,0 +1,201 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+	"github.com/ln80/privacy-engine/memory"
+)
+
+func TestProtector_EncryptOnly(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-pub1k3"
+
+	engine := memory.NewKeyEngine()
+
+	withFieldBinding := func(pc *ProtectorConfig) {
+		pc.Binding = BindField
+	}
+
+	producer := NewEncryptOnlyProtector(nspace, engine, withFieldBinding)
+	consumer := NewProtector(nspace, engine, withFieldBinding)
+
+	prf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+		Address: Address{
+			Street: "Baker Street",
+		},
+	}
+	oprf := prf
+
+	if err := producer.Encrypt(ctx, &prf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err := parseWireFormatWithParams(prf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := aes.IDX25519, params[paramAlgorithm]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert idempotency
+	eprf := prf
+	if err := producer.Encrypt(ctx, &prf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := eprf, prf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the consumer decrypts the producer's values
+	if err := consumer.Decrypt(ctx, &prf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oprf, prf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert values encrypted by the producer and the consumer are decryptable after a key rotation
+	if err := consumer.RotateKey(ctx, prf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	prf2 := oprf
+	if err := producer.Encrypt(ctx, &prf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := consumer.Decrypt(ctx, &prf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oprf, prf2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert deterministic fields are not supported
+	acc := Account{
+		UserID:   "kal5430",
+		Email:    "idir@example.com",
+		Fullname: "Idir Moore",
+	}
+	if err := producer.Encrypt(ctx, &acc); !errors.Is(err, core.ErrEncryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrEncryptionFailure, err)
+	}
+
+	// assert forgetting the subject crypto-erases the producer's values
+	if err := consumer.Forget(ctx, prf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	prf = eprf
+	if err := consumer.Decrypt(ctx, &prf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "deleted pii", prf.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the producer can't encrypt values of a forgotten subject
+	prf = oprf
+	if err := producer.Encrypt(ctx, &prf); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+}
+
+// keylessEngine presents a public Key engine that fails the test if keys are fetched.
+type keylessEngine struct {
+	core.PublicKeyEngine
+	t *testing.T
+}
+
+func (e *keylessEngine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	e.t.Fatalf("expect GetKeys not be called, got: %v", keyIDs)
+	return nil, nil
+}
+
+func (e *keylessEngine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	e.t.Fatalf("expect GetOrCreateKeys not be called, got: %v", keyIDs)
+	return nil, nil
+}
+
+func TestProtector_EncryptOnly_Keyless(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-pub1k4"
+
+	engine := memory.NewKeyEngine()
+
+	producer := NewEncryptOnlyProtector(nspace, &keylessEngine{PublicKeyEngine: engine.(core.PublicKeyEngine), t: t})
+	consumer := NewProtector(nspace, engine)
+
+	// assert subjects' keys created by the consumer are not used until their public keys are published
+	prf := Profile{UserID: "kal5431", Fullname: "Idir Moore"}
+	if err := consumer.Encrypt(ctx, &Profile{UserID: prf.UserID, Fullname: "Idir Moore"}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := producer.Encrypt(ctx, &prf); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+	if err := consumer.(PublicKeyPublisher).PublishPublicKeys(ctx, prf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert the producer encrypts data of both existing and fresh new subjects using public keys only
+	prf2 := Profile{UserID: "kal5432", Fullname: "Ali Moore"}
+	if err := producer.Encrypt(ctx, &prf, &prf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	values, err := producer.EncryptValues(ctx, "kal5433", "Baker Street")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	doc, err := producer.EncryptJSON(ctx, []byte(`{"id":"kal5434","name":"Sara Moore"}`), JSONSpec{SubjectID: "$.id", Fields: []string{"$.name"}})
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	if err := consumer.Decrypt(ctx, &prf, &prf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "Idir Moore", prf.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "Ali Moore", prf2.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if values, err = consumer.(ValuesProtector).DecryptValues(ctx, values...); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "Baker Street", values[0]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if doc, err = consumer.(JSONProtector).DecryptJSON(ctx, doc, JSONSpec{Fields: []string{"$.name"}}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := `{"id":"kal5434","name":"Sara Moore"}`, string(doc); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the producer can't publish public keys
+	if err := producer.(PublicKeyPublisher).PublishPublicKeys(ctx, prf.UserID); !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func TestNewEncryptOnlyProtector_Panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expect to panic")
+		}
+	}()
+
+	// hide the engine's optional interfaces
+	engine := struct{ core.KeyEngine }{memory.NewKeyEngine()}
+
+	NewEncryptOnlyProtector("tenant-pub1k3", engine)
+}
@@ -0,0 +1,115 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+)
+
+// Errors returned by EncryptorRegistry service
+var (
+	ErrInvalidEncryptorID     = errors.New("invalid encryptor ID")
+	ErrEncryptorAlreadyExists = errors.New("encryptor already registered")
+	ErrEncryptorNotFound      = errors.New("encryptor not found")
+)
+
+var (
+	encryptorIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// EncryptorRegistry maps algorithm IDs to Encryptors, and marks one of them as the default one used for new writes.
+//
+// Algorithm IDs are recorded along with cipher texts, therefore they must remain stable
+// as long as data encrypted using the associated Encryptor exists.
+type EncryptorRegistry interface {
+
+	// Register registers the given Encryptor under the given algorithm ID, which only contains
+	// [A-Za-z0-9._-] characters. It fails if the ID is already registered, and panics if the Encryptor is nil.
+	Register(id string, enc core.Encryptor) error
+
+	// SetDefault marks the Encryptor registered under the given ID as the default one.
+	SetDefault(id string) error
+
+	// Lookup returns the Encryptor registered under the given ID.
+	Lookup(id string) (core.Encryptor, bool)
+
+	// Default returns the default Encryptor and its ID.
+	Default() (string, core.Encryptor)
+}
+
+type encryptorRegistry struct {
+	mu         sync.RWMutex
+	encryptors map[string]core.Encryptor
+	defaultID  string
+}
+
+var _ EncryptorRegistry = &encryptorRegistry{}
+
+// NewEncryptorRegistry returns a thread-safe EncryptorRegistry instance.
+//
+// It ships with the 'AES 256 GCM' Encryptor registered under aes.ID256GCM ID, and used as the default one,
+// and the 'AES 256 GCM SIV' Encryptor registered under aes.ID256GCMSIV ID as an alternative.
+func NewEncryptorRegistry() EncryptorRegistry {
+	return &encryptorRegistry{
+		encryptors: map[string]core.Encryptor{
+			aes.ID256GCM:    aes.New256GCMEncryptor(),
+			aes.ID256GCMSIV: aes.New256GCMSIVEncryptor(),
+		},
+		defaultID: aes.ID256GCM,
+	}
+}
+
+// Register implements EncryptorRegistry interface
+func (r *encryptorRegistry) Register(id string, enc core.Encryptor) error {
+	if enc == nil {
+		panic("invalid Encryptor service, nil value found")
+	}
+	if !encryptorIDRegex.MatchString(id) {
+		return fmt.Errorf("%w: '%s'", ErrInvalidEncryptorID, id)
+	}
+	if encID := core.EncryptorID(enc); encID != "" && encID != id {
+		return fmt.Errorf("%w: '%s' identifies itself as '%s'", ErrInvalidEncryptorID, id, encID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.encryptors[id]; ok {
+		return fmt.Errorf("%w: '%s'", ErrEncryptorAlreadyExists, id)
+	}
+	r.encryptors[id] = enc
+	return nil
+}
+
+// SetDefault implements EncryptorRegistry interface
+func (r *encryptorRegistry) SetDefault(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.encryptors[id]; !ok {
+		return fmt.Errorf("%w: '%s'", ErrEncryptorNotFound, id)
+	}
+	r.defaultID = id
+	return nil
+}
+
+// Lookup implements EncryptorRegistry interface
+func (r *encryptorRegistry) Lookup(id string) (core.Encryptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enc, ok := r.encryptors[id]
+	return enc, ok
+}
+
+// Default implements EncryptorRegistry interface
+func (r *encryptorRegistry) Default() (string, core.Encryptor) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.defaultID, r.encryptors[r.defaultID]
+}
@@ -0,0 +1,131 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/memory"
+	"github.com/ln80/privacy-engine/privacytest"
+)
+
+func TestEncryptorRegistry(t *testing.T) {
+	r := NewEncryptorRegistry()
+
+	// assert the registry ships with 'AES 256 GCM' as default
+	id, enc := r.Default()
+	if want, got := aes.ID256GCM, id; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if enc == nil {
+		t.Fatal("expect default encryptor be not nil")
+	}
+
+	// assert the registry ships with 'AES 256 GCM SIV' as an alternative
+	if _, ok := r.Lookup(aes.ID256GCMSIV); !ok {
+		t.Fatalf("expect %s encryptor be registered", aes.ID256GCMSIV)
+	}
+
+	mock := &privacytest.UnstableEncryptorMock{PointOfFailure: 100}
+
+	if err := r.Register("mock:1", mock); !errors.Is(err, ErrInvalidEncryptorID) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidEncryptorID, err)
+	}
+	if err := r.Register("mock", aes.New256GCMEncryptor()); !errors.Is(err, ErrInvalidEncryptorID) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidEncryptorID, err)
+	}
+	if err := r.Register(aes.ID256GCM, mock); !errors.Is(err, ErrEncryptorAlreadyExists) {
+		t.Fatalf("expect err be %v, got %v", ErrEncryptorAlreadyExists, err)
+	}
+	if err := r.SetDefault("mock"); !errors.Is(err, ErrEncryptorNotFound) {
+		t.Fatalf("expect err be %v, got %v", ErrEncryptorNotFound, err)
+	}
+
+	if err := r.Register("mock", mock); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if enc, ok := r.Lookup("mock"); !ok || enc != mock {
+		t.Fatalf("expect %v, %v be equals", mock, enc)
+	}
+	if err := r.SetDefault("mock"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if id, _ := r.Default(); id != "mock" {
+		t.Fatalf("expect %v, %v be equals", "mock", id)
+	}
+}
+
+func TestProtector_EncryptorRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-r3g1st"
+
+	engine := memory.NewKeyEngine()
+
+	r := NewEncryptorRegistry()
+	if err := r.Register("mock", &privacytest.UnstableEncryptorMock{PointOfFailure: 100}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := r.SetDefault("mock"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	p := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Encryptors = r
+	})
+
+	pf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf := pf
+
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err := parseWireFormatWithParams(pf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "mock", params[paramAlgorithm]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// switch the default algorithm for new writes
+	if err := r.SetDefault(aes.ID256GCMSIV); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert values are decrypted using the recorded algorithm
+	epf := pf
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert values are migrated to the default algorithm
+	pf = epf
+	if err := p.(Reencrypter).Reencrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err = parseWireFormatWithParams(pf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := aes.ID256GCMSIV, params[paramAlgorithm]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if err := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Encryptors = NewEncryptorRegistry()
+	}).Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
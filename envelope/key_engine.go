@@ -0,0 +1,402 @@
+// Package envelope provides a Key engine wrapper that implements envelope encryption,
+// i.e., subject keys are wrapped by a key-encryption key before being persisted by the origin engine.
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+)
+
+const envelopeVersion byte = 1
+
+var errInvalidWrappedKey = errors.New("invalid wrapped key format")
+
+// Wrapper is a core.KeyEngineWrapper that wraps subject keys using a KeyWrapper
+// before passing them to the origin engine, and unwraps them on read.
+//
+// The origin engine only holds wrapped keys along with the ID of the key-encryption key used.
+type Wrapper struct {
+	origin  core.KeyEngine
+	wrapper KeyWrapper
+}
+
+var _ core.KeyEngineWrapper = &Wrapper{}
+var _ core.VersionedKeyEngine = &Wrapper{}
+var _ core.NamespaceKeyEngine = &Wrapper{}
+var _ core.ExpiringKeyEngine = &Wrapper{}
+var _ core.HoldKeyEngine = &Wrapper{}
+var _ core.PublicKeyEngine = &Wrapper{}
+
+// NewWrapper returns an envelope encryption wrapper on top of the given core.KeyEngine.
+// It panics if the origin engine or the key wrapper is nil.
+func NewWrapper(origin core.KeyEngine, wrapper KeyWrapper) *Wrapper {
+	if origin == nil {
+		panic("invalid origin Key Engine, nil value found")
+	}
+	if wrapper == nil {
+		panic("invalid Key Wrapper, nil value found")
+	}
+	return &Wrapper{
+		origin:  origin,
+		wrapper: wrapper,
+	}
+}
+
+// Origin implements core.KeyEngineWrapper
+func (w *Wrapper) Origin() core.KeyEngine {
+	return w.origin
+}
+
+// GetKeys implements core.KeyEngine
+func (w *Wrapper) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	keys, err := w.origin.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.unwrapKeys(ctx, namespace, keys, core.ErrGetKeyFailure)
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (w *Wrapper) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	keys, err := w.origin.GetOrCreateKeys(ctx, namespace, keyIDs, w.wrapKeyGen(keyGen))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.unwrapKeys(ctx, namespace, keys, core.ErrGetKeyFailure)
+}
+
+// DisableKey implements core.KeyEngine
+func (w *Wrapper) DisableKey(ctx context.Context, namespace, keyID string) error {
+	return w.origin.DisableKey(ctx, namespace, keyID)
+}
+
+// ReEnableKey implements core.KeyEngine
+func (w *Wrapper) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	return w.origin.ReEnableKey(ctx, namespace, keyID)
+}
+
+// DeleteKey implements core.KeyEngine
+func (w *Wrapper) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	return w.origin.DeleteKey(ctx, namespace, keyID)
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+func (w *Wrapper) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	return w.origin.DeleteUnusedKeys(ctx, namespace)
+}
+
+// DisableNamespace implements core.NamespaceKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (w *Wrapper) DisableNamespace(ctx context.Context, namespace string) error {
+	nke, ok := w.origin.(core.NamespaceKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrDisableKeyFailure, core.ErrUnsupported)
+	}
+	return nke.DisableNamespace(ctx, namespace)
+}
+
+// ReEnableNamespace implements core.NamespaceKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (w *Wrapper) ReEnableNamespace(ctx context.Context, namespace string) error {
+	nke, ok := w.origin.(core.NamespaceKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrReEnableKeyFailure, core.ErrUnsupported)
+	}
+	return nke.ReEnableNamespace(ctx, namespace)
+}
+
+// DeleteNamespace implements core.NamespaceKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (w *Wrapper) DeleteNamespace(ctx context.Context, namespace string) error {
+	nke, ok := w.origin.(core.NamespaceKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrDeleteKeyFailure, core.ErrUnsupported)
+	}
+	return nke.DeleteNamespace(ctx, namespace)
+}
+
+// ScheduleKeyExpiry implements core.ExpiringKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (w *Wrapper) ScheduleKeyExpiry(ctx context.Context, namespace, keyID string, at time.Time) error {
+	eke, ok := w.origin.(core.ExpiringKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrScheduleKeyFailure, core.ErrUnsupported)
+	}
+	return eke.ScheduleKeyExpiry(ctx, namespace, keyID, at)
+}
+
+// ExpiredKeys implements core.ExpiringKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (w *Wrapper) ExpiredKeys(ctx context.Context, namespace string, at time.Time, retention time.Duration) ([]string, error) {
+	eke, ok := w.origin.(core.ExpiringKeyEngine)
+	if !ok {
+		return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+	}
+	return eke.ExpiredKeys(ctx, namespace, at, retention)
+}
+
+// KeyExpired implements core.ExpiringKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (w *Wrapper) KeyExpired(ctx context.Context, namespace, keyID string, at time.Time, retention time.Duration) (bool, error) {
+	eke, ok := w.origin.(core.ExpiringKeyEngine)
+	if !ok {
+		return false, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+	}
+	return eke.KeyExpired(ctx, namespace, keyID, at, retention)
+}
+
+// PlaceHold implements core.HoldKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.HoldKeyEngine.
+func (w *Wrapper) PlaceHold(ctx context.Context, namespace, keyID, reason string) error {
+	hke, ok := w.origin.(core.HoldKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrHoldKeyFailure, core.ErrUnsupported)
+	}
+	return hke.PlaceHold(ctx, namespace, keyID, reason)
+}
+
+// ReleaseHold implements core.HoldKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.HoldKeyEngine.
+func (w *Wrapper) ReleaseHold(ctx context.Context, namespace, keyID string) (core.KeyHold, error) {
+	hke, ok := w.origin.(core.HoldKeyEngine)
+	if !ok {
+		return core.KeyHold{}, errors.Join(core.ErrHoldKeyFailure, core.ErrUnsupported)
+	}
+	return hke.ReleaseHold(ctx, namespace, keyID)
+}
+
+// GetOrCreatePublicKeys implements core.PublicKeyEngine
+//
+// New keys are wrapped before being persisted, while their public keys are stored in plain by the origin engine.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.PublicKeyEngine.
+func (w *Wrapper) GetOrCreatePublicKeys(ctx context.Context, namespace string, keyIDs []string, keyPairGen core.KeyPairGen) (core.KeyMap, error) {
+	pke, ok := w.origin.(core.PublicKeyEngine)
+	if !ok {
+		return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+	}
+	return pke.GetOrCreatePublicKeys(ctx, namespace, keyIDs,
+		func(ctx context.Context, namespace, keyID string) (core.Key, core.Key, error) {
+			key, pub, err := keyPairGen(ctx, namespace, keyID)
+			if err != nil {
+				return "", "", err
+			}
+			wrapped, err := w.wrap(ctx, namespace, keyID, key)
+			if err != nil {
+				return "", "", err
+			}
+			return core.Key(wrapped), pub, nil
+		})
+}
+
+// PutPublicKeys implements core.PublicKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.PublicKeyEngine.
+func (w *Wrapper) PutPublicKeys(ctx context.Context, namespace string, publicKeys core.KeyMap) error {
+	pke, ok := w.origin.(core.PublicKeyEngine)
+	if !ok {
+		return errors.Join(core.ErrPersistKeyFailure, core.ErrUnsupported)
+	}
+	return pke.PutPublicKeys(ctx, namespace, publicKeys)
+}
+
+// GetOrCreateLatestKeys implements core.VersionedKeyEngine
+//
+// It falls back to the first version of keys if the origin engine is not versioned.
+func (w *Wrapper) GetOrCreateLatestKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	versioned, ok := w.origin.(core.VersionedKeyEngine)
+	if !ok {
+		keys, err := w.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+		if err != nil {
+			return nil, err
+		}
+		latest := make(core.VersionedKeyMap)
+		for keyID, k := range keys {
+			latest[keyID] = core.VersionedKey{Version: 1, Key: k}
+		}
+		return latest, nil
+	}
+
+	latest, err := versioned.GetOrCreateLatestKeys(ctx, namespace, keyIDs, w.wrapKeyGen(keyGen))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.unwrapVersionedKeys(ctx, namespace, latest)
+}
+
+// GetKeyVersions implements core.VersionedKeyEngine
+//
+// It falls back to the first version of keys if the origin engine is not versioned.
+func (w *Wrapper) GetKeyVersions(ctx context.Context, namespace string, keyIDs []string) (core.KeyVersionsMap, error) {
+	versioned, ok := w.origin.(core.VersionedKeyEngine)
+	if !ok {
+		keys, err := w.GetKeys(ctx, namespace, keyIDs)
+		if err != nil {
+			return nil, err
+		}
+		versions := make(core.KeyVersionsMap)
+		for keyID, k := range keys {
+			versions[keyID] = map[int]core.Key{1: k}
+		}
+		return versions, nil
+	}
+
+	versions, err := versioned.GetKeyVersions(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(core.KeyVersionsMap)
+	for keyID, kv := range versions {
+		result[keyID] = make(map[int]core.Key)
+		for version, stored := range kv {
+			key, err := w.unwrap(ctx, namespace, keyID, stored)
+			if err != nil {
+				return nil, errors.Join(core.ErrGetKeyFailure, err)
+			}
+			result[keyID][version] = key
+		}
+	}
+	return result, nil
+}
+
+// RotateKeys implements core.VersionedKeyEngine
+//
+// It returns core.ErrUnsupported error if the origin engine is not versioned.
+func (w *Wrapper) RotateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	versioned, ok := w.origin.(core.VersionedKeyEngine)
+	if !ok {
+		return nil, errors.Join(core.ErrRotateKeyFailure, core.ErrUnsupported)
+	}
+
+	latest, err := versioned.RotateKeys(ctx, namespace, keyIDs, w.wrapKeyGen(keyGen))
+	if err != nil {
+		return nil, err
+	}
+
+	return w.unwrapVersionedKeys(ctx, namespace, latest)
+}
+
+// RewrapKeys wraps again the keys of the given keyIDs using the current key-encryption key.
+// It rewraps all keys of the namespace if keyIDs is nil.
+//
+// It's meant to be used after a KEK rotation; subject keys themselves don't change,
+// and keys already wrapped by the current KEK are skipped.
+// The origin engine must implement core.KeyRewriter, otherwise core.ErrUnsupported is returned.
+func (w *Wrapper) RewrapKeys(ctx context.Context, namespace string, keyIDs []string) error {
+	rewriter, ok := w.origin.(core.KeyRewriter)
+	if !ok {
+		return errors.Join(core.ErrRewriteKeyFailure, core.ErrUnsupported)
+	}
+
+	return rewriter.RewriteKeys(ctx, namespace, keyIDs, func(keyID string, stored core.Key) (core.Key, error) {
+		kekID, wrapped, err := decodeEnvelope(stored)
+		if err != nil {
+			return "", err
+		}
+		if kekID == w.wrapper.KEKID() {
+			return stored, nil
+		}
+		key, err := w.wrapper.UnwrapKey(ctx, namespace, keyID, kekID, wrapped)
+		if err != nil {
+			return "", err
+		}
+		rewrapped, err := w.wrap(ctx, namespace, keyID, key)
+		if err != nil {
+			return "", err
+		}
+		return core.Key(rewrapped), nil
+	})
+}
+
+// wrapKeyGen returns a core.KeyGen that wraps the keys generated by the given one.
+func (w *Wrapper) wrapKeyGen(keyGen core.KeyGen) core.KeyGen {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	return func(ctx context.Context, namespace, keyID string) (string, error) {
+		key, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return "", err
+		}
+		return w.wrap(ctx, namespace, keyID, core.Key(key))
+	}
+}
+
+func (w *Wrapper) wrap(ctx context.Context, namespace, keyID string, key core.Key) (string, error) {
+	wrapped, err := w.wrapper.WrapKey(ctx, namespace, keyID, key)
+	if err != nil {
+		return "", err
+	}
+	return encodeEnvelope(w.wrapper.KEKID(), wrapped), nil
+}
+
+func (w *Wrapper) unwrap(ctx context.Context, namespace, keyID string, stored core.Key) (core.Key, error) {
+	kekID, wrapped, err := decodeEnvelope(stored)
+	if err != nil {
+		return "", err
+	}
+	return w.wrapper.UnwrapKey(ctx, namespace, keyID, kekID, wrapped)
+}
+
+func (w *Wrapper) unwrapKeys(ctx context.Context, namespace string, keys core.KeyMap, failure error) (core.KeyMap, error) {
+	result := core.NewKeyMap()
+	for keyID, stored := range keys {
+		key, err := w.unwrap(ctx, namespace, keyID, stored)
+		if err != nil {
+			return nil, errors.Join(failure, err)
+		}
+		result[keyID] = key
+	}
+	return result, nil
+}
+
+func (w *Wrapper) unwrapVersionedKeys(ctx context.Context, namespace string, keys core.VersionedKeyMap) (core.VersionedKeyMap, error) {
+	result := make(core.VersionedKeyMap)
+	for keyID, vk := range keys {
+		key, err := w.unwrap(ctx, namespace, keyID, vk.Key)
+		if err != nil {
+			return nil, errors.Join(core.ErrGetKeyFailure, err)
+		}
+		result[keyID] = core.VersionedKey{Version: vk.Version, Key: key}
+	}
+	return result, nil
+}
+
+// encodeEnvelope encodes the wrapped key along with the ID of its key-encryption key:
+// version (1 byte) | kekID length (1 byte) | kekID | wrapped key; the result is base64 encoded.
+func encodeEnvelope(kekID string, wrapped []byte) string {
+	b := make([]byte, 0, 2+len(kekID)+len(wrapped))
+	b = append(b, envelopeVersion, byte(len(kekID)))
+	b = append(b, kekID...)
+	b = append(b, wrapped...)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func decodeEnvelope(stored core.Key) (kekID string, wrapped []byte, err error) {
+	b, err := base64.StdEncoding.DecodeString(string(stored))
+	if err != nil {
+		return "", nil, errors.Join(errInvalidWrappedKey, err)
+	}
+	if len(b) < 2 || b[0] != envelopeVersion || len(b) < 2+int(b[1]) {
+		return "", nil, errInvalidWrappedKey
+	}
+	n := int(b[1])
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
@@ -0,0 +1,178 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ln80/privacy-engine/core"
+	"github.com/ln80/privacy-engine/memory"
+	"github.com/ln80/privacy-engine/privacytest"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	gracePeriod := 100 * time.Millisecond
+
+	withGracePeriod := func(c *core.KeyEngineConfig) {
+		c.GracePeriod = gracePeriod
+	}
+
+	kek := KEK{ID: "kek-1", Key: bytes.Repeat([]byte{1}, 32)}
+
+	wrappers := map[string]KeyWrapper{
+		"aes-gcm": NewAESGCMKeyWrapper(kek),
+		"aes-kw":  NewAESKWKeyWrapper(kek),
+	}
+
+	for name, kw := range wrappers {
+		t.Run("envelope wrapper engine with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+				c.GracePeriod = gracePeriod
+			})
+		})
+
+		t.Run("versioned envelope wrapper engine with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunVersionedKeyEngineTest(t, ctx, eng)
+		})
+
+		t.Run("envelope wrapper engine namespace with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunNamespaceKeyEngineTest(t, ctx, eng)
+		})
+
+		t.Run("envelope wrapper engine expiry with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunExpiringKeyEngineTest(t, ctx, eng)
+		})
+
+		t.Run("envelope wrapper engine hold with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunHoldKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+				c.GracePeriod = gracePeriod
+			})
+		})
+
+		t.Run("envelope wrapper engine public keys with "+name, func(t *testing.T) {
+			eng := NewWrapper(memory.NewKeyEngine(withGracePeriod), kw)
+
+			privacytest.RunPublicKeyEngineTest(t, ctx, eng)
+		})
+
+		t.Run("cache on top of envelope wrapper engine with "+name, func(t *testing.T) {
+			eng := memory.NewCacheWrapper(NewWrapper(memory.NewKeyEngine(withGracePeriod), kw), 20*time.Minute)
+
+			privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+				c.GracePeriod = gracePeriod
+			})
+		})
+	}
+}
+
+func TestKeyEngine_Wrapping(t *testing.T) {
+	ctx := context.Background()
+
+	namespace, keyIDs := "tenant-q9x2a1", []string{"sub-1", "sub-2"}
+
+	kek1 := KEK{ID: "kek-1", Key: bytes.Repeat([]byte{1}, 32)}
+	kek2 := KEK{ID: "kek-2", Key: bytes.Repeat([]byte{2}, 32)}
+
+	origin := memory.NewKeyEngine()
+
+	eng := NewWrapper(origin, NewAESGCMKeyWrapper(kek1))
+
+	keys, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert the origin engine only holds wrapped keys
+	stored, err := origin.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, keyID := range keyIDs {
+		if stored[keyID] == keys[keyID] {
+			t.Fatalf("expect %s key be wrapped", keyID)
+		}
+		kekID, _, err := decodeEnvelope(stored[keyID])
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := kek1.ID, kekID; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// assert a wrapped key is bound to its subject
+	if _, err := eng.wrapper.UnwrapKey(ctx, namespace, keyIDs[1], kek1.ID, mustWrapped(t, stored[keyIDs[0]])); !errors.Is(err, ErrUnwrapKeyFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrUnwrapKeyFailure, err)
+	}
+
+	// rotate KEK: the new KEK is current, while the old one is kept to unwrap existing keys
+	rotated := NewWrapper(origin, NewAESGCMKeyWrapper(kek2, kek1))
+
+	got, err := rotated.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !keysEqual(keys, got) {
+		t.Fatalf("expect %v, %v be equals", keys, got)
+	}
+
+	// the old KEK is no more available before rewrapping keys
+	if _, err := NewWrapper(origin, NewAESGCMKeyWrapper(kek2)).GetKeys(ctx, namespace, keyIDs); !errors.Is(err, ErrKEKNotFound) {
+		t.Fatalf("expect err be %v, got %v", ErrKEKNotFound, err)
+	}
+
+	if err := rotated.RewrapKeys(ctx, namespace, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert data keys don't change, and are readable using only the new KEK
+	got, err = NewWrapper(origin, NewAESGCMKeyWrapper(kek2)).GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !keysEqual(keys, got) {
+		t.Fatalf("expect %v, %v be equals", keys, got)
+	}
+
+	// assert rewrapping is not supported if the origin engine can't rewrite keys
+	noRewriter := NewWrapper(struct{ core.KeyEngine }{origin}, NewAESGCMKeyWrapper(kek2))
+	if err := noRewriter.RewrapKeys(ctx, namespace, nil); !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func mustWrapped(t *testing.T, stored core.Key) []byte {
+	t.Helper()
+
+	_, wrapped, err := decodeEnvelope(stored)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	return wrapped
+}
+
+func keysEqual(want, got core.KeyMap) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for id, k := range want {
+		if got[id] != k {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	paes "github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+)
+
+// Errors returned by KeyWrapper implementations
+var (
+	ErrWrapKeyFailure   = errors.New("failed to wrap encryption key")
+	ErrUnwrapKeyFailure = errors.New("failed to unwrap encryption key")
+	ErrKEKNotFound      = errors.New("key-encryption key not found")
+)
+
+// KeyWrapper presents the service responsible for wrapping, i.e., encrypting,
+// subject keys with a key-encryption key (KEK).
+//
+// Implementations may rely on a local keyring or a remote KMS.
+type KeyWrapper interface {
+
+	// KEKID returns the ID of the current key-encryption key, which is used to wrap keys.
+	KEKID() string
+
+	// WrapKey wraps the given key using the current key-encryption key.
+	WrapKey(ctx context.Context, namespace, keyID string, key core.Key) ([]byte, error)
+
+	// UnwrapKey unwraps the given wrapped key using the key-encryption key of the given kekID.
+	UnwrapKey(ctx context.Context, namespace, keyID, kekID string, wrapped []byte) (core.Key, error)
+}
+
+// KEK presents a key-encryption key, and its unique ID.
+type KEK struct {
+	ID  string
+	Key []byte
+}
+
+type keyring struct {
+	current string
+	keks    map[string]cipher.Block
+}
+
+func newKeyring(current KEK, previous []KEK) keyring {
+	kr := keyring{
+		current: current.ID,
+		keks:    make(map[string]cipher.Block),
+	}
+	for _, kek := range append([]KEK{current}, previous...) {
+		if kek.ID == "" || len(kek.ID) > 255 {
+			panic("invalid key-encryption key ID, its length must be between 1 and 255")
+		}
+		if _, ok := kr.keks[kek.ID]; ok {
+			panic(fmt.Sprintf("duplicate key-encryption key ID '%s'", kek.ID))
+		}
+		block, err := aes.NewCipher(kek.Key)
+		if err != nil {
+			panic(fmt.Sprintf("invalid key-encryption key '%s': %v", kek.ID, err))
+		}
+		kr.keks[kek.ID] = block
+	}
+	return kr
+}
+
+func (kr keyring) KEKID() string {
+	return kr.current
+}
+
+func (kr keyring) block(kekID string) (cipher.Block, error) {
+	block, ok := kr.keks[kekID]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrKEKNotFound, kekID)
+	}
+	return block, nil
+}
+
+type gcmKeyWrapper struct {
+	keyring
+}
+
+var _ KeyWrapper = &gcmKeyWrapper{}
+
+// NewAESGCMKeyWrapper returns a local KeyWrapper that wraps keys using AES-GCM.
+// The namespace and key ID are authenticated, which binds a wrapped key to its subject.
+//
+// The current KEK is used to wrap keys, while previous ones are only used to unwrap keys
+// wrapped before a rotation. It panics if a KEK is invalid.
+func NewAESGCMKeyWrapper(current KEK, previous ...KEK) KeyWrapper {
+	return &gcmKeyWrapper{keyring: newKeyring(current, previous)}
+}
+
+// WrapKey implements KeyWrapper
+func (w *gcmKeyWrapper) WrapKey(ctx context.Context, namespace, keyID string, key core.Key) ([]byte, error) {
+	block, err := w.block(w.current)
+	if err != nil {
+		return nil, errors.Join(ErrWrapKeyFailure, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Join(ErrWrapKeyFailure, err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Join(ErrWrapKeyFailure, err)
+	}
+
+	return aead.Seal(nonce, nonce, []byte(key), additionalData(namespace, keyID)), nil
+}
+
+// UnwrapKey implements KeyWrapper
+func (w *gcmKeyWrapper) UnwrapKey(ctx context.Context, namespace, keyID, kekID string, wrapped []byte) (core.Key, error) {
+	block, err := w.block(kekID)
+	if err != nil {
+		return "", errors.Join(ErrUnwrapKeyFailure, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", errors.Join(ErrUnwrapKeyFailure, err)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return "", errors.Join(ErrUnwrapKeyFailure, errInvalidWrappedKey)
+	}
+
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, ciphertext, additionalData(namespace, keyID))
+	if err != nil {
+		return "", errors.Join(ErrUnwrapKeyFailure, err)
+	}
+
+	return core.Key(key), nil
+}
+
+// additionalData returns an unambiguous encoding of the namespace and the key ID.
+func additionalData(namespace, keyID string) []byte {
+	aad := make([]byte, 0, 8+len(namespace)+len(keyID))
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(namespace)))
+	aad = append(aad, namespace...)
+	aad = binary.BigEndian.AppendUint32(aad, uint32(len(keyID)))
+	return append(aad, keyID...)
+}
+
+type kwKeyWrapper struct {
+	keyring
+	keks map[string][]byte
+}
+
+var _ KeyWrapper = &kwKeyWrapper{}
+
+// NewAESKWKeyWrapper returns a local KeyWrapper that wraps keys using AES Key Wrap (RFC 3394).
+// Wrapped keys' sizes must be a multiple of 8 bytes, which is the case of AES keys.
+//
+// Note that, unlike AES-GCM, AES Key Wrap doesn't bind a wrapped key to its subject.
+//
+// The current KEK is used to wrap keys, while previous ones are only used to unwrap keys
+// wrapped before a rotation. It panics if a KEK is invalid.
+func NewAESKWKeyWrapper(current KEK, previous ...KEK) KeyWrapper {
+	w := &kwKeyWrapper{
+		keyring: newKeyring(current, previous),
+		keks:    make(map[string][]byte),
+	}
+	for _, kek := range append([]KEK{current}, previous...) {
+		w.keks[kek.ID] = kek.Key
+	}
+	return w
+}
+
+// WrapKey implements KeyWrapper
+func (w *kwKeyWrapper) WrapKey(ctx context.Context, namespace, keyID string, key core.Key) ([]byte, error) {
+	wrapped, err := paes.WrapKey(w.keks[w.current], []byte(key))
+	if err != nil {
+		return nil, errors.Join(ErrWrapKeyFailure, err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapKey implements KeyWrapper
+func (w *kwKeyWrapper) UnwrapKey(ctx context.Context, namespace, keyID, kekID string, wrapped []byte) (core.Key, error) {
+	kek, ok := w.keks[kekID]
+	if !ok {
+		return "", errors.Join(ErrUnwrapKeyFailure, fmt.Errorf("%w: '%s'", ErrKEKNotFound, kekID))
+	}
+	key, err := paes.UnwrapKey(kek, wrapped)
+	if err != nil {
+		return "", errors.Join(ErrUnwrapKeyFailure, err)
+	}
+	return core.Key(key), nil
+}
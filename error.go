@@ -1,5 +1,11 @@
 package privacy
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // Error is a developer-friendly error wrapper that speaks privacy language.
 // Its base error contains more technical details,
 // and it can be enriched with meta-data, e.g., namespace and subject.
@@ -42,6 +48,16 @@ func (e Error) Namespace() string {
 	return e.namespace
 }
 
+// Field returns the path of the associated field to the error if it exists, e.g., "Addresses.Street".
+// It returns the first one in case of multiple failed fields, see FieldErrors.
+func (e Error) Field() string {
+	var ferr FieldError
+	if errors.As(e.Err, &ferr) {
+		return ferr.Path
+	}
+	return ""
+}
+
 func (e Error) withNamespace(nspace string) Error {
 	e.namespace = nspace
 	return e
@@ -89,3 +105,57 @@ func (e Error) Is(err error) bool {
 
 	return false
 }
+
+// FieldError describes the failure of a single field to be encrypted or decrypted.
+type FieldError struct {
+	// Index is the position of the field's struct in the given structs pointers.
+	Index int
+
+	// Path is the field path, e.g., "Addresses.Street". Slice indexes and map keys are not part of the path.
+	// It's the concrete JSON path of JSON documents' fields, e.g., "$.orders[2].customer.email".
+	Path string
+
+	// SubjectID is the field's subject, if known.
+	SubjectID string
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error implements error interface.
+func (e FieldError) Error() string {
+	str := fmt.Sprintf("field '%s' at #%d", e.Path, e.Index)
+	if e.SubjectID != "" {
+		str += " [sub:'" + e.SubjectID + "']"
+	}
+	if e.Err != nil {
+		str += ": " + e.Err.Error()
+	}
+	return str
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors collects the failures of fields to be encrypted or decrypted, one per field,
+// in the order fields were processed. Use errors.As to retrieve it from Protector errors.
+type FieldErrors []FieldError
+
+// Error implements error interface.
+func (e FieldErrors) Error() string {
+	strs := make([]string, 0, len(e))
+	for _, ferr := range e {
+		strs = append(strs, ferr.Error())
+	}
+	return strings.Join(strs, "\n")
+}
+
+// Unwrap allows errors.Is and errors.As to match the causes of fields failures.
+func (e FieldErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, ferr := range e {
+		errs = append(errs, ferr)
+	}
+	return errs
+}
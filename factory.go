@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/ln80/privacy-engine/core"
 )
 
 // FactoryClearFunc presents the function returned by Factory.Instance method.
@@ -13,10 +15,19 @@ type FactoryClearFunc func()
 // FactoryNewFunc is used by the Factory service to create Perotector instance per namespace.
 type FactoryNewFunc func(namespace string) Protector
 
+// ForgottenEvent presents a subject forgotten by the Factory's monitoring process
+// because its scheduled time has passed or its retention has elapsed.
+type ForgottenEvent struct {
+	Namespace string
+	SubjectID string
+	At        time.Time
+}
+
 // Factory manages and maintains a registry of Protector services.
 //
 // It monitors each Protector service to track its activity
 // and regularly clears encryption materials caches.
+// It also regularly forgets expired subjects, see RetentionManager.
 type Factory interface {
 
 	// Instance creates a new Protector instance for the given namespace or returns the existing one.
@@ -25,7 +36,16 @@ type Factory interface {
 	// Monitor starts a long-running process in a separate Goroutine.
 	// It checks Protectors' activities and removes inactive ones,
 	// and clears their caches based on their cache TTL config.
+	//
+	// It also forgets expired subjects of registered Protectors and of the configured SweepNamespaces,
+	// according to the SweepPeriod config.
 	Monitor(ctx context.Context)
+
+	// ForgetNamespace forgets all subjects of the given namespace using its Protector instance,
+	// then evicts the instance and clears its encryption materials cache.
+	//
+	// See NamespaceManager.ForgetNamespace.
+	ForgetNamespace(ctx context.Context, namespace string) error
 }
 
 // FactoryConfig presents the configuration of Factory service
@@ -36,6 +56,17 @@ type FactoryConfig struct {
 
 	// MonitorPeriod is the frequency of the regular checks made by the monitoring process.
 	MonitorPeriod time.Duration
+
+	// SweepPeriod is the frequency at which the monitoring process forgets expired subjects.
+	// It's rounded up to MonitorPeriod, and zero disables sweeping.
+	SweepPeriod time.Duration
+
+	// SweepNamespaces are swept even if they don't have a registered Protector instance,
+	// otherwise only namespaces of active Protectors are swept.
+	SweepNamespaces []string
+
+	// OnForgotten is called for each subject forgotten by the monitoring process.
+	OnForgotten func(ForgottenEvent)
 }
 
 type factory struct {
@@ -59,6 +90,7 @@ func NewFactory(newProt FactoryNewFunc, opts ...func(*FactoryConfig)) Factory {
 		FactoryConfig: &FactoryConfig{
 			IDLE:          20 * time.Minute,
 			MonitorPeriod: 5 * time.Second,
+			SweepPeriod:   time.Minute,
 		},
 	}
 
@@ -109,6 +141,72 @@ func (f *factory) clear(ctx context.Context, force bool) {
 	}
 }
 
+// ForgetNamespace implements Factory interface
+func (f *factory) ForgetNamespace(ctx context.Context, namespace string) error {
+	f.mu.Lock()
+	p, ok := f.reg[namespace]
+	delete(f.reg, namespace)
+	f.mu.Unlock()
+
+	if !ok {
+		p = f.newProtector(namespace)
+	}
+
+	// Force Protector to clear cache even if forgetting the namespace fails,
+	// the next instance will reload encryption materials from the Key engine.
+	defer func() { _ = p.Clear(ctx, true) }()
+
+	// An instance may be registered again while forgetting the namespace, and may have cached
+	// encryption materials meanwhile. Evict it and clear its cache as well.
+	defer func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if p, ok := f.reg[namespace]; ok {
+			_ = p.Clear(ctx, true)
+			delete(f.reg, namespace)
+		}
+	}()
+
+	nm, ok := p.(NamespaceManager)
+	if !ok {
+		return ErrForgetNamespaceFailure.withBase(core.ErrUnsupported).withNamespace(namespace)
+	}
+	return nm.ForgetNamespace(ctx)
+}
+
+// sweep forgets expired subjects of registered Protectors and of the configured SweepNamespaces.
+func (f *factory) sweep(ctx context.Context) {
+	f.mu.RLock()
+	protectors := make(map[string]Protector, len(f.reg))
+	for nspace, p := range f.reg {
+		protectors[nspace] = p
+	}
+	f.mu.RUnlock()
+
+	for _, nspace := range f.SweepNamespaces {
+		if _, ok := protectors[nspace]; !ok {
+			protectors[nspace] = f.newProtector(nspace)
+		}
+	}
+
+	for nspace, p := range protectors {
+		rm, ok := p.(RetentionManager)
+		if !ok {
+			continue
+		}
+		// Ignore the returned error, subjects that failed to be forgotten are retried by the next sweep.
+		forgotten, _ := rm.ForgetExpired(ctx)
+		if f.OnForgotten == nil {
+			continue
+		}
+		now := time.Now()
+		for _, subID := range forgotten {
+			f.OnForgotten(ForgottenEvent{Namespace: nspace, SubjectID: subID, At: now})
+		}
+	}
+}
+
 // Monitor implements Factory interface
 func (f *factory) Monitor(ctx context.Context) {
 	ticker := time.NewTicker(f.MonitorPeriod)
@@ -121,6 +219,7 @@ func (f *factory) Monitor(ctx context.Context) {
 			cancel()
 		}()
 
+		lastSweepAt := time.Now()
 		for {
 			select {
 			case <-ctx.Done():
@@ -128,6 +227,11 @@ func (f *factory) Monitor(ctx context.Context) {
 
 			case <-ticker.C:
 				f.clear(ctx, false)
+
+				if f.SweepPeriod > 0 && time.Since(lastSweepAt) >= f.SweepPeriod {
+					f.sweep(ctx)
+					lastSweepAt = time.Now()
+				}
 			}
 		}
 	}()
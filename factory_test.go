@@ -2,10 +2,14 @@ package privacy
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/ln80/privacy-engine/core"
 	"github.com/ln80/privacy-engine/memory"
 )
 
@@ -14,6 +18,20 @@ type spyProtector struct {
 
 	mu    sync.RWMutex
 	Calls FuncCalls
+
+	// OnForgetNamespace is called before forgetting the namespace, if not nil.
+	OnForgetNamespace func()
+}
+
+func (tp *spyProtector) ForgetNamespace(ctx context.Context) error {
+	if tp.OnForgetNamespace != nil {
+		tp.OnForgetNamespace()
+	}
+	return tp.Protector.(NamespaceManager).ForgetNamespace(ctx)
+}
+
+func (tp *spyProtector) RecoverNamespace(ctx context.Context) error {
+	return tp.Protector.(NamespaceManager).RecoverNamespace(ctx)
 }
 
 func (tp *spyProtector) Clear(ctx context.Context, force bool) error {
@@ -115,3 +133,189 @@ func TestFactory(t *testing.T) {
 	// assert Protector was deleted from registry even is not IDLE
 	assertProtectorCount(t, f.(*factory), 0)
 }
+
+func TestFactory_ForgetNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-f4n5p1"
+
+	engine := memory.NewKeyEngine()
+	f := NewFactory(func(namespace string) Protector {
+		return &spyProtector{
+			Protector: NewProtector(namespace, engine),
+		}
+	})
+
+	p, _ := f.Instance(nspace)
+
+	pf := Profile{UserID: "kal5433", Fullname: "Idir Moore", Gender: "M"}
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	if err := f.ForgetNamespace(ctx, nspace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert the Protector is evicted and its cache cleared
+	p.(*traceable).Protector.(*spyProtector).Calls.AssertCount(t, "Clear", 1)
+	if want, got := 0, len(f.(*factory).reg); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+
+	// assert a new instance doesn't use the forgotten encryption materials
+	p, _ = f.Instance(nspace)
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "deleted pii", pf.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert forgetting a namespace without a registered Protector
+	if err := f.ForgetNamespace(ctx, "tenant-f4n5p2"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 1, len(f.(*factory).reg); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+}
+
+func TestFactory_ForgetNamespace_Reregistered(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-f4n5p3"
+
+	engine := memory.NewKeyEngine()
+	f := NewFactory(func(namespace string) Protector {
+		return &spyProtector{
+			Protector: NewProtector(namespace, engine),
+		}
+	})
+
+	p, _ := f.Instance(nspace)
+
+	// an instance is registered again, and caches encryption materials, while forgetting the namespace.
+	var (
+		p2  Protector
+		pf2 = Profile{UserID: "kal5434", Fullname: "Ali Moore", Gender: "M"}
+	)
+	p.(*traceable).Protector.(*spyProtector).OnForgetNamespace = func() {
+		p2, _ = f.Instance(nspace)
+		if err := p2.Encrypt(ctx, &pf2); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	}
+
+	if err := f.ForgetNamespace(ctx, nspace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert the re-registered Protector is evicted and its cache cleared
+	p2.(*traceable).Protector.(*spyProtector).Calls.AssertCount(t, "Clear", 1)
+	if want, got := 0, len(f.(*factory).reg); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	if err := p2.Decrypt(ctx, &pf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "deleted pii", pf2.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestFactory_OptionalInterfaces(t *testing.T) {
+	ctx := context.Background()
+
+	engine := memory.NewKeyEngine()
+
+	// assert instances forward optional interfaces' methods
+	p, _ := NewFactory(func(namespace string) Protector {
+		return NewProtector(namespace, engine)
+	}).Instance("tenant-0p7i0n1")
+
+	pf := Profile{
+		UserID:   "kal5435",
+		Fullname: "Idir Moore",
+	}
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := p.(Reencrypter).Reencrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert optional interfaces not implemented by the wrapped Protector are not supported
+	p, _ = NewFactory(func(namespace string) Protector {
+		return struct{ Protector }{NewProtector(namespace, engine)}
+	}).Instance("tenant-0p7i0n2")
+
+	if err := p.(Reencrypter).Reencrypt(ctx, &pf); !errors.Is(err, ErrEncryptDecryptFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+	if _, err := io.ReadAll(p.(StreamProtector).EncryptStream(ctx, pf.UserID, strings.NewReader("blob"))); !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func TestFactory_Sweep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nspace1, nspace2 := "tenant-s5w1p1", "tenant-s5w1p2"
+
+	engine := memory.NewKeyEngine()
+	builder := func(namespace string) Protector {
+		return NewProtector(namespace, engine)
+	}
+
+	var (
+		mu     sync.Mutex
+		events []ForgottenEvent
+	)
+	f := NewFactory(builder, func(fc *FactoryConfig) {
+		fc.MonitorPeriod = 10 * time.Millisecond
+		fc.SweepPeriod = 20 * time.Millisecond
+		fc.SweepNamespaces = []string{nspace2}
+		fc.OnForgotten = func(e ForgottenEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			events = append(events, e)
+		}
+	})
+
+	// the first namespace has a registered Protector, while the second one is only configured
+	p1, _ := f.Instance(nspace1)
+	if err := p1.(RetentionManager).ForgetAt(ctx, "sub-1", time.Now()); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := builder(nspace2).(RetentionManager).ForgetAt(ctx, "sub-2", time.Now()); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	f.Monitor(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if want, got := 2, len(events); want != got {
+		t.Fatalf("expect %d, %d be equals", want, got)
+	}
+	for _, e := range events {
+		switch e.Namespace {
+		case nspace1:
+			if want, got := "sub-1", e.SubjectID; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		case nspace2:
+			if want, got := "sub-2", e.SubjectID; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		default:
+			t.Fatalf("unexpected event %v", e)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package privacy
+
+import (
+	"reflect"
+	"strings"
+
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+// sensitiveStruct wraps a scanned sensitive struct along with the pointer it was scanned from.
+type sensitiveStruct struct {
+	sensitive.Struct
+
+	ptr       any
+	subjectID string
+}
+
+// scanStruct scans the given struct pointer. The subject ID is resolved only if it's required.
+func scanStruct(structPtr any, requireSubject bool) (sensitiveStruct, error) {
+	s, err := sensitive.Scan(structPtr, requireSubject)
+	if err != nil {
+		return sensitiveStruct{}, err
+	}
+	ss := sensitiveStruct{Struct: s, ptr: structPtr}
+	if requireSubject && s.HasSensitive() {
+		ss.subjectID = s.SubjectID()
+	}
+	return ss, nil
+}
+
+// Replace walks through sensitive fields the same way sensitive.Struct.Replace does,
+// except that it sets the FieldReplace name to the field path, e.g., "Addresses.Street".
+//
+// Slice indexes and map keys are not part of the path, so that it remains stable when elements are reordered.
+func (s sensitiveStruct) Replace(fn sensitive.ReplaceFunc) error {
+	return s.replace(fn, nil)
+}
+
+// replace is similar to Replace, and records the changes in the given journal, if any, to allow rolling them back.
+func (s sensitiveStruct) replace(fn sensitive.ReplaceFunc, j *journal) error {
+	return s.walk(func(_ reflect.Value, fr sensitive.FieldReplace, elem reflect.Value) error {
+		val := elem.String()
+		newVal, err := fn(fr, val)
+		if err != nil {
+			return err
+		}
+		if newVal != val {
+			j.setString(elem, newVal)
+		}
+		return nil
+	}, j)
+}
+
+// journal records the changes made to structs, so that they can be rolled back in case of failure.
+// A nil journal doesn't record changes.
+type journal struct {
+	undo []func()
+}
+
+// setString sets the given string value, and records the previous one.
+func (j *journal) setString(v reflect.Value, val string) {
+	if j != nil {
+		old := v.String()
+		j.undo = append(j.undo, func() { v.SetString(old) })
+	}
+	v.SetString(val)
+}
+
+// setMapIndex sets the given map element, and records the previous one.
+func (j *journal) setMapIndex(m, key, elem reflect.Value) {
+	if j != nil {
+		old := m.MapIndex(key)
+		j.undo = append(j.undo, func() { m.SetMapIndex(key, old) })
+	}
+	m.SetMapIndex(key, elem)
+}
+
+// commit keeps the recorded changes, so that rolling back is a no-op.
+func (j *journal) commit() {
+	j.undo = nil
+}
+
+// rollback restores the recorded changes in reverse order.
+func (j *journal) rollback() {
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		j.undo[i]()
+	}
+	j.undo = nil
+}
+
+// fieldVisitor is called for each non-empty sensitive data field, along with the struct that holds it.
+type fieldVisitor func(parent reflect.Value, fr sensitive.FieldReplace, elem reflect.Value) error
+
+// walk calls the given visitor for each sensitive data field. Map elements are walked through copies,
+// which are put back in their maps, if changed, and recorded in the given journal, if any.
+func (s sensitiveStruct) walk(visit fieldVisitor, j *journal) error {
+	return walkFields(reflect.Indirect(reflect.ValueOf(s.ptr)), "", s.subjectID, visit, j)
+}
+
+func walkFields(v reflect.Value, prefix, subjectID string, visit fieldVisitor, j *journal) error {
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	rt := v.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sensitive.ParseTag(sf.Tag)
+		if tag == nil {
+			continue
+		}
+
+		// sensitive.ParseTag ignores options without value, e.g., 'deterministic'
+		for _, opt := range strings.Split(sf.Tag.Get(tag.ID), ",")[1:] {
+			if opt = strings.TrimSpace(opt); opt != "" && !strings.Contains(opt, "=") {
+				tag.Options[opt] = ""
+			}
+		}
+
+		fv := v.Field(i)
+		if fv.IsZero() || !fv.CanSet() {
+			continue
+		}
+		path := prefix + sf.Name
+		elem := reflect.Indirect(fv)
+
+		switch tag.Name {
+		case "data":
+			if elem.Kind() != reflect.String {
+				continue
+			}
+			if err := visit(v, sensitive.FieldReplace{
+				SubjectID: subjectID,
+				Name:      path,
+				RType:     sf.Type,
+				Kind:      tag.Options.Get("kind"),
+				Options:   tag.Options,
+			}, elem); err != nil {
+				return err
+			}
+
+		case "dive":
+			if err := walkNested(elem, path+".", subjectID, visit, j); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func walkNested(elem reflect.Value, prefix, subjectID string, visit fieldVisitor, j *journal) error {
+	switch elem.Kind() {
+	case reflect.Slice:
+		for i := 0; i < elem.Len(); i++ {
+			if err := walkFields(reflect.Indirect(elem.Index(i)), prefix, subjectID, visit, j); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		for _, k := range elem.MapKeys() {
+			mapElem := elem.MapIndex(k)
+			if mapElem.IsZero() {
+				continue
+			}
+			if mapElem.Kind() == reflect.Ptr {
+				if err := walkFields(mapElem.Elem(), prefix, subjectID, visit, j); err != nil {
+					return err
+				}
+				continue
+			}
+			// map values are not addressable, walk through a copy then put it back if it has changed,
+			// so that read-only walks don't write into maps, which may be shared.
+			newElem := reflect.New(mapElem.Type()).Elem()
+			newElem.Set(mapElem)
+			if err := walkFields(newElem, prefix, subjectID, visit, j); err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(mapElem.Interface(), newElem.Interface()) {
+				j.setMapIndex(elem, k, newElem)
+			}
+		}
+
+	default:
+		return walkFields(elem, prefix, subjectID, visit, j)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package privacy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+func TestSensitiveStruct_Replace(t *testing.T) {
+	h := Household{UserID: "sub-1", Members: map[string]Address{"idir": {Street: "Baker Street"}}}
+
+	s, err := scanStruct(&h, true)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert read-only walks don't write map elements back
+	j := &journal{}
+	paths := make([]string, 0)
+	if err := s.replace(func(fr sensitive.FieldReplace, val string) (string, error) {
+		paths = append(paths, fr.Name)
+		return val, nil
+	}, j); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []string{"Members.Street"}, paths; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 0, len(j.undo); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := s.replace(func(fr sensitive.FieldReplace, val string) (string, error) {
+		return strings.ToUpper(val), nil
+	}, j); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "BAKER STREET", h.Members["idir"].Street; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	j.rollback()
+	if want, got := "Baker Street", h.Members["idir"].Street; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
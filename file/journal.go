@@ -0,0 +1,136 @@
+package file
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	errCorruptedFrame = errors.New("corrupted journal frame")
+)
+
+const (
+	frameHeaderSize = 8
+	frameMaxSize    = 1 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// header is the first frame of both snapshot and journal files.
+// It defines the file's generation, which is used to detect journals
+// that were already compacted into a snapshot.
+type header struct {
+	Gen uint64 `json:"gen"`
+}
+
+// encodeFrame returns the binary frame of the given value.
+// A frame is composed of a 4-bytes payload length, a 4-bytes CRC-32C checksum,
+// and a JSON payload.
+func encodeFrame(v any) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > frameMaxSize {
+		return nil, fmt.Errorf("journal frame too large: %d bytes", len(payload))
+	}
+
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+
+	return append(frame, payload...), nil
+}
+
+// readFrames reads and decodes the frames of the given reader and calls fn for each payload.
+//
+// It returns the offset of the last valid frame's end. A torn or corrupted frame
+// stops the reading and is reported as errCorruptedFrame, so that the caller may truncate it.
+func readFrames(r io.Reader, fn func(payload []byte) error) (offset int64, err error) {
+	br := bufio.NewReader(r)
+	hdr := make([]byte, frameHeaderSize)
+	for {
+		if _, err = io.ReadFull(br, hdr); err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			return offset, errors.Join(errCorruptedFrame, err)
+		}
+
+		size := binary.BigEndian.Uint32(hdr[0:4])
+		if size > frameMaxSize {
+			return offset, fmt.Errorf("%w: invalid size %d", errCorruptedFrame, size)
+		}
+
+		payload := make([]byte, size)
+		if _, err = io.ReadFull(br, payload); err != nil {
+			return offset, errors.Join(errCorruptedFrame, err)
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(hdr[4:8]) {
+			return offset, fmt.Errorf("%w: checksum mismatch", errCorruptedFrame)
+		}
+
+		if err = fn(payload); err != nil {
+			return offset, errors.Join(errCorruptedFrame, err)
+		}
+
+		offset += int64(frameHeaderSize + size)
+	}
+}
+
+// writeFileAtomic writes the given frames to a temporary file, syncs it,
+// then renames it to the given path. The parent directory is synced as well
+// to make sure the rename is durable.
+//
+// The previous file, if any, is unlinked by the rename and its content is no longer reachable.
+func writeFileAtomic(path string, frames ...[]byte) (err error) {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	for _, frame := range frames {
+		if _, err = w.Write(frame); err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,1016 @@
+// Package file contains a persistent core.KeyEngine implementation backed by local files.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+)
+
+const (
+	snapshotFile = "keys.snapshot"
+	journalFile  = "keys.journal"
+
+	compactThresholdDefault = 1000
+)
+
+var (
+	ErrEngineClosed = errors.New("file key engine is closed")
+
+	errStaleJournal = errors.New("stale journal")
+)
+
+// KeyEngineConfig presents the configuration of the file-backed KeyEngine.
+type KeyEngineConfig struct {
+	core.KeyEngineConfig
+
+	// CompactThreshold is the number of journal records that triggers a compaction
+	// of the journal into a new snapshot.
+	CompactThreshold int
+}
+
+// record presents the persisted state of an encryption key.
+type record struct {
+	Namespace  string        `json:"ns"`
+	ID         string        `json:"id"`
+	Key        []byte        `json:"key,omitempty"`
+	State      core.KeyState `json:"state"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	DisabledAt time.Time     `json:"disabledAt"`
+	DeletedAt  time.Time     `json:"deletedAt"`
+	ExpiresAt  time.Time     `json:"expiresAt"`
+
+	// NamespaceDisabled reports whether the key was disabled along with its namespace.
+	NamespaceDisabled bool `json:"nsDisabled,omitempty"`
+
+	Hold *holdRecord `json:"hold,omitempty"`
+
+	// Rotations contains the key versions created by rotations, i.e., starting from version 2.
+	Rotations [][]byte `json:"rotations,omitempty"`
+
+	// PublicKey is the public key of the key's first version, if created along with it or published.
+	PublicKey []byte `json:"publicKey,omitempty"`
+}
+
+// holdRecord presents the persisted state of a key hold.
+type holdRecord struct {
+	Reason          string    `json:"reason"`
+	PlacedAt        time.Time `json:"placedAt"`
+	ForgetRequested bool      `json:"forgetRequested,omitempty"`
+}
+
+func (h *holdRecord) requestForget(requested bool) *holdRecord {
+	if h == nil {
+		return nil
+	}
+	updated := *h
+	updated.ForgetRequested = requested
+	return &updated
+}
+
+func (r *record) latest() core.VersionedKey {
+	if n := len(r.Rotations); n > 0 {
+		return core.VersionedKey{Version: n + 1, Key: core.Key(r.Rotations[n-1])}
+	}
+	return core.VersionedKey{Version: 1, Key: core.Key(r.Key)}
+}
+
+func (r *record) versions() map[int]core.Key {
+	versions := map[int]core.Key{1: core.Key(r.Key)}
+	for i, k := range r.Rotations {
+		versions[i+2] = core.Key(k)
+	}
+	return versions
+}
+
+// KeyEngine is a core.KeyEngine implementation that persists encryption keys
+// in an append-only journal, which is regularly compacted into a snapshot.
+//
+// Each write is synced to the disk before being acknowledged, and a torn journal record,
+// e.g., due to a crash, is truncated at the next start.
+//
+// Deleted keys are immediately compacted so that their values no longer exist on the disk.
+// If the compaction fails, the deletion is still acknowledged and the compaction is retried
+// with the next write, at the next start, or by calling Compact.
+// Note that the engine assumes it's the only process writing to the given directory.
+type KeyEngine struct {
+	dir string
+
+	*KeyEngineConfig
+
+	mu          sync.Mutex
+	keys        map[string]map[string]*record
+	gen         uint64
+	journal     *os.File
+	journalSize int64
+	records     int
+
+	// shredPending reports whether deleted keys' values may still exist on the disk.
+	shredPending bool
+}
+
+var _ core.KeyEngine = &KeyEngine{}
+var _ core.KeyRewriter = &KeyEngine{}
+var _ core.VersionedKeyEngine = &KeyEngine{}
+var _ core.NamespaceKeyEngine = &KeyEngine{}
+var _ core.ExpiringKeyEngine = &KeyEngine{}
+var _ core.HoldKeyEngine = &KeyEngine{}
+var _ core.PublicKeyEngine = &KeyEngine{}
+
+// NewKeyEngine opens, or creates if it doesn't exist, a file-backed KeyEngine in the given directory.
+// Options params allow overwriting the default configuration.
+//
+// It returns an error if the persisted files can't be loaded.
+// Note that the engine must be closed to release the journal file.
+func NewKeyEngine(dir string, opts ...func(*KeyEngineConfig)) (*KeyEngine, error) {
+	e := &KeyEngine{
+		dir:  dir,
+		keys: make(map[string]map[string]*record),
+		KeyEngineConfig: &KeyEngineConfig{
+			KeyEngineConfig:  core.NewKeyEngineConfig(),
+			CompactThreshold: compactThresholdDefault,
+		},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(e.KeyEngineConfig)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	// remove leftovers of interrupted atomic writes, they may contain deleted keys.
+	for _, name := range []string{snapshotFile, journalFile} {
+		if err := os.Remove(filepath.Join(dir, name+".tmp")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if err := e.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("failed to load keys snapshot: %w", err)
+	}
+	if err := e.loadJournal(); err != nil {
+		return nil, fmt.Errorf("failed to load keys journal: %w", err)
+	}
+	if e.shredPending {
+		// a compaction of deleted keys has failed, retry it, or with the next write if it fails again.
+		_ = e.compact()
+	}
+
+	return e, nil
+}
+
+func (e *KeyEngine) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(e.dir, snapshotFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	first := true
+	_, err = readFrames(f, func(payload []byte) error {
+		if first {
+			first = false
+			var h header
+			if err := json.Unmarshal(payload, &h); err != nil {
+				return err
+			}
+			e.gen = h.Gen
+			return nil
+		}
+		_, err := e.apply(payload)
+		return err
+	})
+
+	// snapshots are atomically written, a corrupted one can't be partially recovered.
+	return err
+}
+
+func (e *KeyEngine) loadJournal() error {
+	path := filepath.Join(e.dir, journalFile)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return e.resetJournal()
+		}
+		return err
+	}
+
+	var (
+		first = true
+		gen   uint64
+		stale bool
+	)
+	offset, err := readFrames(f, func(payload []byte) error {
+		if first {
+			first = false
+			var h header
+			if err := json.Unmarshal(payload, &h); err != nil {
+				return err
+			}
+			gen = h.Gen
+			if stale = gen < e.gen; stale {
+				return errStaleJournal
+			}
+			return nil
+		}
+		e.records++
+		r, err := e.apply(payload)
+		if err != nil {
+			return err
+		}
+		// deletions are compacted right away, unless the compaction has failed.
+		if r.State == core.StateDeleted {
+			e.shredPending = true
+		}
+		return nil
+	})
+	_ = f.Close()
+
+	switch {
+	case stale, first:
+		// the journal was either already compacted into the snapshot
+		// or its header is torn, there is nothing to replay.
+		e.records = 0
+		return e.resetJournal()
+	case gen > e.gen:
+		return fmt.Errorf("journal generation %d is ahead of snapshot generation %d", gen, e.gen)
+	case errors.Is(err, errCorruptedFrame):
+		// drop the torn tail of the journal
+		if err := os.Truncate(path, offset); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	e.journal, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	e.journalSize = offset
+	return e.journal.Sync()
+}
+
+// resetJournal atomically replaces the journal with an empty one of the current generation.
+func (e *KeyEngine) resetJournal() error {
+	if e.journal != nil {
+		_ = e.journal.Close()
+		e.journal = nil
+	}
+
+	h, err := encodeFrame(header{Gen: e.gen})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(e.dir, journalFile)
+	if err := writeFileAtomic(path, h); err != nil {
+		return err
+	}
+
+	e.journal, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	e.journalSize = int64(len(h))
+	return err
+}
+
+// apply decodes and applies a persisted record to the in-memory state, and returns it.
+func (e *KeyEngine) apply(payload []byte) (*record, error) {
+	var r record
+	if err := json.Unmarshal(payload, &r); err != nil {
+		return nil, err
+	}
+	e.set(&r)
+	return &r, nil
+}
+
+func (e *KeyEngine) set(r *record) {
+	if _, ok := e.keys[r.Namespace]; !ok {
+		e.keys[r.Namespace] = make(map[string]*record)
+	}
+	e.keys[r.Namespace][r.ID] = r
+}
+
+func (e *KeyEngine) get(namespace, keyID string) (*record, bool) {
+	r, ok := e.keys[namespace][keyID]
+	return r, ok
+}
+
+// persist appends the given records to the journal and syncs it,
+// then applies them to the in-memory state.
+//
+// It triggers a compaction if the journal exceeds the configured threshold.
+func (e *KeyEngine) persist(records ...*record) error {
+	if e.journal == nil {
+		return ErrEngineClosed
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	buf := make([]byte, 0)
+	for _, r := range records {
+		frame, err := encodeFrame(r)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, frame...)
+	}
+	if err := e.append(buf); err != nil {
+		// do not leave a torn record behind, otherwise it will hide the next ones.
+		_ = e.journal.Truncate(e.journalSize)
+		return err
+	}
+
+	for _, r := range records {
+		e.set(r)
+	}
+
+	e.records += len(records)
+	if e.shredPending || e.CompactThreshold > 0 && e.records >= e.CompactThreshold {
+		// records are already durable, a compaction failure is ignored
+		// and the compaction will be retried with the next write.
+		_ = e.compact()
+	}
+	return nil
+}
+
+func (e *KeyEngine) append(buf []byte) error {
+	if _, err := e.journal.Write(buf); err != nil {
+		return err
+	}
+	if err := e.journal.Sync(); err != nil {
+		return err
+	}
+	e.journalSize += int64(len(buf))
+	return nil
+}
+
+// Compact writes the current state of keys to a new snapshot, and resets the journal.
+//
+// Once it returns, the values of deleted keys no longer exist in the engine's files.
+func (e *KeyEngine) Compact() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return ErrEngineClosed
+	}
+	return e.compact()
+}
+
+func (e *KeyEngine) compact() error {
+	gen := e.gen + 1
+
+	h, err := encodeFrame(header{Gen: gen})
+	if err != nil {
+		return err
+	}
+	frames := [][]byte{h}
+	for _, nsKeys := range e.keys {
+		for _, r := range nsKeys {
+			frame, err := encodeFrame(r)
+			if err != nil {
+				return err
+			}
+			frames = append(frames, frame)
+		}
+	}
+
+	if err := writeFileAtomic(filepath.Join(e.dir, snapshotFile), frames...); err != nil {
+		return err
+	}
+
+	// The snapshot is now the source of truth, the previous journal is stale
+	// even if the reset below fails.
+	e.gen = gen
+	e.records = 0
+	e.shredPending = false
+	return e.resetJournal()
+}
+
+// Close closes the journal file. The engine is no longer usable afterward.
+func (e *KeyEngine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return nil
+	}
+	err := e.journal.Close()
+	e.journal = nil
+	return err
+}
+
+// GetKeys implements core.KeyEngine
+func (e *KeyEngine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, ErrEngineClosed)
+	}
+
+	keys := core.NewKeyMap()
+	for _, keyID := range keyIDs {
+		r, ok := e.get(namespace, keyID)
+		if !ok || r.State != core.StateActive {
+			continue
+		}
+		keys[keyID] = core.Key(r.Key)
+	}
+
+	return keys, nil
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (e *KeyEngine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := core.NewKeyMap()
+	newRecords := make([]*record, 0)
+	now := time.Now()
+	for _, keyID := range keyIDs {
+		if r, ok := e.get(namespace, keyID); ok {
+			// do not create a new key for disabled or deleted ones
+			if r.State == core.StateActive {
+				keys[keyID] = core.Key(r.Key)
+			}
+			continue
+		}
+		if _, ok := keys[keyID]; ok {
+			continue
+		}
+
+		newKey, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+		newRecords = append(newRecords, &record{
+			Namespace: namespace,
+			ID:        keyID,
+			Key:       []byte(newKey),
+			State:     core.StateActive,
+			CreatedAt: now,
+		})
+		keys[keyID] = core.Key(newKey)
+	}
+
+	if err := e.persist(newRecords...); err != nil {
+		return nil, errors.Join(core.ErrPersistKeyFailure, err)
+	}
+
+	return keys, nil
+}
+
+// DisableKey implements core.KeyEngine
+func (e *KeyEngine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	switch {
+	case r.State == core.StateDeleted:
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	case r.State == core.StateDisabled && !r.NamespaceDisabled:
+		return nil
+	case r.Hold != nil:
+		return e.refuseHeld(r)
+	}
+
+	updated := *r
+	// keep the first disable timestamp to not extend the grace period.
+	if r.State != core.StateDisabled {
+		updated.DisabledAt = time.Now()
+	}
+	updated.State = core.StateDisabled
+	// the subject is individually disabled, reenabling its namespace must not reenable it.
+	updated.NamespaceDisabled = false
+	if err := e.persist(&updated); err != nil {
+		return errors.Join(core.ErrDisableKeyFailure, err)
+	}
+
+	return nil
+}
+
+// ReEnableKey implements core.KeyEngine
+func (e *KeyEngine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	switch r.State {
+	case core.StateDeleted:
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	case core.StateActive:
+		return nil
+	}
+
+	updated := *r
+	updated.State = core.StateActive
+	updated.DisabledAt = time.Time{}
+	updated.NamespaceDisabled = false
+	// the subject is recovered, cancel the forget requested while on hold.
+	updated.Hold = r.Hold.requestForget(false)
+	if err := e.persist(&updated); err != nil {
+		return errors.Join(core.ErrReEnableKeyFailure, err)
+	}
+
+	return nil
+}
+
+// DisableNamespace implements core.NamespaceKeyEngine
+func (e *KeyEngine) DisableNamespace(ctx context.Context, namespace string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	disabled := make([]*record, 0)
+	for _, r := range e.keys[namespace] {
+		if r.State != core.StateActive || r.Hold != nil {
+			continue
+		}
+		updated := *r
+		updated.State = core.StateDisabled
+		updated.DisabledAt = now
+		updated.NamespaceDisabled = true
+		disabled = append(disabled, &updated)
+	}
+
+	if err := e.persist(disabled...); err != nil {
+		return errors.Join(core.ErrDisableKeyFailure, err)
+	}
+	return nil
+}
+
+// ReEnableNamespace implements core.NamespaceKeyEngine
+func (e *KeyEngine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enabled := make([]*record, 0)
+	for _, r := range e.keys[namespace] {
+		if r.State != core.StateDisabled || !r.NamespaceDisabled {
+			continue
+		}
+		updated := *r
+		updated.State = core.StateActive
+		updated.DisabledAt = time.Time{}
+		updated.NamespaceDisabled = false
+		enabled = append(enabled, &updated)
+	}
+
+	if err := e.persist(enabled...); err != nil {
+		return errors.Join(core.ErrReEnableKeyFailure, err)
+	}
+	return nil
+}
+
+// DeleteNamespace implements core.NamespaceKeyEngine
+func (e *KeyEngine) DeleteNamespace(ctx context.Context, namespace string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	deleted := make([]*record, 0)
+	for _, r := range e.keys[namespace] {
+		if r.State == core.StateDeleted || r.Hold != nil {
+			continue
+		}
+		deleted = append(deleted, r)
+	}
+
+	if err := e.shred(deleted...); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	return nil
+}
+
+// DeleteKey implements core.KeyEngine
+//
+// The key record is kept without its value to prevent the creation of a new key for the same ID.
+func (e *KeyEngine) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok || r.State == core.StateDeleted {
+		return nil
+	}
+	if r.Hold != nil {
+		return e.refuseHeld(r)
+	}
+
+	if err := e.shred(r); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	return nil
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+func (e *KeyEngine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	unused := make([]*record, 0)
+	for _, r := range e.keys[namespace] {
+		if r.State != core.StateDisabled || time.Since(r.DisabledAt) < e.GracePeriod || r.Hold != nil {
+			continue
+		}
+		unused = append(unused, r)
+	}
+
+	if err := e.shred(unused...); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	return nil
+}
+
+// ScheduleKeyExpiry implements core.ExpiringKeyEngine
+func (e *KeyEngine) ScheduleKeyExpiry(ctx context.Context, namespace, keyID string, at time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	if r.State == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	updated := *r
+	updated.ExpiresAt = at
+	if err := e.persist(&updated); err != nil {
+		return errors.Join(core.ErrScheduleKeyFailure, err)
+	}
+	return nil
+}
+
+// ExpiredKeys implements core.ExpiringKeyEngine
+func (e *KeyEngine) ExpiredKeys(ctx context.Context, namespace string, at time.Time, retention time.Duration) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, ErrEngineClosed)
+	}
+
+	expired := make([]string, 0)
+	for keyID, r := range e.keys[namespace] {
+		if r.expired(at, retention) {
+			expired = append(expired, keyID)
+		}
+	}
+	slices.Sort(expired)
+
+	return expired, nil
+}
+
+// KeyExpired implements core.ExpiringKeyEngine
+func (e *KeyEngine) KeyExpired(ctx context.Context, namespace, keyID string, at time.Time, retention time.Duration) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return false, errors.Join(core.ErrGetKeyFailure, ErrEngineClosed)
+	}
+
+	r, ok := e.get(namespace, keyID)
+	return ok && r.expired(at, retention), nil
+}
+
+// expired reports whether the key is active, not held, and expired at the given time, see core.ExpiringKeyEngine.
+func (r *record) expired(at time.Time, retention time.Duration) bool {
+	if r.State != core.StateActive || r.Hold != nil {
+		return false
+	}
+	deadline := r.ExpiresAt
+	if deadline.IsZero() {
+		if retention <= 0 {
+			return false
+		}
+		deadline = r.CreatedAt.Add(retention)
+	}
+	return !deadline.After(at)
+}
+
+// PlaceHold implements core.HoldKeyEngine
+func (e *KeyEngine) PlaceHold(ctx context.Context, namespace, keyID, reason string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	if r.State == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	hold := &holdRecord{Reason: reason, PlacedAt: time.Now()}
+	if r.Hold != nil {
+		hold.PlacedAt = r.Hold.PlacedAt
+		hold.ForgetRequested = r.Hold.ForgetRequested
+	}
+	updated := *r
+	updated.Hold = hold
+	if err := e.persist(&updated); err != nil {
+		return errors.Join(core.ErrHoldKeyFailure, err)
+	}
+	return nil
+}
+
+// ReleaseHold implements core.HoldKeyEngine
+func (e *KeyEngine) ReleaseHold(ctx context.Context, namespace, keyID string) (core.KeyHold, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r, ok := e.get(namespace, keyID)
+	if !ok {
+		return core.KeyHold{}, core.ErrKeyNotFound
+	}
+	if r.State == core.StateDeleted {
+		return core.KeyHold{}, fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+	if r.Hold == nil {
+		return core.KeyHold{}, nil
+	}
+
+	updated := *r
+	updated.Hold = nil
+	if err := e.persist(&updated); err != nil {
+		return core.KeyHold{}, errors.Join(core.ErrHoldKeyFailure, err)
+	}
+	return core.KeyHold{
+		Reason:          r.Hold.Reason,
+		PlacedAt:        r.Hold.PlacedAt,
+		ForgetRequested: r.Hold.ForgetRequested,
+	}, nil
+}
+
+// refuseHeld records the request to forget the given held key, and returns core.ErrKeyOnHold error.
+func (e *KeyEngine) refuseHeld(r *record) error {
+	if !r.Hold.ForgetRequested {
+		updated := *r
+		updated.Hold = r.Hold.requestForget(true)
+		if err := e.persist(&updated); err != nil {
+			return errors.Join(core.ErrKeyOnHold, err)
+		}
+	}
+	return core.ErrKeyOnHold
+}
+
+// RewriteKeys implements core.KeyRewriter
+//
+// The journal is compacted afterward, so that previous values no longer exist on the disk.
+func (e *KeyEngine) RewriteKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyRewriteFunc) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if keyIDs == nil {
+		keyIDs = make([]string, 0, len(e.keys[namespace]))
+		for keyID := range e.keys[namespace] {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+
+	rewritten := make([]*record, 0)
+	for _, keyID := range keyIDs {
+		r, ok := e.get(namespace, keyID)
+		if !ok || r.State == core.StateDeleted {
+			continue
+		}
+		newKey, err := fn(keyID, core.Key(r.Key))
+		if err != nil {
+			return errors.Join(core.ErrRewriteKeyFailure, err)
+		}
+		changed := newKey != core.Key(r.Key)
+
+		rotations := make([][]byte, 0, len(r.Rotations))
+		for _, k := range r.Rotations {
+			newRotation, err := fn(keyID, core.Key(k))
+			if err != nil {
+				return errors.Join(core.ErrRewriteKeyFailure, err)
+			}
+			changed = changed || newRotation != core.Key(k)
+			rotations = append(rotations, []byte(newRotation))
+		}
+		if !changed {
+			continue
+		}
+		updated := *r
+		updated.Key = []byte(newKey)
+		if len(rotations) > 0 {
+			updated.Rotations = rotations
+		}
+		rewritten = append(rewritten, &updated)
+	}
+	if len(rewritten) == 0 {
+		return nil
+	}
+
+	if err := e.persist(rewritten...); err != nil {
+		return errors.Join(core.ErrRewriteKeyFailure, err)
+	}
+	if err := e.compact(); err != nil {
+		return errors.Join(core.ErrRewriteKeyFailure, err)
+	}
+	return nil
+}
+
+// GetOrCreateLatestKeys implements core.VersionedKeyEngine
+func (e *KeyEngine) GetOrCreateLatestKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	keys, err := e.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latest := make(core.VersionedKeyMap)
+	for keyID := range keys {
+		if r, ok := e.get(namespace, keyID); ok && r.State == core.StateActive {
+			latest[keyID] = r.latest()
+		}
+	}
+	return latest, nil
+}
+
+// GetOrCreatePublicKeys implements core.PublicKeyEngine
+func (e *KeyEngine) GetOrCreatePublicKeys(ctx context.Context, namespace string, keyIDs []string, keyPairGen core.KeyPairGen) (core.KeyMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pubs := core.NewKeyMap()
+	newRecords := make([]*record, 0)
+	now := time.Now()
+	for _, keyID := range keyIDs {
+		if r, ok := e.get(namespace, keyID); ok {
+			// do not create a new key for disabled or deleted ones
+			if r.State == core.StateActive && len(r.PublicKey) > 0 {
+				pubs[keyID] = core.Key(r.PublicKey)
+			}
+			continue
+		}
+		if _, ok := pubs[keyID]; ok {
+			continue
+		}
+
+		newKey, pub, err := keyPairGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+		newRecords = append(newRecords, &record{
+			Namespace: namespace,
+			ID:        keyID,
+			Key:       []byte(newKey),
+			State:     core.StateActive,
+			CreatedAt: now,
+			PublicKey: []byte(pub),
+		})
+		pubs[keyID] = pub
+	}
+
+	if err := e.persist(newRecords...); err != nil {
+		return nil, errors.Join(core.ErrPersistKeyFailure, err)
+	}
+
+	return pubs, nil
+}
+
+// PutPublicKeys implements core.PublicKeyEngine
+func (e *KeyEngine) PutPublicKeys(ctx context.Context, namespace string, publicKeys core.KeyMap) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	updated := make([]*record, 0)
+	for keyID, pub := range publicKeys {
+		r, ok := e.get(namespace, keyID)
+		if !ok || r.State != core.StateActive || len(r.PublicKey) > 0 {
+			continue
+		}
+		u := *r
+		u.PublicKey = []byte(pub)
+		updated = append(updated, &u)
+	}
+
+	if err := e.persist(updated...); err != nil {
+		return errors.Join(core.ErrPersistKeyFailure, err)
+	}
+	return nil
+}
+
+// GetKeyVersions implements core.VersionedKeyEngine
+func (e *KeyEngine) GetKeyVersions(ctx context.Context, namespace string, keyIDs []string) (core.KeyVersionsMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.journal == nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, ErrEngineClosed)
+	}
+
+	versions := make(core.KeyVersionsMap)
+	for _, keyID := range keyIDs {
+		r, ok := e.get(namespace, keyID)
+		if !ok || r.State != core.StateActive {
+			continue
+		}
+		versions[keyID] = r.versions()
+	}
+	return versions, nil
+}
+
+// RotateKeys implements core.VersionedKeyEngine
+func (e *KeyEngine) RotateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latest := make(core.VersionedKeyMap)
+	rotated := make([]*record, 0)
+	for _, keyID := range keyIDs {
+		if _, ok := latest[keyID]; ok {
+			continue
+		}
+		r, ok := e.get(namespace, keyID)
+		if !ok || r.State != core.StateActive {
+			return nil, errors.Join(core.ErrRotateKeyFailure, fmt.Errorf("%w: '%s'", core.ErrKeyNotFound, keyID))
+		}
+		newKey, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrRotateKeyFailure, err)
+		}
+		updated := *r
+		updated.Rotations = append(append(make([][]byte, 0, len(r.Rotations)+1), r.Rotations...), []byte(newKey))
+		rotated = append(rotated, &updated)
+		latest[keyID] = updated.latest()
+	}
+
+	if err := e.persist(rotated...); err != nil {
+		return nil, errors.Join(core.ErrRotateKeyFailure, err)
+	}
+	return latest, nil
+}
+
+// shred marks the given records as deleted and immediately compacts the journal,
+// so that the keys' values are physically removed from the disk.
+//
+// Once the deletions are persisted, a compaction failure is ignored and the compaction
+// is retried with the next write or at the next start.
+func (e *KeyEngine) shred(records ...*record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	deleted := make([]*record, 0, len(records))
+	for _, r := range records {
+		updated := *r
+		updated.Key = nil
+		updated.Rotations = nil
+		updated.PublicKey = nil
+		updated.State = core.StateDeleted
+		updated.DisabledAt = time.Time{}
+		updated.DeletedAt = now
+		updated.NamespaceDisabled = false
+		deleted = append(deleted, &updated)
+	}
+
+	if err := e.persist(deleted...); err != nil {
+		return err
+	}
+	e.shredPending = true
+	_ = e.compact()
+	return nil
+}
@@ -0,0 +1,354 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ln80/privacy-engine/core"
+	"github.com/ln80/privacy-engine/privacytest"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	gracePeriod := 100 * time.Millisecond
+
+	withGracePeriod := func(c *KeyEngineConfig) {
+		c.GracePeriod = gracePeriod
+	}
+
+	t.Run("file engine", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("file engine with frequent compaction", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod, func(c *KeyEngineConfig) {
+			c.CompactThreshold = 1
+		})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("versioned file engine", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunVersionedKeyEngineTest(t, ctx, eng)
+	})
+
+	t.Run("file engine namespace", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunNamespaceKeyEngineTest(t, ctx, eng)
+	})
+
+	t.Run("file engine expiry", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunExpiringKeyEngineTest(t, ctx, eng)
+	})
+
+	t.Run("file engine hold", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunHoldKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("file engine public keys", func(t *testing.T) {
+		eng, err := NewKeyEngine(t.TempDir(), withGracePeriod)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		privacytest.RunPublicKeyEngineTest(t, ctx, eng)
+	})
+}
+
+func TestKeyEngine_Persistence(t *testing.T) {
+	ctx := context.Background()
+
+	namespace := "tenant-f0l8a1"
+
+	open := func(t *testing.T, dir string) *KeyEngine {
+		t.Helper()
+
+		eng, err := NewKeyEngine(dir)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		t.Cleanup(func() { _ = eng.Close() })
+		return eng
+	}
+
+	assertKeys := func(t *testing.T, eng core.KeyEngine, want core.KeyMap, keyIDs ...string) {
+		t.Helper()
+
+		got, err := eng.GetKeys(ctx, namespace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if len(want) != len(got) {
+			t.Fatalf("expect %d keys, got %d", len(want), len(got))
+		}
+		for keyID, key := range want {
+			if got[keyID] != key {
+				t.Fatalf("expect key %s be equals to the persisted one", keyID)
+			}
+		}
+	}
+
+	t.Run("reload keys after restart", func(t *testing.T) {
+		dir := t.TempDir()
+
+		eng := open(t, dir)
+		keys, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-1", "sub-2", "sub-3"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, namespace, "sub-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		eng = open(t, dir)
+		assertKeys(t, eng, core.KeyMap{"sub-1": keys["sub-1"], "sub-3": keys["sub-3"]}, "sub-1", "sub-2", "sub-3")
+
+		if err := eng.ReEnableKey(ctx, namespace, "sub-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.Compact(); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		eng = open(t, dir)
+		assertKeys(t, eng, keys, "sub-1", "sub-2", "sub-3")
+	})
+
+	t.Run("recover from a torn journal record", func(t *testing.T) {
+		dir := t.TempDir()
+
+		eng := open(t, dir)
+		keys, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-1"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		// simulate a crash in the middle of a record write
+		frame, err := encodeFrame(record{Namespace: namespace, ID: "sub-2", Key: []byte("torn"), State: core.StateActive})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_, _ = f.Write(frame[:len(frame)-3])
+		_ = f.Close()
+
+		eng = open(t, dir)
+		assertKeys(t, eng, keys, "sub-1", "sub-2")
+
+		// assert new records are not hidden by the torn one
+		keys2, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-2"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		eng = open(t, dir)
+		assertKeys(t, eng, core.KeyMap{"sub-1": keys["sub-1"], "sub-2": keys2["sub-2"]}, "sub-1", "sub-2")
+	})
+
+	t.Run("ignore a journal already compacted into the snapshot", func(t *testing.T) {
+		dir := t.TempDir()
+
+		eng := open(t, dir)
+		keys, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-1"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		journal, err := os.ReadFile(filepath.Join(dir, journalFile))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DeleteKey(ctx, namespace, "sub-1"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		// simulate a crash after writing the snapshot and before resetting the journal.
+		if err := os.WriteFile(filepath.Join(dir, journalFile), journal, 0o600); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		eng = open(t, dir)
+		assertKeys(t, eng, core.KeyMap{}, "sub-1")
+
+		// assert the deleted key isn't resurrected
+		if want, err := core.ErrKeyNotFound, eng.ReEnableKey(ctx, namespace, "sub-1"); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		if onDisk(t, dir, keys["sub-1"]) {
+			t.Fatal("expect deleted key be removed from the disk")
+		}
+	})
+
+	t.Run("physically remove deleted keys", func(t *testing.T) {
+		dir := t.TempDir()
+
+		eng, err := NewKeyEngine(dir, func(c *KeyEngineConfig) {
+			c.GracePeriod = 10 * time.Millisecond
+		})
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		defer eng.Close()
+
+		keys, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-1", "sub-2", "sub-3"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		for _, key := range keys {
+			if !onDisk(t, dir, key) {
+				t.Fatal("expect key be persisted on the disk")
+			}
+		}
+
+		if err := eng.DeleteKey(ctx, namespace, "sub-1"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if err := eng.DisableKey(ctx, namespace, "sub-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := eng.DeleteUnusedKeys(ctx, namespace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		if onDisk(t, dir, keys["sub-1"]) || onDisk(t, dir, keys["sub-2"]) {
+			t.Fatal("expect deleted keys be removed from the disk")
+		}
+		if !onDisk(t, dir, keys["sub-3"]) {
+			t.Fatal("expect active key be persisted on the disk")
+		}
+	})
+
+	t.Run("retry a failed compaction of deleted keys", func(t *testing.T) {
+		dir := t.TempDir()
+
+		// make the snapshot writes fail
+		tmp := filepath.Join(dir, snapshotFile+".tmp")
+		failCompaction := func(t *testing.T, fail bool) {
+			t.Helper()
+
+			var err error
+			if fail {
+				err = os.Mkdir(tmp, 0o700)
+			} else {
+				err = os.Remove(tmp)
+			}
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+		}
+
+		eng := open(t, dir)
+		keys, err := eng.GetOrCreateKeys(ctx, namespace, []string{"sub-1", "sub-2", "sub-3"}, nil)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+
+		failCompaction(t, true)
+		if err := eng.DeleteKey(ctx, namespace, "sub-1"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		assertKeys(t, eng, core.KeyMap{"sub-2": keys["sub-2"], "sub-3": keys["sub-3"]}, "sub-1", "sub-2", "sub-3")
+		failCompaction(t, false)
+		if !onDisk(t, dir, keys["sub-1"]) {
+			t.Fatal("expect deleted key remain on the disk")
+		}
+
+		// assert the compaction is retried with the next write
+		if err := eng.DisableKey(ctx, namespace, "sub-3"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if onDisk(t, dir, keys["sub-1"]) {
+			t.Fatal("expect deleted key be removed from the disk")
+		}
+
+		failCompaction(t, true)
+		if err := eng.DeleteKey(ctx, namespace, "sub-2"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_ = eng.Close()
+
+		// assert the compaction is retried at the next start
+		eng = open(t, dir)
+		assertKeys(t, eng, core.KeyMap{}, "sub-1", "sub-2", "sub-3")
+		if onDisk(t, dir, keys["sub-2"]) {
+			t.Fatal("expect deleted key be removed from the disk")
+		}
+	})
+}
+
+// onDisk checks whether the given key value exists in the engine files.
+// Note that keys are JSON-encoded, and therefore stored in base64.
+func onDisk(t *testing.T, dir string, key core.Key) bool {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	encoded := []byte(base64.StdEncoding.EncodeToString([]byte(key)))
+	for _, entry := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if bytes.Contains(b, encoded) {
+			return true
+		}
+	}
+	return false
+}
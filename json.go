@@ -0,0 +1,429 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ln80/privacy-engine/core"
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+var (
+	ErrInvalidJSONPath = errors.New("invalid JSON path")
+)
+
+// JSONProtector is implemented by Protector services that encrypt and decrypt JSON documents.
+type JSONProtector interface {
+
+	// EncryptJSON encrypts the Personal data fields of the given JSON document, described by the given spec,
+	// and returns the resulting document, where encrypted values are wire formatted strings, see CheckFormat.
+	// It allows protecting schemaless documents, which can't be described using 'pii' tags.
+	//
+	// Each field's subject ID is resolved relatively to the field, e.g., "$.orders[*].customer.email" uses
+	// the subject ID of its own order when the spec's subject path is "$.orders[*].customer.id", see JSONSpec.
+	// Missing, null, and empty string fields are left untouched, while other non string fields are not supported.
+	//
+	// Similarly to Protector.Encrypt, failures are returned as FieldErrors error according to the configured ErrorPolicy,
+	// where the path of each failure is the concrete JSON path of the field, e.g., "$.orders[2].customer.email".
+	EncryptJSON(ctx context.Context, doc []byte, spec JSONSpec) ([]byte, error)
+
+	// DecryptJSON decrypts the Personal data fields of the given JSON document, described by the given spec,
+	// and returns the resulting document. Subject IDs are read from the wire formatted values, hence
+	// the spec's subject path is not required. Values of forgotten subjects are replaced by empty strings.
+	//
+	// Similarly to Protector.Decrypt, failures are returned as FieldErrors error according to the configured ErrorPolicy.
+	DecryptJSON(ctx context.Context, doc []byte, spec JSONSpec) ([]byte, error)
+}
+
+var _ JSONProtector = &protector{}
+
+// JSONSpec describes the Personal data of JSON documents, see JSONProtector.EncryptJSON.
+//
+// Paths support a subset of JSONPath: the root '$', object members, e.g., "$.user.email",
+// array indexes, e.g., "$.emails[0]", and array wildcards, e.g., "$.orders[*].email".
+type JSONSpec struct {
+
+	// SubjectID is the JSON path of the subject ID, e.g., "$.user.id". The value must be a string or a number.
+	//
+	// Wildcards shared with a field's path are bound to the field's indexes, which allows arrays of objects
+	// that each have their own subject, e.g., "$.orders[*].customer.id" for "$.orders[*].customer.email".
+	// It must resolve to a single value for each field.
+	SubjectID string
+
+	// Fields are the JSON paths of the Personal data fields, e.g., "$.user.email" or "$.orders[*].customer.email".
+	Fields []string
+}
+
+// jsonWildcard is the index of array wildcard segments.
+const jsonWildcard = -1
+
+// jsonSegment presents either an object member or an array index of a JSON path.
+type jsonSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonMatch presents a value matched by a JSON path.
+type jsonMatch struct {
+	// path is the concrete path of the value, e.g., "$.orders[2].email".
+	path string
+
+	// bindings are the indexes matched by the path's wildcards, in order.
+	bindings []int
+
+	value any
+	set   func(any)
+}
+
+// jsonTarget presents a Personal data field of a JSON document to encrypt or decrypt.
+type jsonTarget struct {
+	jsonMatch
+
+	name      string
+	subjectID string
+	err       error
+}
+
+// parseJSONPath parses the given JSON path, see JSONSpec.
+func parseJSONPath(path string) ([]jsonSegment, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s' must start with '$'", ErrInvalidJSONPath, path)
+	}
+
+	segs := make([]jsonSegment, 0)
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: '%s' has an empty member", ErrInvalidJSONPath, path)
+			}
+			segs = append(segs, jsonSegment{key: rest[:end]})
+			rest = rest[end:]
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("%w: '%s' has an unclosed bracket", ErrInvalidJSONPath, path)
+			}
+			seg := jsonSegment{index: jsonWildcard, isIndex: true}
+			if idx := rest[1:end]; idx != "*" {
+				n, err := strconv.Atoi(idx)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("%w: '%s' has an invalid index '%s'", ErrInvalidJSONPath, path, idx)
+				}
+				seg.index = n
+			}
+			segs = append(segs, seg)
+			rest = rest[end+1:]
+
+		default:
+			return nil, fmt.Errorf("%w: '%s' has an unexpected character '%c'", ErrInvalidJSONPath, path, rest[0])
+		}
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("%w: '%s' must not target the root", ErrInvalidJSONPath, path)
+	}
+	return segs, nil
+}
+
+// jsonFieldName returns the name of the field of the given JSON path, e.g., "orders.customer.email",
+// which is used to bind and derive keys. Similarly to struct fields, array indexes are not part of the name.
+func jsonFieldName(segs []jsonSegment) string {
+	keys := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		if !seg.isIndex {
+			keys = append(keys, seg.key)
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+// matchJSON appends the values of the given node matched by the given path segments to the given matches.
+func matchJSON(node any, segs []jsonSegment, m jsonMatch, matches *[]jsonMatch) {
+	if len(segs) == 0 {
+		m.value = node
+		*matches = append(*matches, m)
+		return
+	}
+
+	seg := segs[0]
+	if !seg.isIndex {
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return
+		}
+		v, ok := obj[seg.key]
+		if !ok {
+			return
+		}
+		matchJSON(v, segs[1:], jsonMatch{
+			path:     m.path + "." + seg.key,
+			bindings: m.bindings,
+			set:      func(nv any) { obj[seg.key] = nv },
+		}, matches)
+		return
+	}
+
+	arr, ok := node.([]any)
+	if !ok {
+		return
+	}
+	for i := range arr {
+		if seg.index != jsonWildcard && seg.index != i {
+			continue
+		}
+		bindings := m.bindings
+		if seg.index == jsonWildcard {
+			bindings = append(slices.Clone(bindings), i)
+		}
+		matchJSON(arr[i], segs[1:], jsonMatch{
+			path:     m.path + "[" + strconv.Itoa(i) + "]",
+			bindings: bindings,
+			set:      func(nv any) { arr[i] = nv },
+		}, matches)
+	}
+}
+
+// bindJSONPath returns a copy of the given subject path, where the wildcards shared with the given field path,
+// i.e., part of their common prefix, are replaced by the given indexes matched by the field path.
+func bindJSONPath(subject, field []jsonSegment, bindings []int) []jsonSegment {
+	bound := slices.Clone(subject)
+	k := 0
+	for i, seg := range subject {
+		if i >= len(field) || seg != field[i] {
+			break
+		}
+		if seg.isIndex && seg.index == jsonWildcard {
+			bound[i].index = bindings[k]
+			k++
+		}
+	}
+	return bound
+}
+
+// resolveJSONSubject returns the subject ID of the given subject path, bound to the given field match.
+func resolveJSONSubject(doc any, subject, field []jsonSegment, m jsonMatch) (string, error) {
+	matches := make([]jsonMatch, 0)
+	matchJSON(doc, bindJSONPath(subject, field, m.bindings), jsonMatch{path: "$"}, &matches)
+
+	switch len(matches) {
+	case 0:
+		return "", errors.New("subject ID not found")
+	case 1:
+	default:
+		return "", errors.New("ambiguous subject ID path, it matches multiple values")
+	}
+
+	switch v := matches[0].value.(type) {
+	case string:
+		if v == "" {
+			return "", errors.New("empty subject ID")
+		}
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("invalid subject ID type %T", matches[0].value)
+}
+
+// unmarshalJSON decodes the given JSON document, while preserving numbers as is.
+func unmarshalJSON(doc []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("invalid JSON document, unexpected data after the top-level value")
+	}
+	return v, nil
+}
+
+// marshalJSON encodes the given JSON document. HTML characters are not escaped to keep wire formats readable.
+// Note that object members are sorted by key, and the original formatting is not preserved.
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonTargets returns the string fields of the given JSON document described by the given spec.
+// Subject IDs are resolved if required, and resolution failures are recorded in targets.
+func jsonTargets(doc any, spec JSONSpec, requireSubject bool) ([]jsonTarget, error) {
+	var subject []jsonSegment
+	if requireSubject {
+		var err error
+		if subject, err = parseJSONPath(spec.SubjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := make([]jsonTarget, 0)
+	for _, path := range spec.Fields {
+		field, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := make([]jsonMatch, 0)
+		matchJSON(doc, field, jsonMatch{path: "$"}, &matches)
+		for _, m := range matches {
+			t := jsonTarget{jsonMatch: m, name: jsonFieldName(field)}
+			switch v := m.value.(type) {
+			case nil:
+				continue
+			case string:
+				if v == "" {
+					continue
+				}
+			default:
+				if !requireSubject {
+					continue
+				}
+				t.err = fmt.Errorf("unsupported JSON value type %T", m.value)
+			}
+			if requireSubject && t.err == nil {
+				t.subjectID, t.err = resolveJSONSubject(doc, subject, field, m)
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+// replaceJSON replaces the given targets' values using the given function according to the ErrorPolicy,
+// similarly to replaceAll, and returns the given document encoded.
+//
+// It returns FieldErrors error in case of failure, along with the partial result if allowed by the policy.
+func (p *protector) replaceJSON(doc any, targets []jsonTarget, fn sensitive.ReplaceFunc) ([]byte, error) {
+	var ferrs FieldErrors
+	for _, t := range targets {
+		val, _ := t.value.(string)
+
+		err := t.err
+		if err == nil {
+			var newVal string
+			newVal, err = fn(sensitive.FieldReplace{
+				SubjectID: t.subjectID,
+				Name:      t.name,
+				Options:   sensitive.TagOptions{},
+			}, val)
+			if err == nil {
+				t.set(newVal)
+				continue
+			}
+		}
+
+		ferr := FieldError{Path: t.path, SubjectID: t.subjectID, Err: err}
+		if ferr.SubjectID == "" {
+			_, ferr.SubjectID, _, _ = parseWireFormat(val)
+		}
+		if !p.tolerated(err) {
+			return nil, FieldErrors{ferr}
+		}
+		ferrs = append(ferrs, ferr)
+	}
+
+	result, err := marshalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(ferrs) == 0 {
+		return result, nil
+	}
+	return result, ferrs
+}
+
+// EncryptJSON implements JSONProtector
+func (p *protector) EncryptJSON(ctx context.Context, doc []byte, spec JSONSpec) (result []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	v, err := unmarshalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := jsonTargets(v, spec, true)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectIDs := make([]string, 0)
+	for _, t := range targets {
+		if t.err == nil {
+			subjectIDs = append(subjectIDs, t.subjectID)
+		}
+	}
+	slices.Sort(subjectIDs)
+	subjectIDs = slices.Compact(subjectIDs)
+
+	keys := core.VersionedKeyMap{}
+	if len(subjectIDs) > 0 {
+		if p.encryptOnly {
+			keys, err = p.getOrCreatePublicKeys(ctx, subjectIDs)
+		} else {
+			keys, err = p.getOrCreateLatestKeys(ctx, subjectIDs)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p.replaceJSON(v, targets, p.encryptFunc(keys))
+}
+
+// DecryptJSON implements JSONProtector
+func (p *protector) DecryptJSON(ctx context.Context, doc []byte, spec JSONSpec) (result []byte, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	v, err := unmarshalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := jsonTargets(v, spec, false)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectIDs := make([]string, 0)
+	for _, t := range targets {
+		if _, subjectID, _, err := parseWireFormat(t.value.(string)); err == nil {
+			subjectIDs = append(subjectIDs, subjectID)
+		}
+	}
+	slices.Sort(subjectIDs)
+	subjectIDs = slices.Compact(subjectIDs)
+
+	keys, err := p.getKeyVersions(ctx, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.replaceJSON(v, targets, p.decryptFunc(keys))
+}
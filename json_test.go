@@ -0,0 +1,171 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/memory"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tcs := []struct {
+		path string
+		segs []jsonSegment
+		err  error
+	}{
+		{path: "$.user.email", segs: []jsonSegment{{key: "user"}, {key: "email"}}},
+		{path: "$.orders[*].email", segs: []jsonSegment{{key: "orders"}, {index: jsonWildcard, isIndex: true}, {key: "email"}}},
+		{path: "$[2].email", segs: []jsonSegment{{index: 2, isIndex: true}, {key: "email"}}},
+		{path: "user.email", err: ErrInvalidJSONPath},
+		{path: "$", err: ErrInvalidJSONPath},
+		{path: "$.user..email", err: ErrInvalidJSONPath},
+		{path: "$.orders[-1]", err: ErrInvalidJSONPath},
+		{path: "$.orders[*", err: ErrInvalidJSONPath},
+		{path: "$user", err: ErrInvalidJSONPath},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.path, func(t *testing.T) {
+			segs, err := parseJSONPath(tc.path)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expect err be %v, got %v", tc.err, err)
+			}
+			if want, got := tc.segs, segs; tc.err == nil && !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+}
+
+func TestProtector_JSON(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-js0n"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	doc := []byte(`{
+		"orders": [
+			{"customer": {"id": "sub-1", "email": "idir@example.com", "name": "Idir Moore"}, "total": 12.50},
+			{"customer": {"id": 42, "email": "kal@example.com", "name": null}, "total": 7},
+			{"customer": {"id": "sub-1", "email": ""}}
+		],
+		"note": "<b>shipped</b>"
+	}`)
+	spec := JSONSpec{
+		SubjectID: "$.orders[*].customer.id",
+		Fields:    []string{"$.orders[*].customer.email", "$.orders[*].customer.name"},
+	}
+
+	encrypted, err := p.(JSONProtector).EncryptJSON(ctx, doc, spec)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	v, err := unmarshalJSON(encrypted)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	customer := func(v any, i int) map[string]any {
+		return v.(map[string]any)["orders"].([]any)[i].(map[string]any)["customer"].(map[string]any)
+	}
+
+	// assert each field is encrypted using its own subject, and is compatible with other APIs
+	for i, want := range map[int]string{0: "sub-1", 1: "42"} {
+		email := customer(v, i)["email"].(string)
+		if err := CheckFormat(email); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		_, got, _, err := parseWireFormat(email)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	if err := CheckFormat(customer(v, 0)["name"].(string)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := any(nil), customer(v, 1)["name"]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "", customer(v, 2)["email"]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	values, err := p.(ValuesProtector).DecryptValues(ctx, customer(v, 1)["email"].(string))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "kal@example.com", values[0]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	decrypted, err := p.(JSONProtector).DecryptJSON(ctx, encrypted, JSONSpec{Fields: spec.Fields})
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	want, err := unmarshalJSON(doc)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	got, err := unmarshalJSON(decrypted)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert fields that can't be resolved to a single subject fail
+	_, err = p.(JSONProtector).EncryptJSON(ctx, doc, JSONSpec{SubjectID: spec.SubjectID, Fields: []string{"$.note"}})
+	var ferrs FieldErrors
+	if !errors.As(err, &ferrs) {
+		t.Fatalf("expect err be %T, got %v", ferrs, err)
+	}
+	if want, got := "$.note", ferrs[0].Path; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, err := p.(JSONProtector).EncryptJSON(ctx, doc, JSONSpec{SubjectID: "orders.id", Fields: spec.Fields}); !errors.Is(err, ErrInvalidJSONPath) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidJSONPath, err)
+	}
+
+	// assert partial results are returned according to the error policy
+	bp := NewProtector(nspace, memory.NewKeyEngine(), func(pc *ProtectorConfig) {
+		pc.ErrorPolicy = BestEffort
+	})
+	partial, err := bp.(JSONProtector).EncryptJSON(ctx, doc, JSONSpec{SubjectID: spec.SubjectID, Fields: append(spec.Fields, "$.orders[*].total")})
+	if !errors.As(err, &ferrs) {
+		t.Fatalf("expect err be %T, got %v", ferrs, err)
+	}
+	if want, got := []string{"$.orders[0].total", "$.orders[1].total"}, []string{ferrs[0].Path, ferrs[1].Path}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if v, err = unmarshalJSON(partial); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := CheckFormat(customer(v, 0)["email"].(string)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert fields are crypto-erased along with their subject
+	if err := p.Forget(ctx, "sub-1"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	decrypted, err = p.(JSONProtector).DecryptJSON(ctx, encrypted, JSONSpec{Fields: spec.Fields})
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if v, err = unmarshalJSON(decrypted); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "", customer(v, 0)["email"]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "kal@example.com", customer(v, 1)["email"]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -33,6 +33,12 @@ type Profile struct {
 	Country  string
 }
 
+type Account struct {
+	UserID   string `pii:"subjectID"`
+	Email    string `pii:"data,deterministic"`
+	Fullname string `pii:"data"`
+}
+
 type StructSubjectNotFound struct {
 	Val1 string
 	Val2 string `pii:"data"`
@@ -2,8 +2,10 @@ package memory
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -13,41 +15,104 @@ import (
 
 const (
 	cacheTTLDefault = 20 * time.Second
+
+	listLimitDefault = 100
 )
 
 type keyCache struct {
-	ID    string
-	Key   core.Key
-	At    int64
-	State core.KeyState
+	ID         string
+	Key        core.Key
+	At         int64
+	State      core.KeyState
+	CreatedAt  time.Time
+	DisabledAt time.Time
+	DeletedAt  time.Time
+	ExpiresAt  time.Time
+	Hold       *core.KeyHold
+	PublicKey  core.Key
+
+	// Rotations contains the key versions created by rotations, i.e., starting from version 2.
+	Rotations []core.Key
+	// NamespaceDisabled reports whether the key was disabled along with its namespace.
+	NamespaceDisabled bool
+
+	// Versioned reports whether Rotations contains all versions of the key.
+	// It's only relevant in cache mode, as the store holds all versions.
+	Versioned bool
 }
 
 func newKeyCache(id string, key core.Key) keyCache {
 	return keyCache{
-		ID:    id,
-		Key:   key,
-		At:    time.Now().Unix(),
-		State: core.StateActive,
+		ID:        id,
+		Key:       key,
+		At:        time.Now().Unix(),
+		State:     core.StateActive,
+		CreatedAt: time.Now(),
 	}
 }
 
+func newVersionsCache(id string, versions map[int]core.Key) keyCache {
+	kc := newKeyCache(id, versions[1])
+	kc.Rotations = make([]core.Key, 0, len(versions))
+	for v := 2; v <= len(versions); v++ {
+		kc.Rotations = append(kc.Rotations, versions[v])
+	}
+	kc.Versioned = true
+	return kc
+}
+
+func (kc keyCache) latest() core.VersionedKey {
+	if n := len(kc.Rotations); n > 0 {
+		return core.VersionedKey{Version: n + 1, Key: kc.Rotations[n-1]}
+	}
+	return core.VersionedKey{Version: 1, Key: kc.Key}
+}
+
+func (kc keyCache) versions() map[int]core.Key {
+	versions := map[int]core.Key{1: kc.Key}
+	for i, k := range kc.Rotations {
+		versions[i+2] = k
+	}
+	return versions
+}
+
 type engine struct {
 	origin core.KeyEngine
 
 	cache map[string]map[string]keyCache
 	mu    sync.RWMutex
 
-	ttl time.Duration
+	ttl         time.Duration
+	gracePeriod time.Duration
 }
 
 var _ core.KeyEngine = &engine{}
 var _ core.KeyEngineCache = &engine{}
+var _ core.KeyRewriter = &engine{}
+var _ core.VersionedKeyEngine = &engine{}
+var _ core.KeyInspector = &engine{}
+var _ core.NamespaceKeyEngine = &engine{}
+var _ core.ExpiringKeyEngine = &engine{}
+var _ core.HoldKeyEngine = &engine{}
+var _ core.PublicKeyEngine = &engine{}
 
 // NewKeyEngine returns an in-memory core.KeyEngine implementation,
 // and is mainly used for tests.
-func NewKeyEngine() core.KeyEngine {
+//
+// Options params allow overwriting the default configuration, e.g., the grace period
+// after which disabled keys are hard deleted by DeleteUnusedKeys.
+func NewKeyEngine(opts ...func(*core.KeyEngineConfig)) core.KeyEngine {
+	cfg := core.NewKeyEngineConfig()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
 	return &engine{
-		cache: make(map[string]map[string]keyCache),
+		cache:       make(map[string]map[string]keyCache),
+		gracePeriod: cfg.GracePeriod,
 	}
 }
 
@@ -192,6 +257,10 @@ func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error
 
 	keyCache, ok := cache[keyID]
 	if !ok {
+		// the key is not cached, while origin has successfully disabled it.
+		if e.origin != nil {
+			return nil
+		}
 		return core.ErrKeyNotFound
 	}
 
@@ -199,7 +268,19 @@ func (e *engine) DisableKey(ctx context.Context, namespace, keyID string) error
 		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
 	}
 
+	if keyCache.Hold != nil && keyCache.State == core.StateActive {
+		keyCache.Hold = requestForget(keyCache.Hold, true)
+		cache[keyID] = keyCache
+		return core.ErrKeyOnHold
+	}
+
+	// keep the first disable timestamp to not extend the grace period.
+	if keyCache.State != core.StateDisabled {
+		keyCache.DisabledAt = time.Now()
+	}
 	keyCache.State = core.StateDisabled
+	// the subject is individually disabled, reenabling its namespace must not reenable it.
+	keyCache.NamespaceDisabled = false
 	cache[keyID] = keyCache
 
 	return nil
@@ -220,6 +301,10 @@ func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error
 
 	keyCache, ok := cache[keyID]
 	if !ok {
+		// the key is not cached, while origin has successfully reenabled it.
+		if e.origin != nil {
+			return nil
+		}
 		return core.ErrKeyNotFound
 	}
 
@@ -228,6 +313,10 @@ func (e *engine) ReEnableKey(ctx context.Context, namespace, keyID string) error
 	}
 
 	keyCache.State = core.StateActive
+	keyCache.DisabledAt = time.Time{}
+	keyCache.NamespaceDisabled = false
+	// the subject is recovered, cancel the forget requested while on hold.
+	keyCache.Hold = requestForget(keyCache.Hold, false)
 	cache[keyID] = keyCache
 
 	return nil
@@ -251,8 +340,18 @@ func (e *engine) DeleteKey(ctx context.Context, namespace, keyID string) error {
 		return nil
 	}
 
+	if keyCache.Hold != nil {
+		keyCache.Hold = requestForget(keyCache.Hold, true)
+		cache[keyID] = keyCache
+		return core.ErrKeyOnHold
+	}
+
 	keyCache.Key = ""
+	keyCache.PublicKey = ""
+	keyCache.Rotations = nil
 	keyCache.State = core.StateDeleted
+	keyCache.DisabledAt = time.Time{}
+	keyCache.DeletedAt = time.Now()
 	cache[keyID] = keyCache
 
 	return nil
@@ -285,8 +384,739 @@ func (e *engine) ClearCache(ctx context.Context, namespace string, force bool) e
 }
 
 // DeleteUnusedKeys implements core.KeyEngine
-func (*engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
-	panic("unimplemented")
+func (e *engine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	if e.origin != nil {
+		if err := e.origin.DeleteUnusedKeys(ctx, namespace); err != nil {
+			return err
+		}
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for keyID, keyCache := range cache {
+		if keyCache.State != core.StateDisabled {
+			continue
+		}
+
+		// The cache doesn't know about origin's grace period,
+		// therefore evict disabled keys as they might be hard deleted.
+		if e.origin != nil {
+			delete(cache, keyID)
+			continue
+		}
+
+		if time.Since(keyCache.DisabledAt) < e.gracePeriod || keyCache.Hold != nil {
+			continue
+		}
+
+		keyCache.Key = ""
+		keyCache.PublicKey = ""
+		keyCache.Rotations = nil
+		keyCache.State = core.StateDeleted
+		keyCache.DisabledAt = time.Time{}
+		keyCache.DeletedAt = time.Now()
+		cache[keyID] = keyCache
+	}
+
+	return nil
+}
+
+// RewriteKeys implements core.KeyRewriter
+func (e *engine) RewriteKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyRewriteFunc) error {
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		rw, ok := e.origin.(core.KeyRewriter)
+		if !ok {
+			return errors.Join(core.ErrRewriteKeyFailure, core.ErrUnsupported)
+		}
+		if err := rw.RewriteKeys(ctx, namespace, keyIDs, fn); err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		// evict rewritten keys, they will be fetched again from origin.
+		if keyIDs == nil {
+			clear(cache)
+		}
+		for _, keyID := range keyIDs {
+			delete(cache, keyID)
+		}
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if keyIDs == nil {
+		keyIDs = make([]string, 0, len(cache))
+		for keyID := range cache {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+
+	// compute all new values before applying any of them
+	rewritten := make(map[string]keyCache)
+	for _, keyID := range keyIDs {
+		keyCache, ok := cache[keyID]
+		if !ok || keyCache.State == core.StateDeleted {
+			continue
+		}
+		newKey, err := fn(keyID, keyCache.Key)
+		if err != nil {
+			return errors.Join(core.ErrRewriteKeyFailure, err)
+		}
+		keyCache.Key = newKey
+
+		rotations := make([]core.Key, 0, len(keyCache.Rotations))
+		for _, k := range keyCache.Rotations {
+			newKey, err := fn(keyID, k)
+			if err != nil {
+				return errors.Join(core.ErrRewriteKeyFailure, err)
+			}
+			rotations = append(rotations, newKey)
+		}
+		keyCache.Rotations = rotations
+		rewritten[keyID] = keyCache
+	}
+	for keyID, keyCache := range rewritten {
+		cache[keyID] = keyCache
+	}
+
+	return nil
+}
+
+// GetOrCreateLatestKeys implements core.VersionedKeyEngine
+//
+// In cache mode, it falls back to the first version of keys if the origin engine is not versioned.
+func (e *engine) GetOrCreateLatestKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		versioned, ok := e.origin.(core.VersionedKeyEngine)
+		if !ok {
+			keys, err := e.origin.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+			if err != nil {
+				return nil, err
+			}
+			latest := make(core.VersionedKeyMap)
+			for keyID, k := range keys {
+				cache[keyID] = newVersionsCache(keyID, map[int]core.Key{1: k})
+				latest[keyID] = core.VersionedKey{Version: 1, Key: k}
+			}
+			return latest, nil
+		}
+
+		latest, err := versioned.GetOrCreateLatestKeys(ctx, namespace, keyIDs, keyGen)
+		if err != nil {
+			return nil, err
+		}
+		for keyID, vk := range latest {
+			if vk.Version == 1 {
+				cache[keyID] = newVersionsCache(keyID, map[int]core.Key{1: vk.Key})
+				continue
+			}
+			// evict outdated entries, they will be fetched again from origin.
+			if kc, ok := cache[keyID]; ok && (!kc.Versioned || kc.latest().Version != vk.Version) {
+				delete(cache, keyID)
+			}
+		}
+		return latest, nil
+	}
+
+	keys, err := e.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	latest := make(core.VersionedKeyMap)
+	for keyID := range keys {
+		latest[keyID] = cache[keyID].latest()
+	}
+	return latest, nil
+}
+
+// GetKeyVersions implements core.VersionedKeyEngine
+//
+// In cache mode, it falls back to the first version of keys if the origin engine is not versioned.
+func (e *engine) GetKeyVersions(ctx context.Context, namespace string, keyIDs []string) (core.KeyVersionsMap, error) {
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	found := make(core.KeyVersionsMap)
+	missed := []string{}
+	for _, keyID := range keyIDs {
+		kc, ok := cache[keyID]
+		if ok && kc.State != core.StateActive {
+			continue
+		}
+		if ok && (e.origin == nil || kc.Versioned) {
+			found[keyID] = kc.versions()
+			continue
+		}
+		if e.origin != nil {
+			missed = append(missed, keyID)
+		}
+	}
+
+	if e.origin == nil || len(missed) == 0 {
+		return found, nil
+	}
+
+	versioned, ok := e.origin.(core.VersionedKeyEngine)
+	if !ok {
+		keys, err := e.origin.GetKeys(ctx, namespace, missed)
+		if err != nil {
+			return nil, err
+		}
+		for keyID, k := range keys {
+			found[keyID] = map[int]core.Key{1: k}
+			cache[keyID] = newVersionsCache(keyID, found[keyID])
+		}
+		return found, nil
+	}
+
+	versions, err := versioned.GetKeyVersions(ctx, namespace, missed)
+	if err != nil {
+		return nil, err
+	}
+	for keyID, kv := range versions {
+		found[keyID] = kv
+		cache[keyID] = newVersionsCache(keyID, kv)
+	}
+	return found, nil
+}
+
+// RotateKeys implements core.VersionedKeyEngine
+//
+// In cache mode, it returns core.ErrUnsupported error if the origin engine is not versioned.
+func (e *engine) RotateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		versioned, ok := e.origin.(core.VersionedKeyEngine)
+		if !ok {
+			return nil, errors.Join(core.ErrRotateKeyFailure, core.ErrUnsupported)
+		}
+		latest, err := versioned.RotateKeys(ctx, namespace, keyIDs, keyGen)
+		if err != nil {
+			return nil, err
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		// evict rotated keys, they will be fetched again from origin.
+		for _, keyID := range keyIDs {
+			delete(cache, keyID)
+		}
+		return latest, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// generate all new versions before applying any of them
+	latest := make(core.VersionedKeyMap)
+	for _, keyID := range keyIDs {
+		if _, ok := latest[keyID]; ok {
+			continue
+		}
+		kc, ok := cache[keyID]
+		if !ok || kc.State != core.StateActive {
+			return nil, errors.Join(core.ErrRotateKeyFailure, fmt.Errorf("%w: '%s'", core.ErrKeyNotFound, keyID))
+		}
+		newKey, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrRotateKeyFailure, err)
+		}
+		latest[keyID] = core.VersionedKey{Version: kc.latest().Version + 1, Key: core.Key(newKey)}
+	}
+	for keyID, vk := range latest {
+		kc := cache[keyID]
+		kc.Rotations = append(kc.Rotations, vk.Key)
+		cache[keyID] = kc
+	}
+
+	return latest, nil
+}
+
+// DisableNamespace implements core.NamespaceKeyEngine
+//
+// In cache mode, it returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (e *engine) DisableNamespace(ctx context.Context, namespace string) error {
+	return e.updateNamespace(ctx, namespace, core.ErrDisableKeyFailure,
+		func(nke core.NamespaceKeyEngine) error {
+			return nke.DisableNamespace(ctx, namespace)
+		},
+		func(kc keyCache) (keyCache, bool) {
+			if kc.State != core.StateActive || kc.Hold != nil {
+				return kc, false
+			}
+			kc.State = core.StateDisabled
+			kc.DisabledAt = time.Now()
+			kc.NamespaceDisabled = true
+			return kc, true
+		})
+}
+
+// ReEnableNamespace implements core.NamespaceKeyEngine
+//
+// In cache mode, it returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (e *engine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	return e.updateNamespace(ctx, namespace, core.ErrReEnableKeyFailure,
+		func(nke core.NamespaceKeyEngine) error {
+			return nke.ReEnableNamespace(ctx, namespace)
+		},
+		func(kc keyCache) (keyCache, bool) {
+			if kc.State != core.StateDisabled || !kc.NamespaceDisabled {
+				return kc, false
+			}
+			kc.State = core.StateActive
+			kc.DisabledAt = time.Time{}
+			kc.NamespaceDisabled = false
+			return kc, true
+		})
+}
+
+// DeleteNamespace implements core.NamespaceKeyEngine
+//
+// In cache mode, it returns core.ErrUnsupported error if the origin engine doesn't implement core.NamespaceKeyEngine.
+func (e *engine) DeleteNamespace(ctx context.Context, namespace string) error {
+	return e.updateNamespace(ctx, namespace, core.ErrDeleteKeyFailure,
+		func(nke core.NamespaceKeyEngine) error {
+			return nke.DeleteNamespace(ctx, namespace)
+		},
+		func(kc keyCache) (keyCache, bool) {
+			if kc.State == core.StateDeleted || kc.Hold != nil {
+				return kc, false
+			}
+			kc.Key = ""
+			kc.PublicKey = ""
+			kc.Rotations = nil
+			kc.State = core.StateDeleted
+			kc.DisabledAt = time.Time{}
+			kc.DeletedAt = time.Now()
+			kc.NamespaceDisabled = false
+			return kc, true
+		})
+}
+
+// updateNamespace applies the given update to all keys of the namespace in store mode.
+// In cache mode, it forwards the operation to the origin engine and evicts the namespace's cache.
+func (e *engine) updateNamespace(ctx context.Context, namespace string, failure error, forward func(core.NamespaceKeyEngine) error, update func(keyCache) (keyCache, bool)) error {
+	cache := e.cacheOf(namespace)
+
+	if e.origin != nil {
+		nke, ok := e.origin.(core.NamespaceKeyEngine)
+		if !ok {
+			return errors.Join(failure, core.ErrUnsupported)
+		}
+		if err := forward(nke); err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		clear(cache)
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for keyID, kc := range cache {
+		if updated, ok := update(kc); ok {
+			cache[keyID] = updated
+		}
+	}
+	return nil
+}
+
+// ScheduleKeyExpiry implements core.ExpiringKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as expiry deadlines are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (e *engine) ScheduleKeyExpiry(ctx context.Context, namespace, keyID string, at time.Time) error {
+	if e.origin != nil {
+		eke, ok := e.origin.(core.ExpiringKeyEngine)
+		if !ok {
+			return errors.Join(core.ErrScheduleKeyFailure, core.ErrUnsupported)
+		}
+		return eke.ScheduleKeyExpiry(ctx, namespace, keyID, at)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyCache, ok := cache[keyID]
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	if keyCache.State == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	keyCache.ExpiresAt = at
+	cache[keyID] = keyCache
+
+	return nil
+}
+
+// ExpiredKeys implements core.ExpiringKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as expiry deadlines are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (e *engine) ExpiredKeys(ctx context.Context, namespace string, at time.Time, retention time.Duration) ([]string, error) {
+	if e.origin != nil {
+		eke, ok := e.origin.(core.ExpiringKeyEngine)
+		if !ok {
+			return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+		}
+		return eke.ExpiredKeys(ctx, namespace, at, retention)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	expired := make([]string, 0)
+	for keyID, kc := range cache {
+		if kc.expired(at, retention) {
+			expired = append(expired, keyID)
+		}
+	}
+	slices.Sort(expired)
+
+	return expired, nil
+}
+
+// KeyExpired implements core.ExpiringKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as expiry deadlines are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.ExpiringKeyEngine.
+func (e *engine) KeyExpired(ctx context.Context, namespace, keyID string, at time.Time, retention time.Duration) (bool, error) {
+	if e.origin != nil {
+		eke, ok := e.origin.(core.ExpiringKeyEngine)
+		if !ok {
+			return false, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+		}
+		return eke.KeyExpired(ctx, namespace, keyID, at, retention)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	kc, ok := cache[keyID]
+	return ok && kc.expired(at, retention), nil
+}
+
+// expired reports whether the key is active, not held, and expired at the given time, see core.ExpiringKeyEngine.
+func (kc keyCache) expired(at time.Time, retention time.Duration) bool {
+	if kc.State != core.StateActive || kc.Hold != nil {
+		return false
+	}
+	deadline := kc.ExpiresAt
+	if deadline.IsZero() {
+		if retention <= 0 {
+			return false
+		}
+		deadline = kc.CreatedAt.Add(retention)
+	}
+	return !deadline.After(at)
+}
+
+// PlaceHold implements core.HoldKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as holds are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.HoldKeyEngine.
+func (e *engine) PlaceHold(ctx context.Context, namespace, keyID, reason string) error {
+	if e.origin != nil {
+		hke, ok := e.origin.(core.HoldKeyEngine)
+		if !ok {
+			return errors.Join(core.ErrHoldKeyFailure, core.ErrUnsupported)
+		}
+		return hke.PlaceHold(ctx, namespace, keyID, reason)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyCache, ok := cache[keyID]
+	if !ok {
+		return core.ErrKeyNotFound
+	}
+	if keyCache.State == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	hold := core.KeyHold{Reason: reason, PlacedAt: time.Now()}
+	if keyCache.Hold != nil {
+		hold.PlacedAt = keyCache.Hold.PlacedAt
+		hold.ForgetRequested = keyCache.Hold.ForgetRequested
+	}
+	keyCache.Hold = &hold
+	cache[keyID] = keyCache
+
+	return nil
+}
+
+// ReleaseHold implements core.HoldKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as holds are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.HoldKeyEngine.
+func (e *engine) ReleaseHold(ctx context.Context, namespace, keyID string) (core.KeyHold, error) {
+	if e.origin != nil {
+		hke, ok := e.origin.(core.HoldKeyEngine)
+		if !ok {
+			return core.KeyHold{}, errors.Join(core.ErrHoldKeyFailure, core.ErrUnsupported)
+		}
+		return hke.ReleaseHold(ctx, namespace, keyID)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyCache, ok := cache[keyID]
+	if !ok {
+		return core.KeyHold{}, core.ErrKeyNotFound
+	}
+	if keyCache.State == core.StateDeleted {
+		return core.KeyHold{}, fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+	if keyCache.Hold == nil {
+		return core.KeyHold{}, nil
+	}
+
+	hold := *keyCache.Hold
+	keyCache.Hold = nil
+	cache[keyID] = keyCache
+
+	return hold, nil
+}
+
+// requestForget returns a copy of the given hold with the updated forget request, or nil if the key is not held.
+func requestForget(hold *core.KeyHold, requested bool) *core.KeyHold {
+	if hold == nil {
+		return nil
+	}
+	updated := *hold
+	updated.ForgetRequested = requested
+	return &updated
+}
+
+// DescribeKeys implements core.KeyInspector
+//
+// In cache mode, it's forwarded to the origin engine, as keys' metadata are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.KeyInspector.
+func (e *engine) DescribeKeys(ctx context.Context, namespace string, keyIDs []string) (map[string]core.KeyMetadata, error) {
+	if e.origin != nil {
+		inspector, ok := e.origin.(core.KeyInspector)
+		if !ok {
+			return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+		}
+		return inspector.DescribeKeys(ctx, namespace, keyIDs)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	metadata := make(map[string]core.KeyMetadata)
+	for _, keyID := range keyIDs {
+		if kc, ok := cache[keyID]; ok {
+			metadata[keyID] = e.metadata(kc)
+		}
+	}
+	return metadata, nil
+}
+
+// ListKeys implements core.KeyInspector
+//
+// In cache mode, it's forwarded to the origin engine, as keys' metadata are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.KeyInspector.
+func (e *engine) ListKeys(ctx context.Context, namespace string, filter core.KeyFilter, cursor string) ([]core.KeyMetadata, string, error) {
+	if e.origin != nil {
+		inspector, ok := e.origin.(core.KeyInspector)
+		if !ok {
+			return nil, "", errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+		}
+		return inspector.ListKeys(ctx, namespace, filter, cursor)
+	}
+
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = listLimitDefault
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keyIDs := make([]string, 0, len(cache))
+	for keyID := range cache {
+		if cursor == "" || keyID > after {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	slices.Sort(keyIDs)
+
+	page := make([]core.KeyMetadata, 0, limit)
+	for _, keyID := range keyIDs {
+		m := e.metadata(cache[keyID])
+		if !filter.Match(m) {
+			continue
+		}
+		if len(page) == limit {
+			// there is at least one more matching key.
+			return page, encodeCursor(page[len(page)-1].ID), nil
+		}
+		page = append(page, m)
+	}
+	return page, "", nil
+}
+
+// GetOrCreatePublicKeys implements core.PublicKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as public keys are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.PublicKeyEngine.
+func (e *engine) GetOrCreatePublicKeys(ctx context.Context, namespace string, keyIDs []string, keyPairGen core.KeyPairGen) (core.KeyMap, error) {
+	if e.origin != nil {
+		pke, ok := e.origin.(core.PublicKeyEngine)
+		if !ok {
+			return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+		}
+		return pke.GetOrCreatePublicKeys(ctx, namespace, keyIDs, keyPairGen)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pubs := core.NewKeyMap()
+	for _, keyID := range keyIDs {
+		if keyCache, ok := cache[keyID]; ok {
+			// do not create a new key for disabled or deleted ones
+			if keyCache.State == core.StateActive && keyCache.PublicKey != "" {
+				pubs[keyID] = keyCache.PublicKey
+			}
+			continue
+		}
+
+		newKey, pub, err := keyPairGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+		keyCache := newKeyCache(keyID, newKey)
+		keyCache.PublicKey = pub
+		cache[keyID] = keyCache
+
+		pubs[keyID] = pub
+	}
+
+	return pubs, nil
+}
+
+// PutPublicKeys implements core.PublicKeyEngine
+//
+// In cache mode, it's forwarded to the origin engine, as public keys are not cached.
+// It returns core.ErrUnsupported error if the origin engine doesn't implement core.PublicKeyEngine.
+func (e *engine) PutPublicKeys(ctx context.Context, namespace string, publicKeys core.KeyMap) error {
+	if e.origin != nil {
+		pke, ok := e.origin.(core.PublicKeyEngine)
+		if !ok {
+			return errors.Join(core.ErrPersistKeyFailure, core.ErrUnsupported)
+		}
+		return pke.PutPublicKeys(ctx, namespace, publicKeys)
+	}
+
+	cache := e.cacheOf(namespace)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for keyID, pub := range publicKeys {
+		keyCache, ok := cache[keyID]
+		if !ok || keyCache.State != core.StateActive || keyCache.PublicKey != "" {
+			continue
+		}
+		keyCache.PublicKey = pub
+		cache[keyID] = keyCache
+	}
+
+	return nil
+}
+
+func (e *engine) metadata(kc keyCache) core.KeyMetadata {
+	latest := kc.latest()
+	m := core.KeyMetadata{
+		ID:         kc.ID,
+		State:      kc.State,
+		Version:    latest.Version,
+		Algorithm:  aes.KeyAlgorithm([]byte(latest.Key)),
+		CreatedAt:  kc.CreatedAt,
+		DisabledAt: kc.DisabledAt,
+		DeletedAt:  kc.DeletedAt,
+		ExpiresAt:  kc.ExpiresAt,
+	}
+	if kc.Hold != nil {
+		hold := *kc.Hold
+		m.Hold = &hold
+	}
+	if kc.State == core.StateDisabled {
+		m.PurgeAt = kc.DisabledAt.Add(e.gracePeriod)
+	}
+	return m
+}
+
+func encodeCursor(keyID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(keyID))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	keyID, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errors.Join(core.ErrInvalidCursor, err)
+	}
+	return string(keyID), nil
 }
 
 // Origin implements core.KeyEngineCache
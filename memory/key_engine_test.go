@@ -2,26 +2,150 @@ package memory
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/ln80/privacy-engine/core"
 	"github.com/ln80/privacy-engine/privacytest"
 )
 
 func TestKeyEngine(t *testing.T) {
 	ctx := context.Background()
 
+	gracePeriod := 100 * time.Millisecond
+
+	withGracePeriod := func(c *core.KeyEngineConfig) {
+		c.GracePeriod = gracePeriod
+	}
+
 	t.Run("in-memory engine", func(t *testing.T) {
-		eng := NewKeyEngine()
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("in-memory engine with auto delete unused keys", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
 
-		privacytest.RunKeyEngineTest(t, ctx, eng)
+		namespace := "tenant-p0d21k"
+		privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.Namespace = namespace
+			c.GracePeriod = gracePeriod
+			c.AutoDeleteUnusedHook = func() {
+				// simulate a scheduled job that regularly deletes unused keys
+				if err := eng.DeleteUnusedKeys(ctx, namespace); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+			}
+		})
 	})
 
 	t.Run("in-memory cache wrapper engine", func(t *testing.T) {
-		originEng := NewKeyEngine()
+		originEng := NewKeyEngine(withGracePeriod)
 
 		eng := NewCacheWrapper(originEng, 20*time.Minute)
 
-		privacytest.RunKeyEngineTest(t, ctx, eng)
+		privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("versioned in-memory engine", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunVersionedKeyEngineTest(t, ctx, eng.(core.VersionedKeyEngine))
+	})
+
+	t.Run("versioned in-memory cache wrapper engine", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunVersionedKeyEngineTest(t, ctx, eng.(core.VersionedKeyEngine))
+	})
+
+	t.Run("in-memory engine inspector", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunKeyInspectorTest(t, ctx, eng.(core.KeyInspector), func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("in-memory cache wrapper engine inspector", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunKeyInspectorTest(t, ctx, eng.(core.KeyInspector), func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("in-memory cache wrapper engine inspector with unsupported origin", func(t *testing.T) {
+		eng := NewCacheWrapper(struct{ core.KeyEngine }{NewKeyEngine()}, 20*time.Minute)
+
+		if _, err := eng.(core.KeyInspector).DescribeKeys(ctx, "tenant-p0d21k", []string{"sub-1"}); !errors.Is(err, core.ErrUnsupported) {
+			t.Fatalf("expect err be %v, got: %v", core.ErrUnsupported, err)
+		}
+	})
+
+	t.Run("in-memory engine namespace", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunNamespaceKeyEngineTest(t, ctx, eng.(core.NamespaceKeyEngine))
+	})
+
+	t.Run("in-memory cache wrapper engine namespace", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunNamespaceKeyEngineTest(t, ctx, eng.(core.NamespaceKeyEngine))
+	})
+
+	t.Run("in-memory engine expiry", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunExpiringKeyEngineTest(t, ctx, eng.(core.ExpiringKeyEngine))
+	})
+
+	t.Run("in-memory cache wrapper engine expiry", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunExpiringKeyEngineTest(t, ctx, eng.(core.ExpiringKeyEngine))
+	})
+
+	t.Run("in-memory engine hold", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunHoldKeyEngineTest(t, ctx, eng.(core.HoldKeyEngine), func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("in-memory cache wrapper engine hold", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunHoldKeyEngineTest(t, ctx, eng.(core.HoldKeyEngine), func(c *privacytest.KeyEngineTestConfig) {
+			c.GracePeriod = gracePeriod
+		})
+	})
+
+	t.Run("in-memory engine public keys", func(t *testing.T) {
+		eng := NewKeyEngine(withGracePeriod)
+
+		privacytest.RunPublicKeyEngineTest(t, ctx, eng.(core.PublicKeyEngine))
+	})
+
+	t.Run("in-memory cache wrapper engine public keys", func(t *testing.T) {
+		eng := NewCacheWrapper(NewKeyEngine(withGracePeriod), 20*time.Minute)
+
+		privacytest.RunPublicKeyEngineTest(t, ctx, eng.(core.PublicKeyEngine))
+	})
+
+	t.Run("in-memory cache wrapper engine namespace with unsupported origin", func(t *testing.T) {
+		eng := NewCacheWrapper(struct{ core.KeyEngine }{NewKeyEngine()}, 20*time.Minute)
+
+		if err := eng.(core.NamespaceKeyEngine).DisableNamespace(ctx, "tenant-p0d21k"); !errors.Is(err, core.ErrUnsupported) {
+			t.Fatalf("expect err be %v, got: %v", core.ErrUnsupported, err)
+		}
 	})
 }
@@ -0,0 +1,143 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+var (
+	ErrInvalidPadding = errors.New("invalid padding")
+)
+
+const (
+	// tagOptionPad is the 'pii' tag option that overrides the configured Padding of a field,
+	// e.g., `pii:"data,pad=32"` or `pii:"data,pad=16|64|256"`, see ParsePadding.
+	tagOptionPad = "pad"
+
+	// paddingISO7816 is the wire format value of the ISO/IEC 7816-4 padding scheme.
+	paddingISO7816 = "iso7816"
+)
+
+// Padding defines the lengths plain texts are padded to before encryption, so that cipher texts
+// don't reveal the length of plain texts, e.g., of names or notes.
+// Plain texts are padded using the ISO/IEC 7816-4 scheme, i.e., a 0x80 byte followed by zero bytes.
+//
+// The padded length always exceeds the plain text's one by at least one byte.
+// Note that empty fields are not encrypted, therefore they remain distinguishable.
+type Padding struct {
+
+	// BlockSize pads plain texts to a multiple of the given size.
+	BlockSize int
+
+	// Buckets pads plain texts to the smallest bucket size that fits, e.g., []int{16, 64, 256}.
+	// Larger plain texts are padded according to BlockSize if set, otherwise to a multiple of the largest bucket.
+	Buckets []int
+}
+
+// IsZero reports whether the Padding is disabled.
+func (pd Padding) IsZero() bool {
+	return pd.BlockSize == 0 && len(pd.Buckets) == 0
+}
+
+// ParsePadding parses the given padding spec: a block size, e.g., "32", or bucket sizes separated by '|',
+// e.g., "16|64|256". The "none" spec disables padding.
+func ParsePadding(spec string) (Padding, error) {
+	if spec == "none" {
+		return Padding{}, nil
+	}
+
+	sizes := strings.Split(spec, "|")
+	pd := Padding{}
+	for _, s := range sizes {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return Padding{}, fmt.Errorf("%w: invalid size '%s'", ErrInvalidPadding, s)
+		}
+		pd.Buckets = append(pd.Buckets, size)
+	}
+	if len(sizes) == 1 {
+		pd = Padding{BlockSize: pd.Buckets[0]}
+	}
+	return pd, pd.validate()
+}
+
+func (pd Padding) validate() error {
+	if pd.BlockSize < 0 {
+		return fmt.Errorf("%w: negative block size", ErrInvalidPadding)
+	}
+	for _, b := range pd.Buckets {
+		if b <= 0 {
+			return fmt.Errorf("%w: bucket sizes must be positive", ErrInvalidPadding)
+		}
+	}
+	return nil
+}
+
+// length returns the padded length of a plain text of the given length.
+func (pd Padding) length(n int) int {
+	n++ // the padding takes at least one byte
+
+	buckets := slices.Clone(pd.Buckets)
+	slices.Sort(buckets)
+	for _, b := range buckets {
+		if n <= b {
+			return b
+		}
+	}
+
+	size := pd.BlockSize
+	if size == 0 && len(buckets) > 0 {
+		size = buckets[len(buckets)-1]
+	}
+	if size <= 1 {
+		return n
+	}
+	return (n + size - 1) / size * size
+}
+
+// fieldPadding returns the Padding of the given field, i.e., its 'pad' tag option if set, otherwise the configured one.
+func (p *protector) fieldPadding(fr sensitive.FieldReplace) (Padding, error) {
+	if spec, ok := fr.Options[tagOptionPad]; ok {
+		return ParsePadding(spec)
+	}
+	return p.Padding, p.Padding.validate()
+}
+
+// padded reports whether the given field's values are padded.
+func (p *protector) padded(fr sensitive.FieldReplace) bool {
+	if spec, ok := fr.Options[tagOptionPad]; ok {
+		return spec != "none"
+	}
+	return !p.Padding.IsZero()
+}
+
+// pad pads the given plain text according to the given field's Padding.
+func (p *protector) pad(fr sensitive.FieldReplace, plainTxt string) (string, error) {
+	pd, err := p.fieldPadding(fr)
+	if err != nil {
+		return "", err
+	}
+	return padISO7816(plainTxt, pd.length(len(plainTxt))), nil
+}
+
+// padISO7816 pads the given plain text to the given length using the ISO/IEC 7816-4 scheme.
+func padISO7816(plainTxt string, length int) string {
+	padded := make([]byte, length)
+	copy(padded, plainTxt)
+	padded[len(plainTxt)] = 0x80
+	return string(padded)
+}
+
+// unpadISO7816 removes the ISO/IEC 7816-4 padding of the given plain text.
+func unpadISO7816(padded string) (string, error) {
+	plainTxt, ok := strings.CutSuffix(strings.TrimRight(padded, "\x00"), "\x80")
+	if !ok {
+		return "", fmt.Errorf("%w: padding marker not found", ErrInvalidPadding)
+	}
+	return plainTxt, nil
+}
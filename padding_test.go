@@ -0,0 +1,150 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/memory"
+)
+
+type Note struct {
+	UserID   string `pii:"subjectID"`
+	Title    string `pii:"data"`
+	Body     string `pii:"data,pad=16|64|256"`
+	Nickname string `pii:"data,pad=none"`
+}
+
+func TestPadding(t *testing.T) {
+	tcs := []struct {
+		spec    string
+		lengths map[int]int
+		err     error
+	}{
+		{spec: "32", lengths: map[int]int{0: 32, 31: 32, 32: 64, 70: 96}},
+		{spec: "16|64|256", lengths: map[int]int{0: 16, 15: 16, 16: 64, 255: 256, 256: 512, 600: 768}},
+		{spec: "64|16", lengths: map[int]int{10: 16, 20: 64}},
+		{spec: "1", lengths: map[int]int{0: 1, 10: 11}},
+		{spec: "none", lengths: map[int]int{10: 11}},
+		{spec: "abc", err: ErrInvalidPadding},
+		{spec: "16|0", err: ErrInvalidPadding},
+		{spec: "-8", err: ErrInvalidPadding},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.spec, func(t *testing.T) {
+			pd, err := ParsePadding(tc.spec)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expect err be %v, got %v", tc.err, err)
+			}
+			for n, want := range tc.lengths {
+				if got := pd.length(n); want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+		})
+	}
+
+	for _, plainTxt := range []string{"", "Idir Moore", "trailing\x00", "marker\x80", "\x80\x00"} {
+		padded := padISO7816(plainTxt, len(plainTxt)+8)
+		got, err := unpadISO7816(padded)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want := plainTxt; want != got {
+			t.Fatalf("expect %q, %q be equals", want, got)
+		}
+	}
+	if _, err := unpadISO7816("Idir Moore\x00"); !errors.Is(err, ErrInvalidPadding) {
+		t.Fatalf("expect err be %v, got %v", ErrInvalidPadding, err)
+	}
+}
+
+func TestProtector_Padding(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-p4dd3d"
+
+	engine := memory.NewKeyEngine()
+
+	p := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Padding = Padding{BlockSize: 32}
+	})
+
+	short := Note{UserID: "kal5430", Title: "Hi", Body: "Short note", Nickname: "Id"}
+	long := Note{UserID: "kal5430", Title: "A much longer title\x00", Body: "A longer note that exceeds the first bucket", Nickname: "Idir"}
+	oshort, olong := short, long
+
+	if err := p.Encrypt(ctx, &short, &long); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	cipherLen := func(val string) int {
+		t.Helper()
+		_, _, _, cipherText, err := parseWireFormatWithParams(val)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		return len(cipherText)
+	}
+
+	// assert lengths are hidden within the same block or bucket, and revealed otherwise
+	if want, got := cipherLen(short.Title), cipherLen(long.Title); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if cipherLen(short.Body) == cipherLen(long.Body) {
+		t.Fatal("expect bodies of different buckets have different lengths")
+	}
+	if cipherLen(short.Nickname) == cipherLen(long.Nickname) {
+		t.Fatal("expect unpadded fields have different lengths")
+	}
+
+	for val, want := range map[string]string{
+		short.Title:    paddingISO7816,
+		short.Body:     paddingISO7816,
+		short.Nickname: "",
+	} {
+		_, _, params, _, err := parseWireFormatWithParams(val)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if got := params[paramPadding]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	eshort := short
+
+	if err := p.Decrypt(ctx, &short, &long); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oshort, short; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := olong, long; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert padding is removed by Reencrypt once disabled, while padded values remain decryptable
+	np := NewProtector(nspace, engine)
+	short = eshort
+	if err := np.(Reencrypter).Reencrypt(ctx, &short); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err := parseWireFormatWithParams(short.Title)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if _, ok := params[paramPadding]; ok {
+		t.Fatalf("expect padding be removed, got %v", params)
+	}
+	if want, got := eshort.Body, short.Body; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if err := np.Decrypt(ctx, &short); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oshort, short; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
@@ -2,7 +2,11 @@ package privacytest
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -151,6 +155,11 @@ func RunKeyEngineTest(t *testing.T, ctx context.Context, eng core.KeyEngine, opt
 		// Honore the grace Period which supposed to be short
 		time.Sleep(cfg.GracePeriod)
 
+		// Disable another key, the third in the list, which is still within the grace period
+		if want, err := nilErr, eng.DisableKey(ctx, namespace, keyIDs[2]); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+
 		if cfg.AutoDeleteUnusedHook != nil {
 			cfg.AutoDeleteUnusedHook()
 		} else {
@@ -168,5 +177,816 @@ func RunKeyEngineTest(t *testing.T, ctx context.Context, eng core.KeyEngine, opt
 		if want, err := core.ErrKeyNotFound, eng.ReEnableKey(ctx, namespace, keyIDs[1]); !errors.Is(err, want) {
 			t.Fatalf("expect err be %v, got: %v", want, err)
 		}
+		keys, err = eng.GetKeys(ctx, namespace, keyIDs[1:2])
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := empty, keys.KeyIDs(); !keysEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// Assert the key disabled within the grace period is still recoverable
+		if want, err := nilErr, eng.ReEnableKey(ctx, namespace, keyIDs[2]); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+		keys, err = eng.GetKeys(ctx, namespace, keyIDs[2:])
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := keyIDs[2:], keys.KeyIDs(); !keysEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+func RunVersionedKeyEngineTest(t *testing.T, ctx context.Context, eng core.VersionedKeyEngine, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-v3r51n"
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+	}
+
+	nilErr := error(nil)
+
+	// Test new keys start at version 1
+	latest, err := eng.GetOrCreateLatestKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	keys, err := eng.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, keyID := range keyIDs {
+		if want, got := (core.VersionedKey{Version: 1, Key: keys[keyID]}), latest[keyID]; want != got || got.Key == "" {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// Test rotate key
+	rotated, err := eng.RotateKeys(ctx, namespace, keyIDs[:1], nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 2, rotated[keyIDs[0]].Version; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if rotated[keyIDs[0]].Key == "" || rotated[keyIDs[0]].Key == keys[keyIDs[0]] {
+		t.Fatal("expect rotated key be a new one")
+	}
+
+	// Assert the latest version is returned, while the first one is still returned by GetKeys
+	latest, err = eng.GetOrCreateLatestKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := rotated[keyIDs[0]], latest[keyIDs[0]]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 1, latest[keyIDs[1]].Version; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	got, err := eng.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := keys[keyIDs[0]], got[keyIDs[0]]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// Test get all key versions
+	versions, err := eng.GetKeyVersions(ctx, namespace, keyIDs)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := (map[int]core.Key{1: keys[keyIDs[0]], 2: rotated[keyIDs[0]].Key}), versions[keyIDs[0]]; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := (map[int]core.Key{1: keys[keyIDs[1]]}), versions[keyIDs[1]]; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// Test rotate an unknown key
+	if _, err := eng.RotateKeys(ctx, namespace, []string{randomID()}, nil); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Fatalf("expect err be %v, got: %v", core.ErrKeyNotFound, err)
+	}
+
+	// Test disable key applies to all versions
+	if want, err := nilErr, eng.DisableKey(ctx, namespace, keyIDs[0]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	versions, err = eng.GetKeyVersions(ctx, namespace, keyIDs[:1])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 0, len(versions); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, err := eng.RotateKeys(ctx, namespace, keyIDs[:1], nil); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Fatalf("expect err be %v, got: %v", core.ErrKeyNotFound, err)
+	}
+
+	// Test renable key restores all versions
+	if want, err := nilErr, eng.ReEnableKey(ctx, namespace, keyIDs[0]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	versions, err = eng.GetKeyVersions(ctx, namespace, keyIDs[:1])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 2, len(versions[keyIDs[0]]); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// Test delete key shreds all versions
+	if want, err := nilErr, eng.DeleteKey(ctx, namespace, keyIDs[0]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	versions, err = eng.GetKeyVersions(ctx, namespace, keyIDs[:1])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 0, len(versions); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	latest, err = eng.GetOrCreateLatestKeys(ctx, namespace, keyIDs[:1], nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 0, len(latest); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func RunKeyInspectorTest(t *testing.T, ctx context.Context, eng core.KeyInspector, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-1n5p3c"
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+		randomID(),
+		randomID(),
+		randomID(),
+	}
+	slices.Sort(keyIDs)
+
+	start := time.Now()
+
+	if _, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.DisableKey(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.DisableKey(ctx, namespace, keyIDs[2]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.DeleteKey(ctx, namespace, keyIDs[3]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// Test describe keys
+	unknown := randomID()
+	metadata, err := eng.DescribeKeys(ctx, namespace, append([]string{unknown}, keyIDs...))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if _, ok := metadata[unknown]; ok {
+		t.Fatalf("expect unknown key %s not be described", unknown)
+	}
+	if want, got := len(keyIDs), len(metadata); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	active := metadata[keyIDs[0]]
+	if want, got := core.KeyState(core.StateActive), active.State; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := 1, active.Version; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "AES-256", active.Algorithm; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if active.CreatedAt.Before(start.Add(-time.Second)) || !active.DisabledAt.IsZero() || !active.PurgeAt.IsZero() {
+		t.Fatalf("expect valid active key timestamps, got: %+v", active)
+	}
+
+	disabled := metadata[keyIDs[1]]
+	if want, got := core.KeyState(core.StateDisabled), disabled.State; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if disabled.DisabledAt.IsZero() || disabled.PurgeAt.Before(disabled.DisabledAt) {
+		t.Fatalf("expect valid disabled key timestamps, got: %+v", disabled)
+	}
+	if cfg.GracePeriod != 0 {
+		if want, got := disabled.DisabledAt.Add(cfg.GracePeriod), disabled.PurgeAt; !want.Equal(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	deleted := metadata[keyIDs[3]]
+	if want, got := core.KeyState(core.StateDeleted), deleted.State; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if deleted.DeletedAt.IsZero() || deleted.Algorithm != "" {
+		t.Fatalf("expect valid deleted key metadata, got: %+v", deleted)
+	}
+
+	// Test list keys page by page
+	listed := []string{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > len(keyIDs) {
+			t.Fatal("expect list keys pagination to end")
+		}
+		page, next, err := eng.ListKeys(ctx, namespace, core.KeyFilter{Limit: 2}, cursor)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if len(page) > 2 {
+			t.Fatalf("expect page size be less or equal to 2, got: %d", len(page))
+		}
+		for _, m := range page {
+			listed = append(listed, m.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if want, got := keyIDs, listed; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// Test list keys by state, the next cursor is empty even if non-matching keys remain
+	page, next, err := eng.ListKeys(ctx, namespace, core.KeyFilter{States: []core.KeyState{core.StateDisabled}, Limit: 2}, "")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if next != "" {
+		t.Fatalf("expect next cursor be empty, got: %s", next)
+	}
+	listed = []string{}
+	for _, m := range page {
+		listed = append(listed, m.ID)
+	}
+	if want, got := keyIDs[1:3], listed; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// Test list keys with an invalid cursor
+	if _, _, err := eng.ListKeys(ctx, namespace, core.KeyFilter{}, "%invalid%"); !errors.Is(err, core.ErrInvalidCursor) {
+		t.Fatalf("expect err be %v, got: %v", core.ErrInvalidCursor, err)
+	}
+}
+
+// RunNamespaceKeyEngineTest runs a common test suite against the given core.NamespaceKeyEngine,
+// mainly to assert the namespace-level operations don't affect other namespaces,
+// and don't reenable the keys of individually disabled subjects.
+func RunNamespaceKeyEngineTest(t *testing.T, ctx context.Context, eng core.NamespaceKeyEngine, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-" + randomID()
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+	otherNamespace := namespace + "-other"
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+		randomID(),
+		randomID(),
+	}
+
+	keys, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	otherKeys, err := eng.GetOrCreateKeys(ctx, otherNamespace, keyIDs[:1], nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	assertKeys := func(namespace string, want core.KeyMap) {
+		t.Helper()
+
+		got, err := eng.GetKeys(ctx, namespace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if len(want) != len(got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		for keyID, k := range want {
+			if got[keyID] != k {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+	}
+
+	// the subject of the first key is individually forgotten
+	if err := eng.DisableKey(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// Test disable namespace
+	if err := eng.DisableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert idempotency
+	if err := eng.DisableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertKeys(namespace, core.NewKeyMap())
+	assertKeys(otherNamespace, otherKeys)
+
+	// assert keys of a disabled namespace are not recreated
+	if got, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	} else if len(got) != 0 {
+		t.Fatalf("expect keys %v be empty", got)
+	}
+
+	// Test reenable namespace
+	if err := eng.ReEnableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert the individually forgotten subject is not reenabled
+	assertKeys(namespace, core.KeyMap{
+		keyIDs[1]: keys[keyIDs[1]],
+		keyIDs[2]: keys[keyIDs[2]],
+		keyIDs[3]: keys[keyIDs[3]],
+	})
+
+	// a subject is individually forgotten while its namespace is disabled
+	if err := eng.DisableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.DisableKey(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.ReEnableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertKeys(namespace, core.KeyMap{
+		keyIDs[2]: keys[keyIDs[2]],
+		keyIDs[3]: keys[keyIDs[3]],
+	})
+
+	// Test delete namespace
+	if err := eng.DeleteNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert idempotency
+	if err := eng.DeleteNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertKeys(namespace, core.NewKeyMap())
+	assertKeys(otherNamespace, otherKeys)
+
+	// assert deleted keys can't be recovered nor recreated
+	if err := eng.ReEnableNamespace(ctx, namespace); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, keyID := range keyIDs {
+		if err := eng.ReEnableKey(ctx, namespace, keyID); !errors.Is(err, core.ErrKeyNotFound) {
+			t.Fatalf("expect err be %v, got: %v", core.ErrKeyNotFound, err)
+		}
+	}
+	if got, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	} else if len(got) != 0 {
+		t.Fatalf("expect keys %v be empty", got)
+	}
+	assertKeys(namespace, core.NewKeyMap())
+}
+
+// RunExpiringKeyEngineTest runs a common test suite against the given core.ExpiringKeyEngine.
+func RunExpiringKeyEngineTest(t *testing.T, ctx context.Context, eng core.ExpiringKeyEngine, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-" + randomID()
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+		randomID(),
+		randomID(),
+	}
+
+	if _, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	now := time.Now()
+
+	assertExpired := func(at time.Time, retention time.Duration, want []string) {
+		t.Helper()
+
+		got, err := eng.ExpiredKeys(ctx, namespace, at, retention)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		want = slices.Clone(want)
+		slices.Sort(want)
+		if !slices.Equal(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		for _, keyID := range append(slices.Clone(keyIDs), randomID()) {
+			ok, err := eng.KeyExpired(ctx, namespace, keyID, at, retention)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if wantOk := slices.Contains(want, keyID); wantOk != ok {
+				t.Fatalf("expect %v, %v be equals", wantOk, ok)
+			}
+		}
+	}
+
+	assertExpired(now, 0, []string{})
+
+	// Test schedule key expiry
+	if err := eng.ScheduleKeyExpiry(ctx, namespace, keyIDs[0], now.Add(-time.Second)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.ScheduleKeyExpiry(ctx, namespace, keyIDs[1], now.Add(time.Hour)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.ScheduleKeyExpiry(ctx, namespace, keyIDs[2], now.Add(-time.Second)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert a zero deadline cancels the scheduled one
+	if err := eng.ScheduleKeyExpiry(ctx, namespace, keyIDs[2], time.Time{}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	assertExpired(now, 0, keyIDs[:1])
+	assertExpired(now.Add(2*time.Hour), 0, keyIDs[:2])
+
+	// assert keys without a deadline expire after the retention,
+	// while a scheduled deadline overrides the retention.
+	assertExpired(now.Add(time.Minute), time.Minute, []string{keyIDs[0], keyIDs[2], keyIDs[3]})
+	assertExpired(now.Add(time.Minute), 2*time.Minute, keyIDs[:1])
+
+	// assert disabled keys are not reported as expired
+	if err := eng.DisableKey(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertExpired(now, 0, []string{})
+
+	// assert the deadline of an unknown or deleted key can't be scheduled
+	if want, err := core.ErrKeyNotFound, eng.ScheduleKeyExpiry(ctx, namespace, randomID(), now); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	if err := eng.DeleteKey(ctx, namespace, keyIDs[3]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, err := core.ErrKeyNotFound, eng.ScheduleKeyExpiry(ctx, namespace, keyIDs[3], now); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	assertExpired(now.Add(time.Minute), time.Minute, keyIDs[2:3])
+}
+
+// RunHoldKeyEngineTest runs a common test suite against the given core.HoldKeyEngine.
+//
+// It also asserts namespace-level operations and expiry skip held keys
+// if the engine implements core.NamespaceKeyEngine and core.ExpiringKeyEngine.
+func RunHoldKeyEngineTest(t *testing.T, ctx context.Context, eng core.HoldKeyEngine, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-" + randomID()
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+		randomID(),
+	}
+
+	keys, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// Test place hold
+	if err := eng.PlaceHold(ctx, namespace, keyIDs[0], "litigation #1"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert a held key can't be disabled nor deleted
+	if want, err := core.ErrKeyOnHold, eng.DisableKey(ctx, namespace, keyIDs[0]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	if want, err := core.ErrKeyOnHold, eng.DeleteKey(ctx, namespace, keyIDs[0]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	if got, err := eng.GetKeys(ctx, namespace, keyIDs[:1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	} else if want := keys[keyIDs[0]]; want != got[keyIDs[0]] {
+		t.Fatalf("expect %v, %v be equals", want, got[keyIDs[0]])
+	}
+
+	// assert placing a hold again only updates the reason
+	if err := eng.PlaceHold(ctx, namespace, keyIDs[0], "litigation #2"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// Test release hold
+	hold, err := eng.ReleaseHold(ctx, namespace, keyIDs[0])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "litigation #2", hold.Reason; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if hold.PlacedAt.IsZero() {
+		t.Fatal("expect hold placed at be set")
+	}
+	if !hold.ForgetRequested {
+		t.Fatal("expect forget requested while on hold be recorded")
+	}
+
+	// assert idempotency
+	if hold, err := eng.ReleaseHold(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	} else if want, got := (core.KeyHold{}), hold; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := eng.DisableKey(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert an already disabled key can be held, and reenabling it cancels the forget request
+	if err := eng.DisableKey(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.PlaceHold(ctx, namespace, keyIDs[1], "litigation #3"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, err := core.ErrKeyOnHold, eng.DeleteKey(ctx, namespace, keyIDs[1]); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+
+	// assert a held key is not deleted once the grace period is exceeded
+	if cfg.GracePeriod > 0 {
+		time.Sleep(cfg.GracePeriod)
+
+		if err := eng.DeleteUnusedKeys(ctx, namespace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	}
+	if err := eng.ReEnableKey(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if hold, err := eng.ReleaseHold(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	} else if hold.ForgetRequested {
+		t.Fatal("expect forget request be canceled")
+	}
+
+	if err := eng.PlaceHold(ctx, namespace, keyIDs[2], "litigation #4"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	if eke, ok := eng.(core.ExpiringKeyEngine); ok {
+		if err := eke.ScheduleKeyExpiry(ctx, namespace, keyIDs[2], time.Now().Add(-time.Second)); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if expired, err := eke.ExpiredKeys(ctx, namespace, time.Now(), 0); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		} else if len(expired) != 0 {
+			t.Fatalf("expect held key not be expired, got %v", expired)
+		}
+	}
+
+	if nke, ok := eng.(core.NamespaceKeyEngine); ok {
+		if err := nke.DeleteNamespace(ctx, namespace); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		got, err := eng.GetKeys(ctx, namespace, keyIDs)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want := (core.KeyMap{keyIDs[2]: keys[keyIDs[2]]}); !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert a hold can't be placed on a deleted key
+		if want, err := core.ErrKeyNotFound, eng.PlaceHold(ctx, namespace, keyIDs[0], "litigation #5"); !errors.Is(err, want) {
+			t.Fatalf("expect err be %v, got: %v", want, err)
+		}
+	}
+
+	if want, err := core.ErrKeyNotFound, eng.PlaceHold(ctx, namespace, randomID(), "litigation #6"); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got: %v", want, err)
+	}
+	if _, err := eng.ReleaseHold(ctx, namespace, randomID()); !errors.Is(err, core.ErrKeyNotFound) {
+		t.Fatalf("expect err be %v, got: %v", core.ErrKeyNotFound, err)
+	}
+}
+
+func RunPublicKeyEngineTest(t *testing.T, ctx context.Context, eng core.PublicKeyEngine, opts ...func(*KeyEngineTestConfig)) {
+	t.Helper()
+
+	cfg := &KeyEngineTestConfig{}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+
+	namespace := "tenant-" + randomID()
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+
+	keyIDs := []string{
+		randomID(),
+		randomID(),
+		randomID(),
+		randomID(),
+	}
+
+	publicKeyOf := func(key core.Key) core.Key {
+		sum := sha256.Sum256([]byte(key))
+		return core.Key(hex.EncodeToString(sum[:]))
+	}
+
+	calls := 0
+	keyPairGen := func(ctx context.Context, namespace, keyID string) (core.Key, core.Key, error) {
+		calls++
+		key := core.Key(randomID() + randomID())
+		return key, publicKeyOf(key), nil
+	}
+
+	assertPublicKeys := func(got core.KeyMap, want []string) {
+		t.Helper()
+
+		keys, err := eng.GetKeys(ctx, namespace, want)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if len(got) != len(want) || len(keys) != len(want) {
+			t.Fatalf("expect %d public keys, got: %d, %d", len(want), len(got), len(keys))
+		}
+		for _, keyID := range want {
+			if want := publicKeyOf(keys[keyID]); want != got[keyID] {
+				t.Fatalf("expect %v, %v be equals", want, got[keyID])
+			}
+		}
+	}
+
+	// assert key pairs are created, while existing keys without a public key are omitted
+	if _, err := eng.GetOrCreateKeys(ctx, namespace, keyIDs[3:], nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	pubs, err := eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs, keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertPublicKeys(pubs, keyIDs[:3])
+	if want, got := 3, calls; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert public keys are only put for active keys without one
+	keys, err := eng.GetKeys(ctx, namespace, keyIDs[3:])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := eng.PutPublicKeys(ctx, namespace, core.KeyMap{
+		keyIDs[3]:  publicKeyOf(keys[keyIDs[3]]),
+		keyIDs[0]:  core.Key("overwritten public key"),
+		randomID(): core.Key("unknown public key"),
+	}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	pubs, err = eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs, keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	assertPublicKeys(pubs, keyIDs)
+
+	// assert public keys are persisted
+	pubs2, err := eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs, keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !reflect.DeepEqual(pubs, pubs2) {
+		t.Fatalf("expect %v, %v be equals", pubs, pubs2)
+	}
+	if want, got := 3, calls; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert public keys of disabled keys are not returned, nor recreated
+	if err := eng.DisableKey(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	got, err := eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs, keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if _, ok := got[keyIDs[0]]; ok || len(got) != len(keyIDs)-1 {
+		t.Fatalf("expect public key of disabled key not be returned, got: %v", got)
+	}
+
+	if err := eng.ReEnableKey(ctx, namespace, keyIDs[0]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	got, err = eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs[:1], keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want := (core.KeyMap{keyIDs[0]: pubs[keyIDs[0]]}); !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert public keys of deleted keys are not returned, nor recreated
+	if err := eng.DeleteKey(ctx, namespace, keyIDs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	got, err = eng.GetOrCreatePublicKeys(ctx, namespace, keyIDs[1:2], keyPairGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expect public key of deleted key not be returned, got: %v", got)
+	}
+	keys, err = eng.GetKeys(ctx, namespace, keyIDs[1:2])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Fatalf("expect deleted key not be recreated, got: %v", keys)
 	}
 }
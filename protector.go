@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/ln80/privacy-engine/aes"
@@ -15,26 +16,42 @@ import (
 
 // Errors returned by Protector service
 var (
-	ErrEncryptDecryptFailure = newErr("failed to encrypt/decrypt")
-	ErrForgetSubjectFailure  = newErr("failed to forget subject")
-	ErrRecoverSubjectFailure = newErr("failed to recover subject")
-	ErrClearCacheFailure     = newErr("failed to clear cache")
-	ErrCannotRecoverSubject  = newErr("cannot recover subject")
-	ErrSubjectForgotten      = newErr("subject is forgotten")
+	ErrEncryptDecryptFailure   = newErr("failed to encrypt/decrypt")
+	ErrForgetSubjectFailure    = newErr("failed to forget subject")
+	ErrRecoverSubjectFailure   = newErr("failed to recover subject")
+	ErrRotateKeyFailure        = newErr("failed to rotate subject key")
+	ErrForgetNamespaceFailure  = newErr("failed to forget namespace")
+	ErrRecoverNamespaceFailure = newErr("failed to recover namespace")
+	ErrClearCacheFailure       = newErr("failed to clear cache")
+	ErrCannotRecoverSubject    = newErr("cannot recover subject")
+	ErrSubjectForgotten        = newErr("subject is forgotten")
+	ErrSubjectOnHold           = newErr("subject is on hold")
+	ErrHoldSubjectFailure      = newErr("failed to place/release subject hold")
+	ErrPublishPublicKeyFailure = newErr("failed to publish subject public key")
 )
 
 // Protector presents the service's interface that encrypts, decrypts,
 // and crypto-erases subjects' Personal data.
+//
+// Protector services created using NewProtector, and those returned by Factory, also implement
+// optional interfaces, e.g., Reencrypter, which are retrieved using type assertions.
 type Protector interface {
 
 	// Encrypt encrypts Personal data fields of the given structs pointers.
-	// It does its best to ensure atomicity in case of multiple structs pointers.
+	// It's atomic in case of multiple structs pointers: if it fails, all fields, including blind indexes,
+	// are restored to their original values, unless the configured ErrorPolicy allows partial results.
 	// It ensures idempotency and only encrypts fields once.
+	//
+	// Fields failures are returned as FieldErrors error, which can be retrieved using errors.As.
+	//
+	// It also fills the blind index of fields tagged with the 'index' option, see BlindIndexer.
 	Encrypt(ctx context.Context, structPts ...any) error
 
 	// Decrypt decrypts Personal data fields of the given structs pointers.
-	// It does its best to ensure in case of multiple structs pointers.
-	// It ensures idempotency and only decrypts fields once.
+	// It's atomic in case of multiple structs pointers: if it fails, all fields are restored to their original values,
+	// unless the configured ErrorPolicy allows partial results. It ensures idempotency and only decrypts fields once.
+	//
+	// Fields failures are returned as FieldErrors error, which can be retrieved using errors.As.
 	//
 	// It replaces the field value with a replacement message, defined in the tag,
 	// if the subject is forgotten. Otherwise, the field will be kept empty.
@@ -42,6 +59,8 @@ type Protector interface {
 
 	// Forget removes the associated encryption materials of the given subject,
 	// and crypto-erases its Personal data.
+	//
+	// It returns ErrSubjectOnHold error if the subject is held, see HoldManager.
 	Forget(ctx context.Context, subID string) error
 
 	// Recover allows to recover encryption materials of the given subject.
@@ -49,12 +68,141 @@ type Protector interface {
 	// It fails if the grace period was exceeded, and encryption materials were hard deleted.
 	Recover(ctx context.Context, subID string) error
 
+	// RotateKey creates a new version of the given subject's encryption key,
+	// which is used to encrypt Personal data from now on.
+	//
+	// Data encrypted using previous versions remains decryptable, and is lazily re-encrypted
+	// using the latest version the next time it's encrypted after a decryption.
+	// Note that other instances may keep using the previous version until their cache expires.
+	//
+	// It requires a Key engine that implements core.VersionedKeyEngine.
+	RotateKey(ctx context.Context, subID string) error
+
 	// Clear clears encryption materials' cache based on cache-related configuration.
 	Clear(ctx context.Context, force bool) error
 
 	core.TokenEngine
 }
 
+// Reencrypter is implemented by Protector services that re-encrypt Personal data.
+type Reencrypter interface {
+
+	// Reencrypt re-encrypts Personal data fields of the given structs pointers using the latest
+	// version of subjects' keys and the current Encryptor, without exposing plain text values.
+	//
+	// Fields that are not encrypted, or already encrypted using the latest key version
+	// and the current Encryptor are left untouched.
+	// Fields of forgotten subjects are left untouched too, and reported
+	// in the returned error as joined ErrSubjectForgotten errors.
+	//
+	// Similarly to Protector.Encrypt, if it fails for another reason, all fields are restored to their original values.
+	Reencrypt(ctx context.Context, structPtrs ...any) error
+}
+
+// NamespaceManager is implemented by Protector services that forget their whole namespace at once.
+type NamespaceManager interface {
+
+	// ForgetNamespace removes the encryption materials of all subjects of the Protector's namespace,
+	// and crypto-erases all its Personal data, e.g., when a tenant is offboarded.
+	//
+	// Encryption materials are disabled in graceful mode, otherwise they are immediately deleted.
+	// Note that other instances may keep using cached materials until their cache expires.
+	//
+	// It requires a Key engine that implements core.NamespaceKeyEngine.
+	ForgetNamespace(ctx context.Context) error
+
+	// RecoverNamespace recovers the encryption materials disabled by ForgetNamespace.
+	// Subjects that were individually forgotten remain forgotten.
+	//
+	// Encryption materials that exceeded the grace period and were hard deleted can't be recovered.
+	RecoverNamespace(ctx context.Context) error
+}
+
+// RetentionManager is implemented by Protector services that forget subjects once their retention has elapsed.
+type RetentionManager interface {
+
+	// ForgetAt schedules forgetting the given subject at the given time, e.g., to fulfill a data retention rule.
+	// It overrides the configured Retention for the subject, and a zero time cancels the scheduled one.
+	//
+	// Subjects are forgotten by ForgetExpired once their time has passed, which is regularly called by Factory.Monitor.
+	// It requires a Key engine that implements core.ExpiringKeyEngine.
+	ForgetAt(ctx context.Context, subID string, at time.Time) error
+
+	// ForgetExpired forgets the subjects whose scheduled time has passed, or whose encryption materials
+	// are older than the configured Retention, and returns their IDs.
+	//
+	// Note that a recovered subject is forgotten again unless its schedule is updated using ForgetAt.
+	// It requires a Key engine that implements core.ExpiringKeyEngine.
+	ForgetExpired(ctx context.Context) (forgotten []string, err error)
+}
+
+// HoldManager is implemented by Protector services that hold subjects, see core.HoldKeyEngine.
+type HoldManager interface {
+
+	// PlaceHold places a hold with the given reason on the given subject, e.g., a legal hold due to a litigation.
+	// A held subject can't be forgotten, and its encryption materials are never deleted until the hold is released.
+	//
+	// It requires a Key engine that implements core.HoldKeyEngine.
+	PlaceHold(ctx context.Context, subID, reason string) error
+
+	// ReleaseHold releases the hold of the given subject. The subject is then forgotten if it was
+	// requested to be forgotten while on hold, or if its scheduled time or retention has passed.
+	//
+	// It requires a Key engine that implements core.HoldKeyEngine.
+	ReleaseHold(ctx context.Context, subID string) error
+}
+
+// tagOptionDeterministic is the 'pii' tag option that enables deterministic encryption of a field.
+const tagOptionDeterministic = "deterministic"
+
+// Binding defines what cipher texts are bound to, in addition to the namespace.
+type Binding int
+
+const (
+	// BindNone doesn't bind cipher texts, they can be moved between subjects and fields of the same namespace.
+	BindNone Binding = iota
+
+	// BindSubject binds cipher texts to their subject ID.
+	BindSubject
+
+	// BindField binds cipher texts to their subject ID and field path, e.g., "Addresses.Street".
+	// Slice indexes and map keys are not part of the path.
+	BindField
+)
+
+// KeyDerivation defines whether fields are encrypted using keys derived from their subject key.
+type KeyDerivation int
+
+const (
+	// DeriveNone encrypts all fields of a subject using its key.
+	DeriveNone KeyDerivation = iota
+
+	// DeriveField encrypts each field using a key derived from the subject key and the field path, e.g., "Addresses.Street".
+	// Slice indexes and map keys are not part of the path.
+	DeriveField
+
+	// DeriveCategory encrypts each field using a key derived from the subject key and the field's data category,
+	// i.e., the 'kind' tag option, e.g., `pii:"data,kind=email"`. Fields without category share the same derived key.
+	DeriveCategory
+)
+
+// ErrorPolicy defines how Encrypt and Decrypt handle fields that fail to be encrypted or decrypted.
+// Failures are returned as FieldErrors, one per field.
+type ErrorPolicy int
+
+const (
+	// FailFast stops at the first failed field, and restores all fields to their original values.
+	FailFast ErrorPolicy = iota
+
+	// BestEffort processes all fields, and leaves the failed ones untouched, while the others are
+	// encrypted or decrypted. All failures are returned.
+	BestEffort
+
+	// SkipCorrupt leaves fields with corrupt values untouched, e.g., tampered cipher texts,
+	// and processes the others. Corrupt fields are returned, while any other failure fails fast.
+	SkipCorrupt
+)
+
 // ProtectorConfig presents the configuration of Protector service
 type ProtectorConfig struct {
 
@@ -66,6 +214,50 @@ type ProtectorConfig struct {
 	// It allows using a specific encryption algorithm.
 	Encryptor core.Encryptor
 
+	// PreviousEncryptors are used to decrypt data encrypted before switching to the current Encryptor.
+	// They are tried in order if the current Encryptor fails to decrypt a value.
+	PreviousEncryptors []core.Encryptor
+
+	// DeterministicEncryptor presents an implementation of core.Encryptor used to encrypt fields
+	// tagged with the 'deterministic' option, e.g., `pii:"data,deterministic"`, which allows equality searches.
+	// It must accept the keys generated by the current Encryptor, as subjects have a single key.
+	DeterministicEncryptor core.Encryptor
+
+	// PublicKeyEncryptor presents an implementation of core.PublicKeyEncryptor used by encrypt-only Protectors
+	// to encrypt values using subjects' public keys, see NewEncryptOnlyProtector.
+	// Protectors use it to decrypt these values, therefore it must accept the keys generated by the current Encryptor.
+	PublicKeyEncryptor core.Encryptor
+
+	// Encryptors, if set, overrides Encryptor: new values are encrypted using the registry's default Encryptor,
+	// and values are decrypted using the Encryptor registered under their recorded algorithm ID.
+	// PreviousEncryptors are still used for values encrypted by unregistered Encryptors.
+	Encryptors EncryptorRegistry
+
+	// Binding binds cipher texts to their subject and optionally their field, using the Encryptor's additional data.
+	// It requires an Encryptor that implements core.AADEncryptor.
+	//
+	// The binding is recorded along with the cipher text, therefore values encrypted using another binding,
+	// e.g., before enabling it, remain decryptable and are bound the next time they are re-encrypted.
+	Binding Binding
+
+	// KeyDerivation encrypts fields using keys derived from subjects' keys using HKDF-SHA256, which limits
+	// the exposure of a single derived key. Derived keys are crypto-erased along with the subject key.
+	//
+	// The derivation label is recorded along with the cipher text, therefore values encrypted using another derivation
+	// remain decryptable and are re-derived the next time they are re-encrypted.
+	// Note that encrypt-only Protectors don't derive keys, as they only hold public keys.
+	KeyDerivation KeyDerivation
+
+	// Padding pads plain texts before encryption to hide their length, see Padding.
+	// It's overridden per field by the 'pad' tag option, e.g., `pii:"data,pad=16|64|256"`, and disabled by `pad=none`.
+	//
+	// Padded values are marked in the wire format, therefore values encrypted using another padding remain decryptable.
+	Padding Padding
+
+	// ErrorPolicy defines how Encrypt and Decrypt handle failed fields, it defaults to FailFast.
+	// Partial results of the other policies are returned along with FieldErrors error.
+	ErrorPolicy ErrorPolicy
+
 	// CacheEnabled used to enable/disable cache.
 	CacheEnabled bool
 
@@ -76,6 +268,22 @@ type ProtectorConfig struct {
 	// Therefore recovery may succeed. Otherwise, encryption materials are immediately deleted.
 	GracefulMode bool
 
+	// Retention defines how long subjects' encryption materials are kept after their creation
+	// before being forgotten by RetentionManager.ForgetExpired, unless another time is scheduled using ForgetAt.
+	// Zero disables retention-based forgetting.
+	Retention time.Duration
+
+	// BlindIndexKey is the secret from which the namespace's blind index HMAC key is derived.
+	BlindIndexKey []byte
+
+	// BlindIndexLength is the number of bytes blind indexes are truncated to, it defaults to 16 bytes.
+	// Shorter indexes leak less information about the values, at the cost of more false positives when searching.
+	BlindIndexLength int
+
+	// Normalizers are custom blind index normalizers referenced by name in the 'normalize' tag option,
+	// in addition to the builtin ones: "trim", "lower", and "e164".
+	Normalizers map[string]Normalizer
+
 	// TokenEngine is an implementation of core.TokenEngine
 	TokenEngine core.TokenEngine
 }
@@ -83,10 +291,18 @@ type ProtectorConfig struct {
 type protector struct {
 	namespace string
 
+	// encryptOnly reports whether the protector only holds subjects' public keys.
+	encryptOnly bool
+
 	*ProtectorConfig
 }
 
 var _ Protector = &protector{}
+var _ Reencrypter = &protector{}
+var _ NamespaceManager = &protector{}
+var _ RetentionManager = &protector{}
+var _ HoldManager = &protector{}
+var _ EncryptOnlyProtector = &protector{}
 
 // NewProtector returns a Protector service instance.
 // It requires a Key engine and accepts options to overwrite the default configuration.
@@ -94,8 +310,13 @@ var _ Protector = &protector{}
 // It panics if the given engine is nil.
 // It uses a default namespace if the given namespace is empty.
 //
-// By default, Cache and Graceful mode options are enabled and 'AES 256 GCM' Encryptor is used.
+// By default, Cache and Graceful mode options are enabled and 'AES 256 GCM' Encryptor is used,
+// while 'AES SIV' Encryptor is used for deterministic fields.
 func NewProtector(namespace string, engine core.KeyEngine, opts ...func(*ProtectorConfig)) Protector {
+	return newProtector(namespace, engine, opts...)
+}
+
+func newProtector(namespace string, engine core.KeyEngine, opts ...func(*ProtectorConfig)) *protector {
 	if namespace == "" {
 		namespace = "default"
 	}
@@ -103,10 +324,12 @@ func NewProtector(namespace string, engine core.KeyEngine, opts ...func(*Protect
 	p := &protector{
 		namespace: namespace,
 		ProtectorConfig: &ProtectorConfig{
-			Encryptor:    aes.New256GCMEncryptor(),
-			KeyEngine:    engine,
-			CacheEnabled: true,
-			GracefulMode: true,
+			Encryptor:              aes.New256GCMEncryptor(),
+			DeterministicEncryptor: aes.NewSIVEncryptor(),
+			PublicKeyEncryptor:     aes.NewX25519Encryptor(),
+			KeyEngine:              engine,
+			CacheEnabled:           true,
+			GracefulMode:           true,
 		},
 	}
 
@@ -121,6 +344,22 @@ func NewProtector(namespace string, engine core.KeyEngine, opts ...func(*Protect
 		panic("invalid Key Engine service, nil value found")
 	}
 
+	if p.DeterministicEncryptor == nil {
+		panic("invalid deterministic Encryptor service, nil value found")
+	}
+
+	if _, ok := p.PublicKeyEncryptor.(core.PublicKeyEncryptor); !ok {
+		panic("invalid public key Encryptor service, public keys not supported")
+	}
+
+	if p.Binding != BindNone {
+		for _, enc := range []core.Encryptor{p.current(), p.DeterministicEncryptor, p.PublicKeyEncryptor} {
+			if _, ok := enc.(core.AADEncryptor); !ok {
+				panic("invalid Encryptor service, additional data not supported")
+			}
+		}
+	}
+
 	if p.CacheEnabled {
 		if _, ok := p.KeyEngine.(core.KeyEngineCache); !ok {
 			p.KeyEngine = memory.NewCacheWrapper(p.KeyEngine, p.CacheTTL)
@@ -144,10 +383,10 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 		}
 	}()
 
-	structs := make([]sensitive.Struct, 0)
+	structs := make([]sensitiveStruct, 0)
 	subjectIDs := make([]string, 0)
 	for _, strPtr := range structPtrs {
-		piiStruct, err := sensitive.Scan(strPtr, true)
+		piiStruct, err := scanStruct(strPtr, true)
 		if err != nil {
 			return err
 		}
@@ -161,15 +400,38 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 		return nil
 	}
 
+	// changes are rolled back on failure, so that structs are either all encrypted or left untouched,
+	// unless partial results are allowed by the ErrorPolicy, see replaceAll.
+	j := &journal{}
+	defer func() {
+		if err != nil {
+			j.rollback()
+		}
+	}()
+
+	if err = p.index(structs, j); err != nil {
+		return err
+	}
+
 	slices.Sort(subjectIDs)
 	subjectIDs = slices.Compact(subjectIDs)
 
-	keys, err := p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, subjectIDs, p.Encryptor.KeyGen())
+	var keys core.VersionedKeyMap
+	if p.encryptOnly {
+		keys, err = p.getOrCreatePublicKeys(ctx, subjectIDs)
+	} else {
+		keys, err = p.getOrCreateLatestKeys(ctx, subjectIDs)
+	}
 	if err != nil {
 		return err
 	}
 
-	fn := func(fr sensitive.FieldReplace, val string) (newVal string, err error) {
+	return p.replaceAll(structs, p.encryptFunc(keys), j)
+}
+
+// encryptFunc returns the function that encrypts fields using the given subjects' keys.
+func (p *protector) encryptFunc(keys core.VersionedKeyMap) sensitive.ReplaceFunc {
+	return func(fr sensitive.FieldReplace, val string) (newVal string, err error) {
 		key, ok := keys[fr.SubjectID]
 		if !ok {
 			err = ErrSubjectForgotten.withSubject(fr.SubjectID)
@@ -182,21 +444,14 @@ func (p *protector) Encrypt(ctx context.Context, structPtrs ...any) (err error)
 			return
 		}
 
-		encodedVal, err := p.Encryptor.Encrypt(p.namespace, key, val)
-		if err != nil {
+		if _, ok := fr.Options[tagOptionDeterministic]; ok && p.encryptOnly {
+			err = fmt.Errorf("%w: deterministic field '%s' not supported in encrypt-only mode", core.ErrEncryptionFailure, fr.Name)
 			return
 		}
-		newVal = wireFormat(fr.SubjectID, encodedVal)
-		return
-	}
 
-	for idx, s := range structs {
-		if err = s.Replace(fn); err != nil {
-			err = fmt.Errorf("%w at #%d", err, idx)
-			return
-		}
+		newVal, err = p.encryptField(fr, fr.SubjectID, key, val)
+		return
 	}
-	return
 }
 
 func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error) {
@@ -206,9 +461,9 @@ func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error)
 		}
 	}()
 
-	structs := make([]sensitive.Struct, 0)
+	structs := make([]sensitiveStruct, 0)
 	for _, strPtr := range structPtrs {
-		piiStruct, err := sensitive.Scan(strPtr, false)
+		piiStruct, err := scanStruct(strPtr, false)
 		if err != nil {
 			return err
 		}
@@ -239,47 +494,507 @@ func (p *protector) Decrypt(ctx context.Context, structPtrs ...any) (err error)
 	}
 	slices.Sort(subjectIDs)
 	subjectIDs = slices.Compact(subjectIDs)
-	keys, err := p.KeyEngine.GetKeys(ctx, p.namespace, subjectIDs)
+	keys, err := p.getKeyVersions(ctx, subjectIDs)
 	if err != nil {
 		return
 	}
 
-	fn = func(fr sensitive.FieldReplace, val string) (newVal string, err error) {
-		v, subjectID, cipherText, err := parseWireFormat(val)
+	// changes are rolled back on failure, so that structs are either all decrypted or left untouched,
+	// unless partial results are allowed by the ErrorPolicy, see replaceAll.
+	j := &journal{}
+	if err = p.replaceAll(structs, p.decryptFunc(keys), j); err != nil {
+		j.rollback()
+	}
+	return
+}
+
+// decryptFunc returns the function that decrypts fields using the given subjects' key versions.
+// Fields of forgotten subjects are replaced by their 'replace' tag option, and fields that are not
+// wire formatted are left untouched.
+func (p *protector) decryptFunc(keys core.KeyVersionsMap) sensitive.ReplaceFunc {
+	return func(fr sensitive.FieldReplace, val string) (newVal string, err error) {
+		v, subjectID, params, cipherText, err := parseWireFormatWithParams(val)
 		if err != nil {
 			// TBD warning ??
 			newVal = val
 			err = nil
 			return
 		}
-		if v != 1 {
-			err = errors.New("unsupported wire format version")
+		if v != 1 && v != wireFormatParamsVersion {
+			err = fmt.Errorf("%w: unsupported version %d", ErrInvalidWireFormat, v)
 			return
 		}
 
-		key, ok := keys[subjectID]
+		versions, ok := keys[subjectID]
 		if !ok {
 			newVal = fr.Options["replace"]
 			return
 		}
 
-		newVal, err = p.Encryptor.Decrypt(p.namespace, key, cipherText)
+		keyVersion, err := p.keyVersion(params)
+		if err != nil {
+			return
+		}
+		key, ok := versions[keyVersion]
+		if !ok {
+			err = fmt.Errorf("key version %d not found", keyVersion)
+			return
+		}
+
+		newVal, _, err = p.decrypt(fr, key, params, cipherText, subjectID)
 		if err != nil {
 			return "", err
 		}
 		return
 	}
+}
 
+// replaceAll replaces the given structs' fields using the given function according to the ErrorPolicy,
+// and records the changes in the given journal.
+//
+// It returns FieldErrors error in case of failure. The journal is cleared if partial results are kept,
+// so that rolling it back is a no-op, otherwise it's left for the caller to roll back.
+func (p *protector) replaceAll(structs []sensitiveStruct, fn sensitive.ReplaceFunc, j *journal) error {
+	var ferrs FieldErrors
+	for idx, s := range structs {
+		if err := s.replace(p.policyFunc(idx, fn, &ferrs), j); err != nil {
+			var ferr FieldError
+			if errors.As(err, &ferr) {
+				return FieldErrors{ferr}
+			}
+			return err
+		}
+	}
+	if len(ferrs) == 0 {
+		return nil
+	}
+
+	j.commit()
+	return ferrs
+}
+
+// policyFunc wraps the given function of the struct at the given index to apply the ErrorPolicy.
+// Failures allowed by the policy are appended to the given FieldErrors and their fields are left untouched,
+// while the others are returned as FieldError.
+func (p *protector) policyFunc(idx int, fn sensitive.ReplaceFunc, ferrs *FieldErrors) sensitive.ReplaceFunc {
+	return func(fr sensitive.FieldReplace, val string) (string, error) {
+		newVal, err := fn(fr, val)
+		if err == nil {
+			return newVal, nil
+		}
+
+		ferr := FieldError{Index: idx, Path: fr.Name, SubjectID: fr.SubjectID, Err: err}
+		if ferr.SubjectID == "" {
+			_, ferr.SubjectID, _, _ = parseWireFormat(val)
+		}
+		if p.tolerated(err) {
+			*ferrs = append(*ferrs, ferr)
+			return val, nil
+		}
+		return "", ferr
+	}
+}
+
+// tolerated reports whether the given field failure is allowed by the ErrorPolicy, i.e., it doesn't fail fast.
+func (p *protector) tolerated(err error) bool {
+	return p.ErrorPolicy == BestEffort || (p.ErrorPolicy == SkipCorrupt && isCorrupt(err))
+}
+
+// isCorrupt reports whether the given error is due to a corrupt value, e.g., a tampered cipher text.
+func isCorrupt(err error) bool {
+	return errors.Is(err, core.ErrDecryptionFailure) ||
+		errors.Is(err, ErrInvalidWireFormat) ||
+		errors.Is(err, ErrInvalidPadding)
+}
+
+// Reencrypt implements Reencrypter
+func (p *protector) Reencrypt(ctx context.Context, structPtrs ...any) error {
+	forgotten, err := p.reencrypt(ctx, structPtrs...)
+	if err != nil {
+		return ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace)
+	}
+	if len(forgotten) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(forgotten))
+	for _, subjectID := range forgotten {
+		errs = append(errs, ErrSubjectForgotten.withNamespace(p.namespace).withSubject(subjectID))
+	}
+	return errors.Join(errs...)
+}
+
+// reencrypt re-encrypts the given structs' fields and returns the IDs of the forgotten subjects.
+func (p *protector) reencrypt(ctx context.Context, structPtrs ...any) (forgotten []string, err error) {
+	structs := make([]sensitiveStruct, 0)
+	for _, strPtr := range structPtrs {
+		piiStruct, err := scanStruct(strPtr, false)
+		if err != nil {
+			return nil, err
+		}
+		if piiStruct.HasSensitive() {
+			structs = append(structs, piiStruct)
+		}
+	}
+	if len(structs) == 0 {
+		return
+	}
+
+	subjectIDs := make([]string, 0)
+	fn := func(fr sensitive.FieldReplace, val string) (string, error) {
+		if _, subjectID, _, err := parseWireFormat(val); err == nil {
+			subjectIDs = append(subjectIDs, subjectID)
+		}
+		return val, nil
+	}
 	for idx, s := range structs {
 		if err = s.Replace(fn); err != nil {
 			err = fmt.Errorf("%w at #%d", err, idx)
 			return
 		}
 	}
+	slices.Sort(subjectIDs)
+	subjectIDs = slices.Compact(subjectIDs)
+
+	keys, err := p.getKeyVersions(ctx, subjectIDs)
+	if err != nil {
+		return
+	}
+
+	fn = func(fr sensitive.FieldReplace, val string) (newVal string, err error) {
+		newVal = val
+
+		v, subjectID, params, cipherText, perr := parseWireFormatWithParams(val)
+		if perr != nil {
+			return
+		}
+		if v != 1 && v != wireFormatParamsVersion {
+			err = fmt.Errorf("%w: unsupported version %d", ErrInvalidWireFormat, v)
+			return
+		}
+
+		versions, ok := keys[subjectID]
+		if !ok {
+			forgotten = append(forgotten, subjectID)
+			return
+		}
+		keyVersion, err := p.keyVersion(params)
+		if err != nil {
+			return
+		}
+		key, ok := versions[keyVersion]
+		if !ok {
+			err = fmt.Errorf("key version %d not found", keyVersion)
+			return
+		}
+
+		plainTxt, current, err := p.decrypt(fr, key, params, cipherText, subjectID)
+		if err != nil {
+			return
+		}
+		latest := latestVersion(versions)
+		newParams := p.wireParams(fr, latest)
+		if current && keyVersion == latest && params[paramBinding] == newParams[paramBinding] &&
+			params[paramDerivation] == newParams[paramDerivation] && params[paramPadding] == newParams[paramPadding] {
+			return
+		}
+
+		newVal, err = p.encryptField(fr, subjectID, core.VersionedKey{Version: latest, Key: versions[latest]}, plainTxt)
+		return
+	}
+
+	// changes are rolled back on failure, so that structs are either all re-encrypted or left untouched.
+	j := &journal{}
+	for idx, s := range structs {
+		if err = s.replace(fn, j); err != nil {
+			j.rollback()
+			err = fmt.Errorf("%w at #%d", err, idx)
+			return
+		}
+	}
 
+	slices.Sort(forgotten)
+	forgotten = slices.Compact(forgotten)
 	return
 }
 
+// keyVersion returns the key version recorded in the wire format parameters.
+// It defaults to the first version.
+func (p *protector) keyVersion(params wireParams) (int, error) {
+	kv, ok := params[paramKeyVersion]
+	if !ok {
+		return 1, nil
+	}
+	version, err := strconv.Atoi(kv)
+	if err != nil {
+		return 0, errors.Join(ErrInvalidWireFormat, err)
+	}
+	return version, nil
+}
+
+// encryptField encrypts the given field's plain text using the given subject's key, and returns its wire format.
+func (p *protector) encryptField(fr sensitive.FieldReplace, subjectID string, key core.VersionedKey, plainTxt string) (string, error) {
+	_, enc := p.fieldEncryptor(fr)
+	fieldKey, err := deriveKey(key.Key, p.derivationLabel(fr))
+	if err != nil {
+		return "", err
+	}
+
+	params := p.wireParams(fr, key.Version)
+	if _, ok := params[paramPadding]; ok {
+		if plainTxt, err = p.pad(fr, plainTxt); err != nil {
+			return "", err
+		}
+	}
+
+	cipherText, err := p.encrypt(enc, fieldKey, plainTxt, p.aad(subjectID, fr.Name))
+	if err != nil {
+		return "", err
+	}
+	return wireFormatWithParams(subjectID, params, cipherText), nil
+}
+
+// wireParams returns the wire format parameters of the given field's value encrypted using the given key version.
+// The first key version, empty algorithm IDs, and empty derivation labels are not recorded.
+func (p *protector) wireParams(fr sensitive.FieldReplace, keyVersion int) wireParams {
+	alg, _ := p.fieldEncryptor(fr)
+	label := p.derivationLabel(fr)
+
+	params := wireParams{}
+	if keyVersion > 1 {
+		params[paramKeyVersion] = strconv.Itoa(keyVersion)
+	}
+	if alg != "" {
+		params[paramAlgorithm] = alg
+	}
+	if label != "" {
+		params[paramDerivation] = label
+	}
+	if p.padded(fr) {
+		params[paramPadding] = paddingISO7816
+	}
+	switch p.binding(fr.Name) {
+	case BindSubject:
+		params[paramBinding] = bindingSubject
+	case BindField:
+		params[paramBinding] = bindingField
+	}
+	return params
+}
+
+// binding returns the Binding of the given field. Values without field path, i.e., standalone values,
+// are bound to their subject only, so that they can be decrypted in any field, see ValuesProtector.
+func (p *protector) binding(field string) Binding {
+	if p.Binding == BindField && field == "" {
+		return BindSubject
+	}
+	return p.Binding
+}
+
+// aad returns the additional data to bind a cipher text to, according to the configured Binding.
+func (p *protector) aad(subjectID, field string) core.AAD {
+	switch p.binding(field) {
+	case BindSubject:
+		return core.AAD{SubjectID: subjectID}
+	case BindField:
+		return core.AAD{SubjectID: subjectID, Field: field}
+	}
+	return core.AAD{}
+}
+
+// derivationLabel returns the label of the key used to encrypt the given field according to the configured
+// KeyDerivation, e.g., "f.Addresses.Street" or "c.email". It's empty if keys are not derived.
+func (p *protector) derivationLabel(fr sensitive.FieldReplace) string {
+	if p.encryptOnly {
+		return ""
+	}
+	switch p.KeyDerivation {
+	case DeriveField:
+		return "f." + fr.Name
+	case DeriveCategory:
+		return "c." + fr.Kind
+	}
+	return ""
+}
+
+// deriveKey derives the key of the given label from the given subject key using HKDF-SHA256.
+// It returns the subject key as is if the label is empty.
+func deriveKey(key core.Key, label string) (core.Key, error) {
+	if label == "" {
+		return key, nil
+	}
+	if !derivationLabelRegex.MatchString(label) {
+		return "", fmt.Errorf("invalid key derivation label '%s'", label)
+	}
+	derived, err := aes.HKDF([]byte(key), nil, []byte("privacy-engine/field-key:"+label), len(key))
+	if err != nil {
+		return "", err
+	}
+	return core.Key(derived), nil
+}
+
+// encrypt encrypts the given plain text using the given Encryptor, and binds it to the given additional data if any.
+// Public key Encryptors encrypt using the public key derived from the given key, unless the protector is encrypt-only,
+// in which case the given key is already a public key.
+func (p *protector) encrypt(enc core.Encryptor, key core.Key, plainTxt string, ad core.AAD) ([]byte, error) {
+	if pke, ok := enc.(core.PublicKeyEncryptor); ok && !p.encryptOnly {
+		pub, err := pke.PublicKey(key)
+		if err != nil {
+			return nil, errors.Join(core.ErrEncryptionFailure, err)
+		}
+		key = pub
+	}
+	if ad.IsZero() {
+		return enc.Encrypt(p.namespace, key, plainTxt)
+	}
+	aadEnc, ok := enc.(core.AADEncryptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: additional data not supported", core.ErrEncryptionFailure)
+	}
+	return aadEnc.EncryptWithAAD(p.namespace, key, plainTxt, ad)
+}
+
+// decrypt decrypts the given field's cipher text using the Encryptor recorded in the wire format parameters.
+// Otherwise, it tries the field's current Encryptor, then the previous ones.
+// It reports whether the field's current Encryptor was used.
+//
+// The additional data, the derived key, and the padding are resolved from the wire format parameters.
+func (p *protector) decrypt(fr sensitive.FieldReplace, key core.Key, params wireParams, cipherText []byte, subjectID string) (plainTxt string, current bool, err error) {
+	var ad core.AAD
+	switch bd := params[paramBinding]; bd {
+	case "":
+	case bindingSubject:
+		ad = core.AAD{SubjectID: subjectID}
+	case bindingField:
+		if fr.Name == "" {
+			return "", false, ErrFieldPathRequired
+		}
+		ad = core.AAD{SubjectID: subjectID, Field: fr.Name}
+	default:
+		return "", false, fmt.Errorf("%w: unknown binding '%s'", ErrInvalidWireFormat, bd)
+	}
+
+	if key, err = deriveKey(key, params[paramDerivation]); err != nil {
+		return "", false, err
+	}
+
+	switch pd := params[paramPadding]; pd {
+	case "":
+		return p.decryptCipher(fr, key, params, cipherText, ad)
+	case paddingISO7816:
+		if plainTxt, current, err = p.decryptCipher(fr, key, params, cipherText, ad); err != nil {
+			return
+		}
+		plainTxt, err = unpadISO7816(plainTxt)
+		return
+	default:
+		return "", false, fmt.Errorf("%w: unknown padding '%s'", ErrInvalidWireFormat, pd)
+	}
+}
+
+// decryptCipher decrypts the given cipher text using the Encryptor recorded in the wire format parameters,
+// otherwise the field's current Encryptor, then the previous ones. See decrypt.
+func (p *protector) decryptCipher(fr sensitive.FieldReplace, key core.Key, params wireParams, cipherText []byte, ad core.AAD) (plainTxt string, current bool, err error) {
+	currentAlg, currentEnc := p.fieldEncryptor(fr)
+
+	if alg, ok := params[paramAlgorithm]; ok {
+		if alg == currentAlg {
+			plainTxt, err = decryptWith(currentEnc, p.namespace, key, cipherText, ad)
+			return plainTxt, err == nil, err
+		}
+		enc, ok := p.lookup(alg)
+		if !ok {
+			return "", false, fmt.Errorf("%w: unknown encryptor '%s'", core.ErrDecryptionFailure, alg)
+		}
+		plainTxt, err = decryptWith(enc, p.namespace, key, cipherText, ad)
+		return plainTxt, false, err
+	}
+
+	plainTxt, err = decryptWith(currentEnc, p.namespace, key, cipherText, ad)
+	if err == nil {
+		return plainTxt, true, nil
+	}
+	for _, enc := range p.PreviousEncryptors {
+		if plainTxt, perr := decryptWith(enc, p.namespace, key, cipherText, ad); perr == nil {
+			return plainTxt, false, nil
+		}
+	}
+	return "", false, err
+}
+
+// current returns the Encryptor used to encrypt new values.
+func (p *protector) current() core.Encryptor {
+	if p.Encryptors != nil {
+		_, enc := p.Encryptors.Default()
+		return enc
+	}
+	return p.Encryptor
+}
+
+// currentID returns the algorithm ID of the current Encryptor, it may be empty.
+func (p *protector) currentID() string {
+	if p.Encryptors != nil {
+		id, _ := p.Encryptors.Default()
+		return id
+	}
+	return core.EncryptorID(p.Encryptor)
+}
+
+// fieldEncryptor returns the Encryptor used to encrypt the given field's new values along with its algorithm ID,
+// which may be empty. Fields tagged with the 'deterministic' option use the DeterministicEncryptor,
+// while encrypt-only protectors use the PublicKeyEncryptor.
+func (p *protector) fieldEncryptor(fr sensitive.FieldReplace) (string, core.Encryptor) {
+	if p.encryptOnly {
+		return core.EncryptorID(p.PublicKeyEncryptor), p.PublicKeyEncryptor
+	}
+	if _, ok := fr.Options[tagOptionDeterministic]; ok {
+		return core.EncryptorID(p.DeterministicEncryptor), p.DeterministicEncryptor
+	}
+	return p.currentID(), p.current()
+}
+
+// lookup returns the Encryptor identified by the given algorithm ID.
+func (p *protector) lookup(alg string) (core.Encryptor, bool) {
+	if p.Encryptors != nil {
+		if enc, ok := p.Encryptors.Lookup(alg); ok {
+			return enc, true
+		}
+	} else if core.EncryptorID(p.Encryptor) == alg {
+		return p.Encryptor, true
+	}
+	if core.EncryptorID(p.DeterministicEncryptor) == alg {
+		return p.DeterministicEncryptor, true
+	}
+	if core.EncryptorID(p.PublicKeyEncryptor) == alg {
+		return p.PublicKeyEncryptor, true
+	}
+	for _, enc := range p.PreviousEncryptors {
+		if core.EncryptorID(enc) == alg {
+			return enc, true
+		}
+	}
+	return nil, false
+}
+
+func decryptWith(enc core.Encryptor, namespace string, key core.Key, cipherText []byte, ad core.AAD) (string, error) {
+	if ad.IsZero() {
+		return enc.Decrypt(namespace, key, cipherText)
+	}
+	aadEnc, ok := enc.(core.AADEncryptor)
+	if !ok {
+		return "", fmt.Errorf("%w: additional data not supported", core.ErrDecryptionFailure)
+	}
+	return aadEnc.DecryptWithAAD(namespace, key, cipherText, ad)
+}
+
+func latestVersion(versions map[int]core.Key) int {
+	latest := 1
+	for version := range versions {
+		latest = max(latest, version)
+	}
+	return latest
+}
+
 // Encrypt implements Protector
 func (p *protector) Forget(ctx context.Context, subID string) (err error) {
 
@@ -294,10 +1009,12 @@ func (p *protector) Forget(ctx context.Context, subID string) (err error) {
 
 	if p.GracefulMode {
 		err = p.KeyEngine.DisableKey(ctx, p.namespace, subID)
-		return
+	} else {
+		err = p.KeyEngine.DeleteKey(ctx, p.namespace, subID)
+	}
+	if errors.Is(err, core.ErrKeyOnHold) {
+		err = ErrSubjectOnHold.withBase(err)
 	}
-
-	err = p.KeyEngine.DeleteKey(ctx, p.namespace, subID)
 	return
 }
 
@@ -324,6 +1041,263 @@ func (p *protector) Recover(ctx context.Context, subID string) (err error) {
 	return
 }
 
+// RotateKey implements Protector
+func (p *protector) RotateKey(ctx context.Context, subID string) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrRotateKeyFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subID)
+		}
+	}()
+
+	versioned, ok := p.KeyEngine.(core.VersionedKeyEngine)
+	if !ok {
+		err = core.ErrUnsupported
+		return
+	}
+
+	_, err = versioned.RotateKeys(ctx, p.namespace, []string{subID}, p.current().KeyGen())
+	return
+}
+
+// ForgetAt implements RetentionManager
+func (p *protector) ForgetAt(ctx context.Context, subID string, at time.Time) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrForgetSubjectFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subID)
+		}
+	}()
+
+	eke, ok := p.KeyEngine.(core.ExpiringKeyEngine)
+	if !ok {
+		err = core.ErrUnsupported
+		return
+	}
+
+	// make sure the subject's key exists, so that data encrypted later is forgotten too.
+	if !at.IsZero() {
+		if _, err = p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, []string{subID}, p.current().KeyGen()); err != nil {
+			return
+		}
+	}
+
+	err = eke.ScheduleKeyExpiry(ctx, p.namespace, subID, at)
+	return
+}
+
+// ForgetExpired implements RetentionManager
+func (p *protector) ForgetExpired(ctx context.Context) (forgotten []string, err error) {
+	eke, ok := p.KeyEngine.(core.ExpiringKeyEngine)
+	if !ok {
+		err = ErrForgetSubjectFailure.
+			withBase(core.ErrUnsupported).
+			withNamespace(p.namespace)
+		return
+	}
+
+	expired, err := eke.ExpiredKeys(ctx, p.namespace, time.Now(), p.Retention)
+	if err != nil {
+		err = ErrForgetSubjectFailure.
+			withBase(err).
+			withNamespace(p.namespace)
+		return
+	}
+
+	errs := make([]error, 0)
+	for _, subID := range expired {
+		if ferr := p.Forget(ctx, subID); ferr != nil {
+			errs = append(errs, ferr)
+			continue
+		}
+		forgotten = append(forgotten, subID)
+	}
+
+	return forgotten, errors.Join(errs...)
+}
+
+// PlaceHold implements HoldManager
+func (p *protector) PlaceHold(ctx context.Context, subID, reason string) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrHoldSubjectFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subID)
+		}
+	}()
+
+	hke, ok := p.KeyEngine.(core.HoldKeyEngine)
+	if !ok {
+		err = core.ErrUnsupported
+		return
+	}
+
+	// make sure the subject's key exists, so that data encrypted later is held too.
+	if _, err = p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, []string{subID}, p.current().KeyGen()); err != nil {
+		return
+	}
+
+	err = hke.PlaceHold(ctx, p.namespace, subID, reason)
+	return
+}
+
+// ReleaseHold implements HoldManager
+func (p *protector) ReleaseHold(ctx context.Context, subID string) error {
+	hke, ok := p.KeyEngine.(core.HoldKeyEngine)
+	if !ok {
+		return ErrHoldSubjectFailure.
+			withBase(core.ErrUnsupported).
+			withNamespace(p.namespace).
+			withSubject(subID)
+	}
+
+	hold, err := hke.ReleaseHold(ctx, p.namespace, subID)
+	if err != nil {
+		return ErrHoldSubjectFailure.
+			withBase(err).
+			withNamespace(p.namespace).
+			withSubject(subID)
+	}
+
+	// apply the forget requested while on hold, or the scheduled time and retention that passed.
+	if hold.ForgetRequested {
+		return p.Forget(ctx, subID)
+	}
+	if eke, ok := p.KeyEngine.(core.ExpiringKeyEngine); ok {
+		expired, err := eke.KeyExpired(ctx, p.namespace, subID, time.Now(), p.Retention)
+		if err != nil {
+			return ErrHoldSubjectFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subID)
+		}
+		if expired {
+			return p.Forget(ctx, subID)
+		}
+	}
+	return nil
+}
+
+// ForgetNamespace implements NamespaceManager
+func (p *protector) ForgetNamespace(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrForgetNamespaceFailure.
+				withBase(err).
+				withNamespace(p.namespace)
+		}
+	}()
+
+	nke, ok := p.KeyEngine.(core.NamespaceKeyEngine)
+	if !ok {
+		err = core.ErrUnsupported
+		return
+	}
+
+	if p.GracefulMode {
+		err = nke.DisableNamespace(ctx, p.namespace)
+		return
+	}
+
+	err = nke.DeleteNamespace(ctx, p.namespace)
+	return
+}
+
+// RecoverNamespace implements NamespaceManager
+func (p *protector) RecoverNamespace(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = ErrRecoverNamespaceFailure.
+				withBase(err).
+				withNamespace(p.namespace)
+		}
+	}()
+
+	nke, ok := p.KeyEngine.(core.NamespaceKeyEngine)
+	if !ok {
+		err = core.ErrUnsupported
+		return
+	}
+
+	err = nke.ReEnableNamespace(ctx, p.namespace)
+	return
+}
+
+// getOrCreateLatestKeys returns the latest version of subjects' keys,
+// it falls back to the first version if the Key engine is not versioned.
+func (p *protector) getOrCreateLatestKeys(ctx context.Context, subjectIDs []string) (core.VersionedKeyMap, error) {
+	if versioned, ok := p.KeyEngine.(core.VersionedKeyEngine); ok {
+		return versioned.GetOrCreateLatestKeys(ctx, p.namespace, subjectIDs, p.current().KeyGen())
+	}
+
+	keys, err := p.KeyEngine.GetOrCreateKeys(ctx, p.namespace, subjectIDs, p.current().KeyGen())
+	if err != nil {
+		return nil, err
+	}
+	latest := make(core.VersionedKeyMap)
+	for subID, key := range keys {
+		latest[subID] = core.VersionedKey{Version: 1, Key: key}
+	}
+	return latest, nil
+}
+
+// getOrCreatePublicKeys returns subjects' public keys, which derive from the first version of their keys.
+//
+// Only public keys are fetched from the Key engine; key pairs of fresh new subjects are generated at their creation,
+// and subjects whose keys don't have a public key yet are missing, see PublicKeyPublisher.
+func (p *protector) getOrCreatePublicKeys(ctx context.Context, subjectIDs []string) (core.VersionedKeyMap, error) {
+	pke, ok := p.KeyEngine.(core.PublicKeyEngine)
+	if !ok {
+		return nil, errors.Join(core.ErrGetKeyFailure, core.ErrUnsupported)
+	}
+
+	enc := p.PublicKeyEncryptor.(core.PublicKeyEncryptor)
+	keyGen := enc.KeyGen()
+	pubs, err := pke.GetOrCreatePublicKeys(ctx, p.namespace, subjectIDs,
+		func(ctx context.Context, namespace, keyID string) (core.Key, core.Key, error) {
+			key, err := keyGen(ctx, namespace, keyID)
+			if err != nil {
+				return "", "", err
+			}
+			pub, err := enc.PublicKey(core.Key(key))
+			if err != nil {
+				return "", "", err
+			}
+			return core.Key(key), pub, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	keys := make(core.VersionedKeyMap)
+	for subID, pub := range pubs {
+		keys[subID] = core.VersionedKey{Version: 1, Key: pub}
+	}
+	return keys, nil
+}
+
+// getKeyVersions returns all versions of subjects' keys,
+// it falls back to the first version if the Key engine is not versioned.
+func (p *protector) getKeyVersions(ctx context.Context, subjectIDs []string) (core.KeyVersionsMap, error) {
+	if versioned, ok := p.KeyEngine.(core.VersionedKeyEngine); ok {
+		return versioned.GetKeyVersions(ctx, p.namespace, subjectIDs)
+	}
+
+	keys, err := p.KeyEngine.GetKeys(ctx, p.namespace, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(core.KeyVersionsMap)
+	for subID, key := range keys {
+		versions[subID] = map[int]core.Key{1: key}
+	}
+	return versions, nil
+}
+
 // Encrypt implements Protector
 func (p *protector) Clear(ctx context.Context, force bool) (err error) {
 	defer func() {
@@ -3,9 +3,14 @@ package privacy
 import (
 	"context"
 	"errors"
+	"maps"
 	"reflect"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ln80/privacy-engine/aes"
 	"github.com/ln80/privacy-engine/core"
 	"github.com/ln80/privacy-engine/memory"
 	"github.com/ln80/privacy-engine/privacytest"
@@ -333,6 +338,947 @@ func TestProtector_EncryptDecrypt(t *testing.T) {
 	})
 }
 
+func TestProtector_RotateKey(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-r0t4k1"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	pf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf := pf
+
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert the first key version is not recorded, unlike the encryptor ID
+	if _, _, params, _, err := parseWireFormatWithParams(pf.Fullname); err != nil || params[paramKeyVersion] != "" || params[paramAlgorithm] != aes.ID256GCM {
+		t.Fatalf("expect wire format without key version, got %v, %v", params, err)
+	}
+
+	if err := p.RotateKey(ctx, pf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	rotated := opf
+	if err := p.Encrypt(ctx, &rotated); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	// assert the latest key version is used and recorded in the cipher text
+	v, _, params, _, err := parseWireFormatWithParams(rotated.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := 2, v; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "2", params[paramKeyVersion]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert both versions are decryptable
+	for _, pf := range []Profile{pf, rotated} {
+		if err := p.Decrypt(ctx, &pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// assert forget crypto-erases data encrypted by all versions
+	if err := p.Forget(ctx, pf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, pf := range []Profile{pf, rotated} {
+		if err := p.Decrypt(ctx, &pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := "deleted pii", pf.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	if want, err := ErrRotateKeyFailure, p.RotateKey(ctx, pf.UserID); !errors.Is(err, want) || !errors.Is(err, core.ErrKeyNotFound) {
+		t.Fatalf("expect err be %v, got %v", want, err)
+	}
+
+	// assert rotation requires a versioned key engine
+	p = NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()}, func(pc *ProtectorConfig) {
+		pc.CacheEnabled = false
+	})
+	if want, err := core.ErrUnsupported, p.RotateKey(ctx, pf.UserID); !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got %v", want, err)
+	}
+}
+
+func TestProtector_ForgetAt(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-f0a7t1"
+
+	engine := memory.NewKeyEngine()
+	p := NewProtector(nspace, engine)
+
+	pfs := []Profile{
+		{UserID: "kal5434", Fullname: "Idir Moore", Gender: "M"},
+		{UserID: "kal5435", Fullname: "Lydia Moore", Gender: "F"},
+	}
+	opfs := slices.Clone(pfs)
+	if err := p.Encrypt(ctx, &pfs[0], &pfs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	if err := p.(RetentionManager).ForgetAt(ctx, pfs[0].UserID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := p.(RetentionManager).ForgetAt(ctx, pfs[1].UserID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	forgotten, err := p.(RetentionManager).ForgetExpired(ctx)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []string{pfs[0].UserID}, forgotten; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	decrypted := slices.Clone(pfs)
+	if err := p.Decrypt(ctx, &decrypted[0], &decrypted[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "deleted pii", decrypted[0].Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := opfs[1], decrypted[1]; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the retention doesn't override a scheduled time
+	p = NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Retention = time.Nanosecond
+	})
+	if forgotten, err := p.(RetentionManager).ForgetExpired(ctx); err != nil || len(forgotten) != 0 {
+		t.Fatalf("expect forgotten subjects be empty, got %v, %v", forgotten, err)
+	}
+
+	// assert subjects without a scheduled time are forgotten once the retention elapsed
+	if err := p.(RetentionManager).ForgetAt(ctx, pfs[1].UserID, time.Time{}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	forgotten, err = p.(RetentionManager).ForgetExpired(ctx)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []string{pfs[1].UserID}, forgotten; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert scheduling requires an expiring key engine
+	p = NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()}, func(pc *ProtectorConfig) {
+		pc.CacheEnabled = false
+	})
+	if err := p.(RetentionManager).ForgetAt(ctx, pfs[0].UserID, time.Now()); !errors.Is(err, ErrForgetSubjectFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+	if _, err := p.(RetentionManager).ForgetExpired(ctx); !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func TestProtector_Hold(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-h0l6d1"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	pfs := []Profile{
+		{UserID: "kal5436", Fullname: "Idir Moore", Gender: "M"},
+		{UserID: "kal5437", Fullname: "Lydia Moore", Gender: "F"},
+	}
+	opfs := slices.Clone(pfs)
+	if err := p.Encrypt(ctx, &pfs[0], &pfs[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	for _, pf := range pfs {
+		if err := p.(HoldManager).PlaceHold(ctx, pf.UserID, "litigation"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	}
+
+	// assert a held subject can't be forgotten
+	err := p.Forget(ctx, pfs[0].UserID)
+	if !errors.Is(err, ErrSubjectOnHold) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectOnHold, err)
+	}
+	var perr Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("expect err be a privacy Error, got %T", err)
+	}
+	if want, got := pfs[0].UserID, perr.Subject(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert a held subject doesn't expire
+	if err := p.(RetentionManager).ForgetAt(ctx, pfs[1].UserID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if forgotten, err := p.(RetentionManager).ForgetExpired(ctx); err != nil || len(forgotten) != 0 {
+		t.Fatalf("expect forgotten subjects be empty, got %v, %v", forgotten, err)
+	}
+
+	decrypted := slices.Clone(pfs)
+	if err := p.Decrypt(ctx, &decrypted[0], &decrypted[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opfs, decrypted; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the pending forget and the passed scheduled time are applied once holds are released
+	for _, pf := range pfs {
+		if err := p.(HoldManager).ReleaseHold(ctx, pf.UserID); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+	}
+
+	decrypted = slices.Clone(pfs)
+	if err := p.Decrypt(ctx, &decrypted[0], &decrypted[1]); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, pf := range decrypted {
+		if want, got := "deleted pii", pf.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+
+	// assert holds require a hold key engine
+	p = NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()}, func(pc *ProtectorConfig) {
+		pc.CacheEnabled = false
+	})
+	if err := p.(HoldManager).PlaceHold(ctx, pfs[0].UserID, "litigation"); !errors.Is(err, ErrHoldSubjectFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+	if err := p.(HoldManager).ReleaseHold(ctx, pfs[0].UserID); !errors.Is(err, ErrHoldSubjectFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func TestProtector_ForgetNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	nspace, otherNspace := "tenant-n5f0r1", "tenant-n5f0r2"
+
+	for _, graceful := range []bool{true, false} {
+		t.Run("graceful mode "+strconv.FormatBool(graceful), func(t *testing.T) {
+			engine := memory.NewKeyEngine()
+			withMode := func(pc *ProtectorConfig) {
+				pc.GracefulMode = graceful
+			}
+			p := NewProtector(nspace, engine, withMode)
+			other := NewProtector(otherNspace, engine, withMode)
+
+			pfs := []Profile{
+				{UserID: "kal5431", Fullname: "Idir Moore", Gender: "M"},
+				{UserID: "kal5432", Fullname: "Lydia Moore", Gender: "F"},
+			}
+			opfs := slices.Clone(pfs)
+			if err := p.Encrypt(ctx, &pfs[0], &pfs[1]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			otherPf := opfs[0]
+			if err := other.Encrypt(ctx, &otherPf); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			// the first subject is individually forgotten
+			if err := p.Forget(ctx, pfs[0].UserID); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			if err := p.(NamespaceManager).ForgetNamespace(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			// assert all subjects of the namespace are crypto-erased
+			for _, pf := range pfs {
+				if err := p.Decrypt(ctx, &pf); err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				if want, got := "deleted pii", pf.Fullname; want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+			// assert other namespaces are not affected
+			if err := other.Decrypt(ctx, &otherPf); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if want, got := opfs[0], otherPf; !reflect.DeepEqual(want, got) {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+
+			if err := p.(NamespaceManager).RecoverNamespace(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			recovered := slices.Clone(pfs)
+			if err := p.Decrypt(ctx, &recovered[0], &recovered[1]); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			// assert the individually forgotten subject is not recovered
+			if want, got := "deleted pii", recovered[0].Fullname; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+			// assert hard deleted encryption materials can't be recovered
+			want := opfs[1].Fullname
+			if !graceful {
+				want = "deleted pii"
+			}
+			if got := recovered[1].Fullname; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+
+	// assert namespace operations require a namespace key engine
+	p := NewProtector(nspace, struct{ core.KeyEngine }{memory.NewKeyEngine()}, func(pc *ProtectorConfig) {
+		pc.CacheEnabled = false
+	})
+	if err := p.(NamespaceManager).ForgetNamespace(ctx); !errors.Is(err, ErrForgetNamespaceFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+	if err := p.(NamespaceManager).RecoverNamespace(ctx); !errors.Is(err, ErrRecoverNamespaceFailure) || !errors.Is(err, core.ErrUnsupported) {
+		t.Fatalf("expect err be %v, got %v", core.ErrUnsupported, err)
+	}
+}
+
+func TestProtector_Reencrypt(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-r3nc71"
+
+	engine := memory.NewKeyEngine()
+	previousEnc := &privacytest.UnstableEncryptorMock{PointOfFailure: 100}
+
+	old := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.Encryptor = previousEnc
+	})
+
+	pf1 := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf1 := pf1
+	pf2 := Profile{
+		UserID:   "aze6590",
+		Fullname: "Anna Gibz",
+		Gender:   "F",
+	}
+
+	if err := old.Encrypt(ctx, &pf1, &pf2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := old.Forget(ctx, pf2.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	epf2 := pf2
+
+	p := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+		pc.PreviousEncryptors = []core.Encryptor{previousEnc}
+	})
+	if err := p.RotateKey(ctx, pf1.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	err := p.(Reencrypter).Reencrypt(ctx, &pf1, &pf2)
+	if want := ErrSubjectForgotten; !errors.Is(err, want) {
+		t.Fatalf("expect err be %v, got %v", want, err)
+	}
+	var perr Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("expect err be privacy Error, got %T", err)
+	}
+	if want, got := pf2.UserID, perr.Subject(); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert fields of forgotten subjects are left untouched
+	if want, got := epf2, pf2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert fields are re-encrypted using the latest key version
+	_, _, params, _, err := parseWireFormatWithParams(pf1.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "2", params[paramKeyVersion]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert idempotency
+	epf1 := pf1
+	if err := p.(Reencrypter).Reencrypt(ctx, &pf1); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := epf1, pf1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert re-encrypted fields no longer require the previous encryptor
+	if err := NewProtector(nspace, engine).Decrypt(ctx, &pf1); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf1, pf1; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestProtector_EncryptorDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-d15p4t"
+
+	engine := memory.NewKeyEngine()
+
+	pf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf := pf
+
+	t.Run("legacy wire format", func(t *testing.T) {
+		// an encryptor without ID produces the v1 wire format
+		legacy := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+			pc.Encryptor = struct{ core.Encryptor }{aes.New256GCMEncryptor()}
+		})
+
+		v1pf := pf
+		if err := legacy.Encrypt(ctx, &v1pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if v, _, params, _, err := parseWireFormatWithParams(v1pf.Fullname); err != nil || v != 1 || params != nil {
+			t.Fatalf("expect legacy wire format, got %v, %v, %v", v, params, err)
+		}
+
+		if err := NewProtector(nspace, engine).Decrypt(ctx, &v1pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := opf, v1pf; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("dispatch to the recorded encryptor", func(t *testing.T) {
+		v2pf := pf
+		if err := NewProtector(nspace, engine).Encrypt(ctx, &v2pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		v, _, params, _, err := parseWireFormatWithParams(v2pf.Fullname)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := 2, v; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := aes.ID256GCM, params[paramAlgorithm]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		epf := v2pf
+
+		// the current encryptor would silently return garbage, the recorded one must be used instead
+		p := NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+			pc.Encryptor = &privacytest.UnstableEncryptorMock{PointOfFailure: 100}
+			pc.PreviousEncryptors = []core.Encryptor{aes.New256GCMEncryptor()}
+		})
+		if err := p.Decrypt(ctx, &v2pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := opf, v2pf; !reflect.DeepEqual(want, got) {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert an unknown encryptor ID is refused
+		p = NewProtector(nspace, engine, func(pc *ProtectorConfig) {
+			pc.Encryptor = &privacytest.UnstableEncryptorMock{PointOfFailure: 100}
+		})
+		if err := p.Decrypt(ctx, &epf); err == nil {
+			t.Fatal("expect err be not nil, got nil")
+		}
+	})
+}
+
+func TestProtector_Binding(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-b1nd4d"
+
+	engine := memory.NewKeyEngine()
+
+	pf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+	}
+	opf := pf
+
+	withBinding := func(b Binding) func(*ProtectorConfig) {
+		return func(pc *ProtectorConfig) {
+			pc.Binding = b
+		}
+	}
+
+	// values encrypted before enabling the binding
+	if err := NewProtector(nspace, engine).Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	legacyPf := pf
+
+	p := NewProtector(nspace, engine, withBinding(BindField))
+
+	// assert compatibility with unbound values
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert unbound values are bound once re-encrypted
+	pf = legacyPf
+	if err := p.(Reencrypter).Reencrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err := parseWireFormatWithParams(pf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := bindingField, params[paramBinding]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	boundPf := pf
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert a value moved to another field fails to decrypt
+	pf = boundPf
+	pf.Gender = pf.Fullname
+	if err := p.Decrypt(ctx, &pf); !errors.Is(err, ErrEncryptDecryptFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrEncryptDecryptFailure, err)
+	}
+
+	// assert subject binding allows moving values between fields of the same subject
+	sp := NewProtector(nspace, engine, withBinding(BindSubject))
+	pf = opf
+	if err := sp.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	pf.Gender = pf.Fullname
+	if err := sp.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf.Fullname, pf.Gender; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert binding requires an Encryptor that supports additional data
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expect to panic")
+		}
+	}()
+	NewProtector(nspace, engine, withBinding(BindSubject), func(pc *ProtectorConfig) {
+		pc.Encryptor = &privacytest.UnstableEncryptorMock{}
+	})
+}
+
+func TestProtector_Deterministic(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-d3t3rm"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	acc := Account{
+		UserID:   "kal5430",
+		Email:    "idir@example.com",
+		Fullname: "Idir Moore",
+	}
+	oacc := acc
+	acc2 := acc
+
+	if err := p.Encrypt(ctx, &acc, &acc2); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert deterministic fields allow equality searches, unlike randomized ones
+	if want, got := acc.Email, acc2.Email; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if acc.Fullname == acc2.Fullname {
+		t.Fatal("expect randomized cipher texts be different")
+	}
+	_, _, params, _, err := parseWireFormatWithParams(acc.Email)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := aes.IDSIV, params[paramAlgorithm]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	eacc := acc
+	if err := p.Decrypt(ctx, &acc); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oacc, acc; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert deterministic fields are crypto-erased along with the subject key
+	if err := p.Forget(ctx, acc.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	acc = eacc
+	if err := p.Decrypt(ctx, &acc); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "", acc.Email; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestProtector_KeyDerivation(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-d3r1v3"
+
+	engine := memory.NewKeyEngine()
+
+	withDerivation := func(kd KeyDerivation) func(*ProtectorConfig) {
+		return func(pc *ProtectorConfig) {
+			pc.KeyDerivation = kd
+		}
+	}
+
+	p := NewProtector(nspace, engine, withDerivation(DeriveField))
+
+	pf := Profile{
+		UserID:   "kal5430",
+		Fullname: "Idir Moore",
+		Gender:   "M",
+		Address: Address{
+			Street: "Baker Street",
+		},
+	}
+	opf := pf
+
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for want, val := range map[string]string{
+		"f.Fullname":       pf.Fullname,
+		"f.Address.Street": pf.Address.Street,
+	} {
+		_, _, params, _, err := parseWireFormatWithParams(val)
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if got := params[paramDerivation]; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	epf := pf
+
+	// assert the recorded label allows decrypting using another derivation config
+	np := NewProtector(nspace, engine)
+	if err := np.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert the value is not encrypted using the subject key
+	_, subjectID, params, cipherText, err := parseWireFormatWithParams(epf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	delete(params, paramDerivation)
+	pf = Profile{UserID: opf.UserID, Fullname: wireFormatWithParams(subjectID, params, cipherText)}
+	if err := p.Decrypt(ctx, &pf); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+
+	// assert values are re-derived by Reencrypt
+	pf = epf
+	cp := NewProtector(nspace, engine, withDerivation(DeriveCategory))
+	if err := cp.(Reencrypter).Reencrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err = parseWireFormatWithParams(pf.Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "c.", params[paramDerivation]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	rpf := pf
+	if err := cp.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := opf, pf; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert derived keys are crypto-erased along with the subject key
+	if err := p.Forget(ctx, opf.UserID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	for _, pf := range []Profile{epf, rpf} {
+		if err := np.Decrypt(ctx, &pf); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if want, got := "deleted pii", pf.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+}
+
+type Household struct {
+	UserID  string             `pii:"subjectID"`
+	Members map[string]Address `pii:"dive"`
+}
+
+func TestProtector_Atomicity(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-4t0m1c"
+
+	p := NewProtector(nspace, memory.NewKeyEngine(), func(pc *ProtectorConfig) {
+		pc.BlindIndexKey = []byte("blind-index-secret")
+	})
+
+	// forget a subject to make its encryption fail
+	if err := p.Encrypt(ctx, &Profile{UserID: "sub-3", Fullname: "Idir Moore"}); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := p.Forget(ctx, "sub-3"); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	c := Contact{UserID: "sub-1", Email: "idir@example.com"}
+	h := Household{UserID: "sub-2", Members: map[string]Address{"idir": {Street: "Baker Street"}}}
+	pf := Profile{UserID: "sub-3", Fullname: "Idir Moore"}
+	oc, opf := c, pf
+
+	assertHousehold := func(want map[string]Address, got Household) {
+		t.Helper()
+		if !reflect.DeepEqual(want, got.Members) {
+			t.Fatalf("expect %v, %v be equals", want, got.Members)
+		}
+	}
+
+	// assert a failure leaves all structs untouched, including blind indexes and map elements
+	if err := p.Encrypt(ctx, &c, &h, &pf); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+	if want, got := oc, c; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	assertHousehold(map[string]Address{"idir": {Street: "Baker Street"}}, h)
+	if want, got := opf, pf; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	if err := p.Encrypt(ctx, &c, &h); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	ec, eMembers := c, maps.Clone(h.Members)
+	if c.EmailIdx == "" || !isWireFormatted(h.Members["idir"].Street) {
+		t.Fatalf("expect structs be encrypted, got %v, %v", c, h)
+	}
+
+	// assert a decryption failure leaves all structs encrypted
+	_, subjectID, params, cipherText, err := parseWireFormatWithParams(c.Email)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	cipherText[len(cipherText)-1] ^= 1
+	bad := Profile{UserID: subjectID, Fullname: wireFormatWithParams(subjectID, params, cipherText)}
+
+	if err := p.Decrypt(ctx, &c, &h, &bad); !errors.Is(err, core.ErrDecryptionFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+	}
+	if want, got := ec, c; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	assertHousehold(eMembers, h)
+
+	if err := p.Decrypt(ctx, &c, &h); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := oc.Email, c.Email; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	assertHousehold(map[string]Address{"idir": {Street: "Baker Street"}}, h)
+}
+
+func TestProtector_ErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-3rr0rs"
+
+	engine := memory.NewKeyEngine()
+
+	encrypted := func(t *testing.T, subjectIDs ...string) []Profile {
+		t.Helper()
+		pfs := make([]Profile, 0, len(subjectIDs))
+		for _, subjectID := range subjectIDs {
+			pf := Profile{UserID: subjectID, Fullname: "Idir Moore"}
+			if err := NewProtector(nspace, engine).Encrypt(ctx, &pf); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			pfs = append(pfs, pf)
+		}
+		return pfs
+	}
+
+	pfs := encrypted(t, "sub-1", "sub-2", "sub-3")
+
+	// corrupt the second profile's cipher text, and make the third one's key version unavailable
+	_, subjectID, params, cipherText, err := parseWireFormatWithParams(pfs[1].Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	cipherText[len(cipherText)-1] ^= 1
+	pfs[1].Fullname = wireFormatWithParams(subjectID, params, cipherText)
+
+	_, subjectID, params, cipherText, err = parseWireFormatWithParams(pfs[2].Fullname)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	params[paramKeyVersion] = "9"
+	pfs[2].Fullname = wireFormatWithParams(subjectID, params, cipherText)
+
+	assertFieldErrors := func(t *testing.T, err error, want ...FieldError) {
+		t.Helper()
+		var ferrs FieldErrors
+		if !errors.As(err, &ferrs) {
+			t.Fatalf("expect err be %T, got %v", ferrs, err)
+		}
+		if want, got := len(want), len(ferrs); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		for i, ferr := range ferrs {
+			ferr.Err = nil
+			if want, got := want[i], ferr; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		}
+		var perr Error
+		if !errors.As(err, &perr) {
+			t.Fatalf("expect err be %T, got %v", perr, err)
+		}
+		if want, got := want[0].Path, perr.Field(); want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	}
+	withPolicy := func(policy ErrorPolicy) func(*ProtectorConfig) {
+		return func(pc *ProtectorConfig) {
+			pc.ErrorPolicy = policy
+		}
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		p := NewProtector(nspace, engine)
+
+		a, bad := encrypted(t, "sub-1")[0], pfs[1]
+		ea := a
+		err := p.Decrypt(ctx, &a, &bad)
+		if !errors.Is(err, core.ErrDecryptionFailure) {
+			t.Fatalf("expect err be %v, got %v", core.ErrDecryptionFailure, err)
+		}
+		assertFieldErrors(t, err, FieldError{Index: 1, Path: "Fullname", SubjectID: "sub-2"})
+		if want, got := ea, a; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		p := NewProtector(nspace, engine, withPolicy(BestEffort))
+
+		a, bad, missing := encrypted(t, "sub-1")[0], pfs[1], pfs[2]
+		err := p.Decrypt(ctx, &a, &bad, &missing)
+		assertFieldErrors(t, err,
+			FieldError{Index: 1, Path: "Fullname", SubjectID: "sub-2"},
+			FieldError{Index: 2, Path: "Fullname", SubjectID: "sub-3"},
+		)
+		if want, got := "Idir Moore", a.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := pfs[1], bad; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := pfs[2], missing; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert encryption keeps going when a subject is forgotten
+		encrypted(t, "sub-4")
+		if err := p.Forget(ctx, "sub-4"); err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		b, forgotten := Profile{UserID: "sub-1", Fullname: "Idir Moore"}, Profile{UserID: "sub-4", Fullname: "Idir Moore"}
+		err = p.Encrypt(ctx, &b, &forgotten)
+		if !errors.Is(err, ErrSubjectForgotten) {
+			t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+		}
+		assertFieldErrors(t, err, FieldError{Index: 1, Path: "Fullname", SubjectID: "sub-4"})
+		if !isWireFormatted(b.Fullname) {
+			t.Fatalf("expect %v be encrypted", b)
+		}
+		if want, got := "Idir Moore", forgotten.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+
+	t.Run("skip corrupt", func(t *testing.T) {
+		p := NewProtector(nspace, engine, withPolicy(SkipCorrupt))
+
+		a, bad := encrypted(t, "sub-1")[0], pfs[1]
+		err := p.Decrypt(ctx, &a, &bad)
+		assertFieldErrors(t, err, FieldError{Index: 1, Path: "Fullname", SubjectID: "sub-2"})
+		if want, got := "Idir Moore", a.Fullname; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+		if want, got := pfs[1], bad; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+
+		// assert other failures fail fast
+		a, bad, missing := encrypted(t, "sub-1")[0], pfs[1], pfs[2]
+		ea := a
+		err = p.Decrypt(ctx, &a, &bad, &missing)
+		assertFieldErrors(t, err, FieldError{Index: 2, Path: "Fullname", SubjectID: "sub-3"})
+		if want, got := ea, a; want != got {
+			t.Fatalf("expect %v, %v be equals", want, got)
+		}
+	})
+}
+
 func BenchmarkProtector(b *testing.B) {
 	nspace := "tenant-d195kla"
 
@@ -0,0 +1,108 @@
+package sqlstore
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect presents the SQL syntax specificities of a database engine.
+type Dialect interface {
+	// Name returns the dialect name.
+	Name() string
+
+	// Placeholder returns the bind parameter at the given position, which starts from 1.
+	Placeholder(n int) string
+
+	// InsertIfAbsent returns an insert statement of the given columns,
+	// that silently ignores the row if it conflicts with an existing one.
+	InsertIfAbsent(table string, columns ...string) string
+
+	// BinaryType returns the column type used to store raw bytes.
+	BinaryType() string
+
+	// ColumnExistsQuery returns a query, and its arguments, that counts the columns
+	// of the given table having the given name, i.e., that returns 0 or 1.
+	ColumnExistsQuery(table, column string) (string, []any)
+}
+
+type postgres struct{}
+
+// Postgres returns the PostgreSQL dialect.
+func Postgres() Dialect {
+	return postgres{}
+}
+
+func (postgres) Name() string { return "postgres" }
+
+func (postgres) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (d postgres) InsertIfAbsent(table string, columns ...string) string {
+	return insert(d, "INSERT INTO", table, columns) + " ON CONFLICT DO NOTHING"
+}
+
+func (postgres) BinaryType() string { return "BYTEA" }
+
+func (d postgres) ColumnExistsQuery(table, column string) (string, []any) {
+	// unquoted identifiers are folded to lower case by PostgreSQL.
+	return "SELECT COUNT(*) FROM information_schema.columns" +
+			" WHERE table_schema = current_schema() AND table_name = " + d.Placeholder(1) + " AND column_name = " + d.Placeholder(2),
+		[]any{strings.ToLower(table), strings.ToLower(column)}
+}
+
+type mysql struct{}
+
+// MySQL returns the MySQL/MariaDB dialect.
+func MySQL() Dialect {
+	return mysql{}
+}
+
+func (mysql) Name() string { return "mysql" }
+
+func (mysql) Placeholder(n int) string { return "?" }
+
+func (d mysql) InsertIfAbsent(table string, columns ...string) string {
+	return insert(d, "INSERT IGNORE INTO", table, columns)
+}
+
+func (mysql) BinaryType() string { return "BLOB" }
+
+func (d mysql) ColumnExistsQuery(table, column string) (string, []any) {
+	return "SELECT COUNT(*) FROM information_schema.columns" +
+			" WHERE table_schema = DATABASE() AND table_name = " + d.Placeholder(1) + " AND column_name = " + d.Placeholder(2),
+		[]any{table, column}
+}
+
+type sqlite struct{}
+
+// SQLite returns the SQLite dialect.
+func SQLite() Dialect {
+	return sqlite{}
+}
+
+func (sqlite) Name() string { return "sqlite" }
+
+func (sqlite) Placeholder(n int) string { return "?" }
+
+func (d sqlite) InsertIfAbsent(table string, columns ...string) string {
+	return insert(d, "INSERT INTO", table, columns) + " ON CONFLICT DO NOTHING"
+}
+
+func (sqlite) BinaryType() string { return "BLOB" }
+
+func (d sqlite) ColumnExistsQuery(table, column string) (string, []any) {
+	return "SELECT COUNT(*) FROM pragma_table_info(" + d.Placeholder(1) + ") WHERE name = " + d.Placeholder(2),
+		[]any{table, column}
+}
+
+func insert(d Dialect, verb, table string, columns []string) string {
+	return verb + " " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders(d, 1, len(columns)) + ")"
+}
+
+// placeholders returns a comma-separated list of count bind parameters starting from the given position.
+func placeholders(d Dialect, from, count int) string {
+	params := make([]string, count)
+	for i := range params {
+		params[i] = d.Placeholder(from + i)
+	}
+	return strings.Join(params, ", ")
+}
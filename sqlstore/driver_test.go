@@ -0,0 +1,785 @@
+package sqlstore
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// fakeDriver is an in-process database/sql driver that interprets the small SQL subset
+// used by the key engine, so that tests don't require a database server.
+//
+// It supports CREATE TABLE, CREATE INDEX (ignored), ALTER TABLE ADD COLUMN, INSERT (with conflict
+// handling of the supported dialects), SELECT, UPDATE and DELETE statements; with 'AND'-only
+// WHERE clauses, 'ORDER BY' and 'LIMIT'. Column existence queries of the supported dialects are answered too.
+// Transactions rollback by restoring a snapshot of the database.
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+var fake = &fakeDriver{dbs: make(map[string]*fakeDB)}
+
+func init() {
+	sql.Register("fakesql", fake)
+}
+
+// openFakeDB returns a new connection pool to a fresh fake database.
+func openFakeDB(name string) (*sql.DB, error) {
+	fake.mu.Lock()
+	fake.dbs[name] = &fakeDB{tables: make(map[string]*fakeTable)}
+	fake.mu.Unlock()
+
+	return sql.Open("fakesql", name)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	db, ok := d.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("fake database %s not found", name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeTable struct {
+	columns  []string
+	defaults map[string]driver.Value
+	pk       []string
+	rows     []map[string]driver.Value
+}
+
+func (t *fakeTable) clone() *fakeTable {
+	c := &fakeTable{columns: slices.Clone(t.columns), defaults: maps.Clone(t.defaults), pk: slices.Clone(t.pk)}
+	for _, r := range t.rows {
+		row := make(map[string]driver.Value, len(r))
+		for k, v := range r {
+			row[k] = v
+		}
+		c.rows = append(c.rows, row)
+	}
+	return c
+}
+
+type fakeDB struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+
+	// hook, if set, is called before executing each statement, e.g., to simulate concurrent processes.
+	hook func(query string)
+}
+
+// fakeDBOf returns the fake database of the given name.
+func fakeDBOf(name string) *fakeDB {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	return fake.dbs[name]
+}
+
+type fakeConn struct {
+	db       *fakeDB
+	snapshot map[string]*fakeTable
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.snapshot = make(map[string]*fakeTable)
+	for name, t := range c.db.tables {
+		c.snapshot[name] = t.clone()
+	}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.snapshot = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.snapshot != nil {
+		c.db.tables = c.snapshot
+		c.snapshot = nil
+	}
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error { return nil }
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.conn.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.conn.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type fakeResult struct {
+	affected int64
+	columns  []string
+	rows     [][]driver.Value
+}
+
+func (db *fakeDB) exec(query string, args []driver.Value) (fakeResult, error) {
+	if db.hook != nil {
+		db.hook(query)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if strings.Contains(query, "information_schema.columns") || strings.Contains(query, "pragma_table_info(") {
+		return db.columnExists(args)
+	}
+
+	p := &fakeParser{tokens: tokenize(query), args: args}
+	res, err := p.statement(db)
+	if err != nil {
+		return res, fmt.Errorf("fakesql: %w in '%s'", err, query)
+	}
+	return res, nil
+}
+
+// columnExists answers the column existence queries of the supported dialects,
+// whose bind parameters are the table and column names.
+func (db *fakeDB) columnExists(args []driver.Value) (fakeResult, error) {
+	if len(args) != 2 {
+		return fakeResult{}, errors.New("fakesql: invalid column existence query")
+	}
+	var n int64
+	if t, ok := db.tables[strings.ToLower(fmt.Sprint(args[0]))]; ok && slices.Contains(t.columns, strings.ToLower(fmt.Sprint(args[1]))) {
+		n = 1
+	}
+	return fakeResult{columns: []string{"count"}, rows: [][]driver.Value{{n}}}, nil
+}
+
+// tokenize splits the query into identifiers, literals, placeholders and symbols.
+// Identifiers and keywords are upper-cased, except quoted strings.
+func tokenize(query string) []string {
+	tokens := []string{}
+	rs := []rune(query)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != '\'' {
+				j++
+			}
+			tokens = append(tokens, string(rs[i:min(j+1, len(rs))]))
+			i = j + 1
+		case r == '$' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(rs) && (rs[j] == '_' || unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j])) {
+				j++
+			}
+			tokens = append(tokens, strings.ToUpper(string(rs[i:j])))
+			i = j
+		case (r == '<' || r == '>' || r == '!') && i+1 < len(rs) && (rs[i+1] == '=' || rs[i+1] == '>'):
+			tokens = append(tokens, string(rs[i:i+2]))
+			i += 2
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+type fakeParser struct {
+	tokens []string
+	pos    int
+	args   []driver.Value
+	argPos int
+}
+
+func (p *fakeParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *fakeParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *fakeParser) accept(tokens ...string) bool {
+	for i, t := range tokens {
+		if p.pos+i >= len(p.tokens) || p.tokens[p.pos+i] != t {
+			return false
+		}
+	}
+	p.pos += len(tokens)
+	return true
+}
+
+func (p *fakeParser) expect(tokens ...string) error {
+	if !p.accept(tokens...) {
+		return fmt.Errorf("expect '%s' at %d, got '%s'", strings.Join(tokens, " "), p.pos, p.peek())
+	}
+	return nil
+}
+
+// ident returns the next identifier in lower case.
+func (p *fakeParser) ident() string {
+	return strings.ToLower(p.next())
+}
+
+// list parses a parenthesized, comma-separated list using the given function.
+func (p *fakeParser) list(fn func() error) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		if p.accept(")") {
+			return nil
+		}
+		if err := p.expect(","); err != nil {
+			return err
+		}
+	}
+}
+
+// value parses a literal or a placeholder.
+func (p *fakeParser) value() (driver.Value, error) {
+	t := p.next()
+	switch {
+	case t == "?" || strings.HasPrefix(t, "$"):
+		idx := p.argPos
+		if strings.HasPrefix(t, "$") {
+			n, err := strconv.Atoi(t[1:])
+			if err != nil {
+				return nil, err
+			}
+			idx = n - 1
+		}
+		p.argPos++
+		if idx >= len(p.args) {
+			return nil, errors.New("missing bind parameter")
+		}
+		return p.args[idx], nil
+	case t == "NULL":
+		return nil, nil
+	case strings.HasPrefix(t, "'"):
+		return strings.Trim(t, "'"), nil
+	case t == "-":
+		v, err := p.value()
+		if n, ok := v.(int64); ok {
+			return -n, err
+		}
+		return nil, errors.New("invalid negative value")
+	default:
+		n, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s'", t)
+		}
+		return n, nil
+	}
+}
+
+func (p *fakeParser) table(db *fakeDB) (string, *fakeTable, error) {
+	name := p.ident()
+	t, ok := db.tables[name]
+	if !ok {
+		return name, nil, fmt.Errorf("table '%s' not found", name)
+	}
+	return name, t, nil
+}
+
+func (p *fakeParser) statement(db *fakeDB) (fakeResult, error) {
+	switch {
+	case p.accept("CREATE", "TABLE"):
+		return fakeResult{}, p.createTable(db)
+	case p.accept("CREATE", "INDEX"), p.accept("CREATE", "UNIQUE", "INDEX"):
+		return fakeResult{}, nil
+	case p.accept("ALTER", "TABLE"):
+		return fakeResult{}, p.alterTable(db)
+	case p.accept("INSERT"):
+		return p.insert(db)
+	case p.accept("SELECT"):
+		return p.selectRows(db)
+	case p.accept("UPDATE"):
+		return p.update(db)
+	case p.accept("DELETE", "FROM"):
+		return p.delete(db)
+	}
+	return fakeResult{}, fmt.Errorf("unsupported statement '%s'", p.peek())
+}
+
+// skipDefinition skips a column type and constraints, and returns the default value, if any.
+func (p *fakeParser) skipDefinition() (def driver.Value, pk bool, err error) {
+	depth := 0
+	for {
+		switch t := p.peek(); {
+		case t == "":
+			return
+		case t == "(":
+			depth++
+		case t == ")" && depth == 0, t == "," && depth == 0:
+			return
+		case t == ")":
+			depth--
+		case t == "PRIMARY":
+			pk = true
+		case t == "DEFAULT":
+			p.next()
+			if def, err = p.value(); err != nil {
+				return
+			}
+			continue
+		}
+		p.next()
+	}
+}
+
+func (p *fakeParser) createTable(db *fakeDB) error {
+	ifNotExists := p.accept("IF", "NOT", "EXISTS")
+	name := p.ident()
+	t := &fakeTable{defaults: make(map[string]driver.Value)}
+	err := p.list(func() error {
+		if p.accept("PRIMARY", "KEY") {
+			return p.list(func() error {
+				t.pk = append(t.pk, p.ident())
+				return nil
+			})
+		}
+		col := p.ident()
+		t.columns = append(t.columns, col)
+		def, pk, err := p.skipDefinition()
+		t.defaults[col] = def
+		if pk {
+			t.pk = append(t.pk, col)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := db.tables[name]; ok {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("table '%s' already exists", name)
+	}
+	db.tables[name] = t
+	return nil
+}
+
+func (p *fakeParser) alterTable(db *fakeDB) error {
+	_, t, err := p.table(db)
+	if err != nil {
+		return err
+	}
+	if err := p.expect("ADD"); err != nil {
+		return err
+	}
+	p.accept("COLUMN")
+	col := p.ident()
+	def, _, err := p.skipDefinition()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(t.columns, col) {
+		return fmt.Errorf("column '%s' already exists", col)
+	}
+	t.columns = append(t.columns, col)
+	t.defaults[col] = def
+	for _, r := range t.rows {
+		r[col] = def
+	}
+	return nil
+}
+
+func (p *fakeParser) insert(db *fakeDB) (fakeResult, error) {
+	ignore := p.accept("IGNORE") || p.accept("OR", "IGNORE")
+	if err := p.expect("INTO"); err != nil {
+		return fakeResult{}, err
+	}
+	_, t, err := p.table(db)
+	if err != nil {
+		return fakeResult{}, err
+	}
+	cols := []string{}
+	if err := p.list(func() error {
+		cols = append(cols, p.ident())
+		return nil
+	}); err != nil {
+		return fakeResult{}, err
+	}
+	if err := p.expect("VALUES"); err != nil {
+		return fakeResult{}, err
+	}
+
+	rows := []map[string]driver.Value{}
+	for {
+		row := make(map[string]driver.Value)
+		for _, col := range t.columns {
+			row[col] = t.defaults[col]
+		}
+		i := 0
+		if err := p.list(func() error {
+			v, err := p.value()
+			if i < len(cols) {
+				row[cols[i]] = v
+			}
+			i++
+			return err
+		}); err != nil {
+			return fakeResult{}, err
+		}
+		rows = append(rows, row)
+		if !p.accept(",") {
+			break
+		}
+	}
+	if p.accept("ON", "CONFLICT", "DO", "NOTHING") {
+		ignore = true
+	}
+
+	var affected int64
+	for _, row := range rows {
+		conflict := slices.ContainsFunc(t.rows, func(r map[string]driver.Value) bool {
+			if len(t.pk) == 0 {
+				return false
+			}
+			for _, col := range t.pk {
+				if compare(r[col], row[col]) != 0 {
+					return false
+				}
+			}
+			return true
+		})
+		if conflict {
+			if ignore {
+				continue
+			}
+			return fakeResult{}, errors.New("primary key conflict")
+		}
+		t.rows = append(t.rows, row)
+		affected++
+	}
+
+	return fakeResult{affected: affected}, nil
+}
+
+type fakeCond func(row map[string]driver.Value) bool
+
+// where parses an optional 'AND'-only WHERE clause.
+func (p *fakeParser) where() (fakeCond, error) {
+	conds := []fakeCond{}
+	if !p.accept("WHERE") {
+		return func(map[string]driver.Value) bool { return true }, nil
+	}
+	for {
+		col := p.ident()
+		var cond fakeCond
+		switch op := p.next(); op {
+		case "IS":
+			not := p.accept("NOT")
+			if err := p.expect("NULL"); err != nil {
+				return nil, err
+			}
+			cond = func(r map[string]driver.Value) bool { return (r[col] == nil) != not }
+		case "IN":
+			values := []driver.Value{}
+			if err := p.list(func() error {
+				v, err := p.value()
+				values = append(values, v)
+				return err
+			}); err != nil {
+				return nil, err
+			}
+			cond = func(r map[string]driver.Value) bool {
+				return slices.ContainsFunc(values, func(v driver.Value) bool { return compare(r[col], v) == 0 })
+			}
+		case "=", "<>", "!=", "<", "<=", ">", ">=":
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			cond = func(r map[string]driver.Value) bool {
+				if r[col] == nil || v == nil {
+					return false
+				}
+				c := compare(r[col], v)
+				switch op {
+				case "=":
+					return c == 0
+				case "<>", "!=":
+					return c != 0
+				case "<":
+					return c < 0
+				case "<=":
+					return c <= 0
+				case ">":
+					return c > 0
+				default:
+					return c >= 0
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported operator '%s'", op)
+		}
+		conds = append(conds, cond)
+		if !p.accept("AND") {
+			break
+		}
+	}
+
+	return func(r map[string]driver.Value) bool {
+		for _, cond := range conds {
+			if !cond(r) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func (p *fakeParser) selectRows(db *fakeDB) (fakeResult, error) {
+	cols := []string{}
+	for {
+		if p.accept("*") {
+			cols = nil
+		} else {
+			cols = append(cols, p.ident())
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect("FROM"); err != nil {
+		return fakeResult{}, err
+	}
+	_, t, err := p.table(db)
+	if err != nil {
+		return fakeResult{}, err
+	}
+	if cols == nil {
+		cols = t.columns
+	}
+	cond, err := p.where()
+	if err != nil {
+		return fakeResult{}, err
+	}
+
+	matched := []map[string]driver.Value{}
+	for _, r := range t.rows {
+		if cond(r) {
+			matched = append(matched, r)
+		}
+	}
+
+	if p.accept("ORDER", "BY") {
+		orderBy := []string{}
+		desc := []bool{}
+		for {
+			orderBy = append(orderBy, p.ident())
+			desc = append(desc, p.accept("DESC"))
+			p.accept("ASC")
+			if !p.accept(",") {
+				break
+			}
+		}
+		slices.SortStableFunc(matched, func(a, b map[string]driver.Value) int {
+			for i, col := range orderBy {
+				if c := compare(a[col], b[col]); c != 0 {
+					if desc[i] {
+						return -c
+					}
+					return c
+				}
+			}
+			return 0
+		})
+	}
+	if p.accept("LIMIT") {
+		v, err := p.value()
+		if err != nil {
+			return fakeResult{}, err
+		}
+		if n, ok := v.(int64); ok && int(n) < len(matched) {
+			matched = matched[:n]
+		}
+	}
+
+	res := fakeResult{columns: cols}
+	for _, r := range matched {
+		values := make([]driver.Value, len(cols))
+		for i, col := range cols {
+			if !slices.Contains(t.columns, col) {
+				return fakeResult{}, fmt.Errorf("column '%s' not found", col)
+			}
+			values[i] = r[col]
+		}
+		res.rows = append(res.rows, values)
+	}
+	return res, nil
+}
+
+func (p *fakeParser) update(db *fakeDB) (fakeResult, error) {
+	_, t, err := p.table(db)
+	if err != nil {
+		return fakeResult{}, err
+	}
+	if err := p.expect("SET"); err != nil {
+		return fakeResult{}, err
+	}
+	set := make(map[string]driver.Value)
+	for {
+		col := p.ident()
+		if err := p.expect("="); err != nil {
+			return fakeResult{}, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return fakeResult{}, err
+		}
+		set[col] = v
+		if !p.accept(",") {
+			break
+		}
+	}
+	cond, err := p.where()
+	if err != nil {
+		return fakeResult{}, err
+	}
+
+	var affected int64
+	for _, r := range t.rows {
+		if !cond(r) {
+			continue
+		}
+		for col, v := range set {
+			r[col] = v
+		}
+		affected++
+	}
+	return fakeResult{affected: affected}, nil
+}
+
+func (p *fakeParser) delete(db *fakeDB) (fakeResult, error) {
+	_, t, err := p.table(db)
+	if err != nil {
+		return fakeResult{}, err
+	}
+	cond, err := p.where()
+	if err != nil {
+		return fakeResult{}, err
+	}
+
+	kept := t.rows[:0]
+	var affected int64
+	for _, r := range t.rows {
+		if cond(r) {
+			affected++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	t.rows = kept
+	return fakeResult{affected: affected}, nil
+}
+
+// compare compares two driver values of the same kind; strings and bytes are comparable.
+func compare(a, b driver.Value) int {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+			return 0
+		}
+	case string:
+		switch b := b.(type) {
+		case string:
+			return strings.Compare(a, b)
+		case []byte:
+			return strings.Compare(a, string(b))
+		}
+	case []byte:
+		switch b := b.(type) {
+		case []byte:
+			return bytes.Compare(a, b)
+		case string:
+			return strings.Compare(string(a), b)
+		}
+	case nil:
+		if b == nil {
+			return 0
+		}
+		return -1
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+var _ driver.Driver = &fakeDriver{}
+var _ driver.Conn = &fakeConn{}
+var _ driver.Tx = &fakeConn{}
@@ -0,0 +1,1062 @@
+// Package sqlstore contains a core.KeyEngine implementation on top of database/sql.
+//
+// It supports PostgreSQL, MySQL and SQLite schemas through pluggable dialects,
+// and leaves the driver choice to the caller.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+)
+
+const (
+	tableDefault = "privacy_keys"
+
+	// batchSizeDefault limits the number of bind parameters in 'IN' clauses.
+	batchSizeDefault = 500
+
+	// holdReasonMaxLength is the max number of characters stored by the hold_reason column.
+	holdReasonMaxLength = 1024
+)
+
+// KeyEngineConfig presents the configuration of the SQL KeyEngine.
+type KeyEngineConfig struct {
+	core.KeyEngineConfig
+
+	// Dialect presents the SQL syntax of the underlying database engine.
+	Dialect Dialect
+
+	// Table is the name of the keys table.
+	Table string
+
+	// BatchSize is the max number of keys fetched by a single query.
+	BatchSize int
+}
+
+// KeyEngine is a core.KeyEngine implementation which stores encryption keys
+// and their life-cycle timestamps in a SQL database.
+//
+// It implements core.VersionedKeyEngine; versions created by rotations are stored
+// in a dedicated table suffixed by '_versions'.
+//
+// It's safe to use by concurrent processes sharing the same database.
+// Keys are created using an insert-if-absent statement, so that the first writer wins,
+// and concurrent processes always end up using the same key of a given ID.
+type KeyEngine struct {
+	db *sql.DB
+
+	*KeyEngineConfig
+}
+
+var _ core.KeyEngine = &KeyEngine{}
+var _ core.KeyRewriter = &KeyEngine{}
+var _ core.VersionedKeyEngine = &KeyEngine{}
+var _ core.NamespaceKeyEngine = &KeyEngine{}
+var _ core.ExpiringKeyEngine = &KeyEngine{}
+var _ core.HoldKeyEngine = &KeyEngine{}
+var _ core.PublicKeyEngine = &KeyEngine{}
+
+// NewKeyEngine returns a KeyEngine on top of the given database and dialect.
+// Options params allow overwriting the default configuration.
+//
+// It panics if the database or the dialect is nil.
+// Note that the schema must be migrated using KeyEngine.Migrate or the Migrations statements.
+func NewKeyEngine(db *sql.DB, dialect Dialect, opts ...func(*KeyEngineConfig)) *KeyEngine {
+	if db == nil {
+		panic("invalid database, nil value found")
+	}
+
+	e := &KeyEngine{
+		db: db,
+		KeyEngineConfig: &KeyEngineConfig{
+			KeyEngineConfig: core.NewKeyEngineConfig(),
+			Dialect:         dialect,
+			Table:           tableDefault,
+			BatchSize:       batchSizeDefault,
+		},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(e.KeyEngineConfig)
+	}
+
+	if e.Dialect == nil {
+		panic("invalid SQL dialect, nil value found")
+	}
+	if e.BatchSize <= 0 {
+		e.BatchSize = batchSizeDefault
+	}
+
+	return e
+}
+
+// row presents a persisted encryption key.
+type row struct {
+	id    string
+	key   []byte
+	state core.KeyState
+}
+
+// batch calls the given function for each batch of keyIDs along with the query arguments,
+// i.e., the namespace followed by the batch's keyIDs, and the 'IN' clause placeholders.
+func (e *KeyEngine) batch(namespace string, keyIDs []string, fn func(args []any, in string) error) error {
+	for start := 0; start < len(keyIDs); start += e.BatchSize {
+		batch := keyIDs[start:min(start+e.BatchSize, len(keyIDs))]
+
+		args := make([]any, 0, len(batch)+1)
+		args = append(args, namespace)
+		for _, keyID := range batch {
+			args = append(args, keyID)
+		}
+
+		if err := fn(args, "("+placeholders(e.Dialect, 2, len(batch))+")"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectKeys returns the persisted keys of the given IDs regardless of their states.
+func (e *KeyEngine) selectKeys(ctx context.Context, namespace string, keyIDs []string) (map[string]row, error) {
+	return e.selectRows(ctx, namespace, keyIDs, "key_value")
+}
+
+// selectPublicKeys returns the persisted public keys of the given IDs regardless of their states,
+// without reading the keys. Rows' key is empty if the key doesn't have a public key.
+func (e *KeyEngine) selectPublicKeys(ctx context.Context, namespace string, keyIDs []string) (map[string]row, error) {
+	return e.selectRows(ctx, namespace, keyIDs, "public_key")
+}
+
+// selectRows returns the rows of the given IDs regardless of their states, where rows' key is the given column.
+func (e *KeyEngine) selectRows(ctx context.Context, namespace string, keyIDs []string, column string) (map[string]row, error) {
+	rows := make(map[string]row)
+	err := e.batch(namespace, keyIDs, func(args []any, in string) error {
+		query := "SELECT key_id, " + column + ", state FROM " + e.Table +
+			" WHERE namespace = " + e.Dialect.Placeholder(1) +
+			" AND key_id IN " + in
+
+		return e.query(ctx, query, args, func(rs *sql.Rows) error {
+			var r row
+			if err := rs.Scan(&r.id, &r.key, &r.state); err != nil {
+				return err
+			}
+			rows[r.id] = r
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// selectVersions returns the versions created by rotations of the given IDs,
+// indexed by key ID then by version.
+func (e *KeyEngine) selectVersions(ctx context.Context, namespace string, keyIDs []string) (map[string]map[int][]byte, error) {
+	versions := make(map[string]map[int][]byte)
+	err := e.batch(namespace, keyIDs, func(args []any, in string) error {
+		query := "SELECT key_id, version, key_value FROM " + versionsTable(e.Table) +
+			" WHERE namespace = " + e.Dialect.Placeholder(1) +
+			" AND key_id IN " + in
+
+		return e.query(ctx, query, args, func(rs *sql.Rows) error {
+			var (
+				keyID   string
+				version int
+				key     []byte
+			)
+			if err := rs.Scan(&keyID, &version, &key); err != nil {
+				return err
+			}
+			if _, ok := versions[keyID]; !ok {
+				versions[keyID] = make(map[int][]byte)
+			}
+			versions[keyID][version] = key
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (e *KeyEngine) query(ctx context.Context, query string, args []any, fn func(*sql.Rows) error) error {
+	rs, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	for rs.Next() {
+		if err := fn(rs); err != nil {
+			return err
+		}
+	}
+	return rs.Err()
+}
+
+func (e *KeyEngine) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetKeys implements core.KeyEngine
+func (e *KeyEngine) GetKeys(ctx context.Context, namespace string, keyIDs []string) (core.KeyMap, error) {
+	rows, err := e.selectKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	keys := core.NewKeyMap()
+	for keyID, r := range rows {
+		if r.state != core.StateActive {
+			continue
+		}
+		keys[keyID] = core.Key(r.key)
+	}
+
+	return keys, nil
+}
+
+// GetOrCreateKeys implements core.KeyEngine
+func (e *KeyEngine) GetOrCreateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.KeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	rows, err := e.selectKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	missing := make([]string, 0)
+	newKeys := make(map[string][]byte)
+	for _, keyID := range keyIDs {
+		if _, ok := rows[keyID]; ok {
+			continue
+		}
+		if _, ok := newKeys[keyID]; ok {
+			continue
+		}
+
+		newKey, err := keyGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+		newKeys[keyID] = []byte(newKey)
+		missing = append(missing, keyID)
+	}
+
+	if len(missing) > 0 {
+		now := time.Now().UnixMilli()
+		stmt := e.Dialect.InsertIfAbsent(e.Table, "namespace", "key_id", "key_value", "state", "created_at")
+		if err := e.inTx(ctx, func(tx *sql.Tx) error {
+			for _, keyID := range missing {
+				if _, err := tx.ExecContext(ctx, stmt, namespace, keyID, newKeys[keyID], core.StateActive, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+
+		// Re-read the inserted keys, a concurrent process may have won the race.
+		created, err := e.selectKeys(ctx, namespace, missing)
+		if err != nil {
+			return nil, errors.Join(core.ErrGetKeyFailure, err)
+		}
+		for keyID, r := range created {
+			rows[keyID] = r
+		}
+	}
+
+	keys := core.NewKeyMap()
+	for keyID, r := range rows {
+		// do not return keys of disabled or deleted ones
+		if r.state != core.StateActive {
+			continue
+		}
+		keys[keyID] = core.Key(r.key)
+	}
+
+	return keys, nil
+}
+
+// state returns the state of the given key, or ErrKeyNotFound if it doesn't exist.
+func (e *KeyEngine) state(ctx context.Context, namespace, keyID string) (core.KeyState, error) {
+	var state core.KeyState
+	err := e.db.QueryRowContext(ctx, "SELECT state FROM "+e.Table+
+		" WHERE namespace = "+e.Dialect.Placeholder(1)+
+		" AND key_id = "+e.Dialect.Placeholder(2), namespace, keyID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", core.ErrKeyNotFound
+	}
+	return state, err
+}
+
+// transition updates the state of the given key if it's in the 'from' state,
+// and returns the resulting state.
+//
+// The key is no longer tracked as disabled along with its namespace.
+func (e *KeyEngine) transition(ctx context.Context, namespace, keyID string, from, to core.KeyState, disabledAt int64) (core.KeyState, error) {
+	d := e.Dialect
+	res, err := e.db.ExecContext(ctx, "UPDATE "+e.Table+
+		" SET state = "+d.Placeholder(1)+", disabled_at = "+d.Placeholder(2)+", ns_disabled = 0, forget_requested = 0"+
+		" WHERE namespace = "+d.Placeholder(3)+" AND key_id = "+d.Placeholder(4)+" AND state = "+d.Placeholder(5),
+		to, disabledAt, namespace, keyID, from)
+	if err != nil {
+		return "", err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", err
+	} else if n > 0 {
+		return to, nil
+	}
+
+	return e.state(ctx, namespace, keyID)
+}
+
+// DisableKey implements core.KeyEngine
+func (e *KeyEngine) DisableKey(ctx context.Context, namespace, keyID string) error {
+	d := e.Dialect
+	held := false
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		// the hold is checked by the update itself, so that a hold placed in the meantime is not bypassed.
+		res, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+			" SET state = "+d.Placeholder(1)+", disabled_at = "+d.Placeholder(2)+", ns_disabled = 0, forget_requested = 0"+
+			" WHERE namespace = "+d.Placeholder(3)+" AND key_id = "+d.Placeholder(4)+" AND state = "+d.Placeholder(5)+
+			" AND held_at = 0",
+			core.StateDisabled, time.Now().UnixMilli(), namespace, keyID, core.StateActive)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+
+		// the key is either held, not active, or not found.
+		var (
+			state  core.KeyState
+			heldAt int64
+		)
+		err = tx.QueryRowContext(ctx, "SELECT state, held_at FROM "+e.Table+
+			" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID).Scan(&state, &heldAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return core.ErrKeyNotFound
+		case err != nil:
+			return err
+		case state == core.StateDeleted:
+			return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+		case state == core.StateActive && heldAt > 0:
+			held = true
+			return e.requestForget(ctx, tx, namespace, keyID)
+		}
+
+		// the subject is individually disabled, reenabling its namespace must not reenable it.
+		_, err = tx.ExecContext(ctx, "UPDATE "+e.Table+" SET ns_disabled = 0"+
+			" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2)+" AND ns_disabled = "+d.Placeholder(3),
+			namespace, keyID, 1)
+		return err
+	}); err != nil {
+		if errors.Is(err, core.ErrKeyNotFound) {
+			return err
+		}
+		return errors.Join(core.ErrDisableKeyFailure, err)
+	}
+	if held {
+		return core.ErrKeyOnHold
+	}
+	return nil
+}
+
+// ReEnableKey implements core.KeyEngine
+func (e *KeyEngine) ReEnableKey(ctx context.Context, namespace, keyID string) error {
+	state, err := e.transition(ctx, namespace, keyID, core.StateDisabled, core.StateActive, 0)
+	if err != nil {
+		if errors.Is(err, core.ErrKeyNotFound) {
+			return err
+		}
+		return errors.Join(core.ErrReEnableKeyFailure, err)
+	}
+	if state == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+
+	// the subject is recovered, cancel the forget requested while on hold.
+	d := e.Dialect
+	if _, err := e.db.ExecContext(ctx, "UPDATE "+e.Table+" SET forget_requested = 0"+
+		" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2)+" AND forget_requested = "+d.Placeholder(3),
+		namespace, keyID, 1); err != nil {
+		return errors.Join(core.ErrReEnableKeyFailure, err)
+	}
+	return nil
+}
+
+// DeleteKey implements core.KeyEngine
+//
+// The key row is kept without its value to prevent the creation of a new key for the same ID.
+//
+// All versions of the key are deleted together.
+func (e *KeyEngine) DeleteKey(ctx context.Context, namespace, keyID string) error {
+	d := e.Dialect
+	held := false
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		// the hold is checked by the update itself, so that a hold placed in the meantime is not bypassed.
+		res, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+			" SET state = "+d.Placeholder(1)+", key_value = NULL, public_key = NULL, disabled_at = 0, deleted_at = "+d.Placeholder(2)+
+			" WHERE namespace = "+d.Placeholder(3)+" AND key_id = "+d.Placeholder(4)+
+			" AND state <> "+d.Placeholder(5)+" AND held_at = 0",
+			core.StateDeleted, time.Now().UnixMilli(), namespace, keyID, core.StateDeleted)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// the key is either held, already deleted, or not found.
+			var (
+				state  core.KeyState
+				heldAt int64
+			)
+			err := tx.QueryRowContext(ctx, "SELECT state, held_at FROM "+e.Table+
+				" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID).Scan(&state, &heldAt)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil
+			case err != nil:
+				return err
+			case state == core.StateDeleted:
+				return nil
+			case heldAt > 0:
+				held = true
+				return e.requestForget(ctx, tx, namespace, keyID)
+			}
+			return fmt.Errorf("unexpected state '%s' of key '%s'", state, keyID)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+versionsTable(e.Table)+
+			" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID)
+		return err
+	}); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	if held {
+		return core.ErrKeyOnHold
+	}
+	return nil
+}
+
+// DeleteUnusedKeys implements core.KeyEngine
+func (e *KeyEngine) DeleteUnusedKeys(ctx context.Context, namespace string) error {
+	d := e.Dialect
+	now := time.Now()
+	deadline := now.Add(-e.GracePeriod).UnixMilli()
+
+	unused := make([]string, 0)
+	if err := e.query(ctx, "SELECT key_id FROM "+e.Table+
+		" WHERE namespace = "+d.Placeholder(1)+" AND state = "+d.Placeholder(2)+" AND disabled_at <= "+d.Placeholder(3)+
+		" AND held_at = 0",
+		[]any{namespace, core.StateDisabled, deadline}, func(rs *sql.Rows) error {
+			var keyID string
+			if err := rs.Scan(&keyID); err != nil {
+				return err
+			}
+			unused = append(unused, keyID)
+			return nil
+		}); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		for _, keyID := range unused {
+			// re-check the state, the key may have been reenabled in the meantime.
+			res, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+				" SET state = "+d.Placeholder(1)+", key_value = NULL, public_key = NULL, disabled_at = 0, deleted_at = "+d.Placeholder(2)+
+				" WHERE namespace = "+d.Placeholder(3)+" AND key_id = "+d.Placeholder(4)+
+				" AND state = "+d.Placeholder(5)+" AND disabled_at <= "+d.Placeholder(6)+" AND held_at = 0",
+				core.StateDeleted, now.UnixMilli(), namespace, keyID, core.StateDisabled, deadline)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+versionsTable(e.Table)+
+				" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	return nil
+}
+
+// DisableNamespace implements core.NamespaceKeyEngine
+func (e *KeyEngine) DisableNamespace(ctx context.Context, namespace string) error {
+	d := e.Dialect
+	if _, err := e.db.ExecContext(ctx, "UPDATE "+e.Table+
+		" SET state = "+d.Placeholder(1)+", disabled_at = "+d.Placeholder(2)+", ns_disabled = 1"+
+		" WHERE namespace = "+d.Placeholder(3)+" AND state = "+d.Placeholder(4)+" AND held_at = 0",
+		core.StateDisabled, time.Now().UnixMilli(), namespace, core.StateActive); err != nil {
+		return errors.Join(core.ErrDisableKeyFailure, err)
+	}
+	return nil
+}
+
+// ReEnableNamespace implements core.NamespaceKeyEngine
+func (e *KeyEngine) ReEnableNamespace(ctx context.Context, namespace string) error {
+	d := e.Dialect
+	if _, err := e.db.ExecContext(ctx, "UPDATE "+e.Table+
+		" SET state = "+d.Placeholder(1)+", disabled_at = 0, ns_disabled = 0"+
+		" WHERE namespace = "+d.Placeholder(2)+" AND state = "+d.Placeholder(3)+" AND ns_disabled = "+d.Placeholder(4),
+		core.StateActive, namespace, core.StateDisabled, 1); err != nil {
+		return errors.Join(core.ErrReEnableKeyFailure, err)
+	}
+	return nil
+}
+
+// DeleteNamespace implements core.NamespaceKeyEngine
+//
+// Key rows are kept without their values to prevent the creation of new keys for the same IDs.
+func (e *KeyEngine) DeleteNamespace(ctx context.Context, namespace string) error {
+	d := e.Dialect
+	now := time.Now().UnixMilli()
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		// select the keys to delete beforehand, held keys keep their values and versions.
+		keyIDs := make([]string, 0)
+		rs, err := tx.QueryContext(ctx, "SELECT key_id FROM "+e.Table+
+			" WHERE namespace = "+d.Placeholder(1)+" AND state <> "+d.Placeholder(2)+" AND held_at = 0",
+			namespace, core.StateDeleted)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		for rs.Next() {
+			var keyID string
+			if err := rs.Scan(&keyID); err != nil {
+				return err
+			}
+			keyIDs = append(keyIDs, keyID)
+		}
+		if err := rs.Err(); err != nil {
+			return err
+		}
+		rs.Close()
+
+		for _, keyID := range keyIDs {
+			// re-check the hold, the key may have been held in the meantime.
+			res, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+				" SET state = "+d.Placeholder(1)+", key_value = NULL, public_key = NULL, disabled_at = 0, ns_disabled = 0, deleted_at = "+d.Placeholder(2)+
+				" WHERE namespace = "+d.Placeholder(3)+" AND key_id = "+d.Placeholder(4)+
+				" AND state <> "+d.Placeholder(5)+" AND held_at = 0",
+				core.StateDeleted, now, namespace, keyID, core.StateDeleted)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err != nil {
+				return err
+			} else if n == 0 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+versionsTable(e.Table)+
+				" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errors.Join(core.ErrDeleteKeyFailure, err)
+	}
+	return nil
+}
+
+// ScheduleKeyExpiry implements core.ExpiringKeyEngine
+func (e *KeyEngine) ScheduleKeyExpiry(ctx context.Context, namespace, keyID string, at time.Time) error {
+	var expiresAt int64
+	if !at.IsZero() {
+		expiresAt = at.UnixMilli()
+	}
+
+	d := e.Dialect
+	if _, err := e.db.ExecContext(ctx, "UPDATE "+e.Table+
+		" SET expires_at = "+d.Placeholder(1)+
+		" WHERE namespace = "+d.Placeholder(2)+" AND key_id = "+d.Placeholder(3)+" AND state <> "+d.Placeholder(4),
+		expiresAt, namespace, keyID, core.StateDeleted); err != nil {
+		return errors.Join(core.ErrScheduleKeyFailure, err)
+	}
+
+	// rows affected are not relied on, as some engines, e.g., MySQL, don't count unchanged rows.
+	state, err := e.state(ctx, namespace, keyID)
+	if err != nil {
+		if errors.Is(err, core.ErrKeyNotFound) {
+			return err
+		}
+		return errors.Join(core.ErrScheduleKeyFailure, err)
+	}
+	if state == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+	return nil
+}
+
+// ExpiredKeys implements core.ExpiringKeyEngine
+func (e *KeyEngine) ExpiredKeys(ctx context.Context, namespace string, at time.Time, retention time.Duration) ([]string, error) {
+	d := e.Dialect
+
+	expired := make([]string, 0)
+	scan := func(rs *sql.Rows) error {
+		var keyID string
+		if err := rs.Scan(&keyID); err != nil {
+			return err
+		}
+		expired = append(expired, keyID)
+		return nil
+	}
+
+	if err := e.query(ctx, "SELECT key_id FROM "+e.Table+
+		" WHERE namespace = "+d.Placeholder(1)+" AND state = "+d.Placeholder(2)+
+		" AND held_at = 0 AND expires_at > 0 AND expires_at <= "+d.Placeholder(3),
+		[]any{namespace, core.StateActive, at.UnixMilli()}, scan); err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	if retention > 0 {
+		if err := e.query(ctx, "SELECT key_id FROM "+e.Table+
+			" WHERE namespace = "+d.Placeholder(1)+" AND state = "+d.Placeholder(2)+
+			" AND held_at = 0 AND expires_at = 0 AND created_at <= "+d.Placeholder(3),
+			[]any{namespace, core.StateActive, at.Add(-retention).UnixMilli()}, scan); err != nil {
+			return nil, errors.Join(core.ErrGetKeyFailure, err)
+		}
+	}
+	slices.Sort(expired)
+
+	return expired, nil
+}
+
+// KeyExpired implements core.ExpiringKeyEngine
+func (e *KeyEngine) KeyExpired(ctx context.Context, namespace, keyID string, at time.Time, retention time.Duration) (bool, error) {
+	d := e.Dialect
+
+	var (
+		state                        core.KeyState
+		heldAt, createdAt, expiresAt int64
+	)
+	err := e.db.QueryRowContext(ctx, "SELECT state, held_at, created_at, expires_at FROM "+e.Table+
+		" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2),
+		namespace, keyID).Scan(&state, &heldAt, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Join(core.ErrGetKeyFailure, err)
+	}
+	if state != core.StateActive || heldAt != 0 {
+		return false, nil
+	}
+
+	deadline := expiresAt
+	if deadline == 0 {
+		if retention <= 0 {
+			return false, nil
+		}
+		deadline = time.UnixMilli(createdAt).Add(retention).UnixMilli()
+	}
+	return deadline <= at.UnixMilli(), nil
+}
+
+// PlaceHold implements core.HoldKeyEngine
+//
+// It returns an error if the reason exceeds 1024 characters.
+func (e *KeyEngine) PlaceHold(ctx context.Context, namespace, keyID, reason string) error {
+	if n := utf8.RuneCountInString(reason); n > holdReasonMaxLength {
+		return errors.Join(core.ErrHoldKeyFailure,
+			fmt.Errorf("hold reason too long: %d characters, max %d", n, holdReasonMaxLength))
+	}
+
+	d := e.Dialect
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		// keep the first hold timestamp and the forget request if the key is already held.
+		if _, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+			" SET hold_reason = "+d.Placeholder(1)+
+			" WHERE namespace = "+d.Placeholder(2)+" AND key_id = "+d.Placeholder(3)+" AND state <> "+d.Placeholder(4),
+			reason, namespace, keyID, core.StateDeleted); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE "+e.Table+
+			" SET held_at = "+d.Placeholder(1)+
+			" WHERE namespace = "+d.Placeholder(2)+" AND key_id = "+d.Placeholder(3)+" AND state <> "+d.Placeholder(4)+" AND held_at = 0",
+			time.Now().UnixMilli(), namespace, keyID, core.StateDeleted)
+		return err
+	}); err != nil {
+		return errors.Join(core.ErrHoldKeyFailure, err)
+	}
+
+	state, err := e.state(ctx, namespace, keyID)
+	if err != nil {
+		if errors.Is(err, core.ErrKeyNotFound) {
+			return err
+		}
+		return errors.Join(core.ErrHoldKeyFailure, err)
+	}
+	if state == core.StateDeleted {
+		return fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+	return nil
+}
+
+// ReleaseHold implements core.HoldKeyEngine
+func (e *KeyEngine) ReleaseHold(ctx context.Context, namespace, keyID string) (core.KeyHold, error) {
+	d := e.Dialect
+
+	var (
+		hold     core.KeyHold
+		state    core.KeyState
+		heldAt   int64
+		requests int
+	)
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT state, hold_reason, held_at, forget_requested FROM "+e.Table+
+			" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2),
+			namespace, keyID).Scan(&state, &hold.Reason, &heldAt, &requests)
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrKeyNotFound
+		}
+		if err != nil || state == core.StateDeleted || heldAt == 0 {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE "+e.Table+
+			" SET hold_reason = '', held_at = 0, forget_requested = 0"+
+			" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2),
+			namespace, keyID)
+		return err
+	}); err != nil {
+		if errors.Is(err, core.ErrKeyNotFound) {
+			return core.KeyHold{}, err
+		}
+		return core.KeyHold{}, errors.Join(core.ErrHoldKeyFailure, err)
+	}
+	if state == core.StateDeleted {
+		return core.KeyHold{}, fmt.Errorf("%w: hard deleted key", core.ErrKeyNotFound)
+	}
+	if heldAt == 0 {
+		return core.KeyHold{}, nil
+	}
+
+	hold.PlacedAt = time.UnixMilli(heldAt)
+	hold.ForgetRequested = requests > 0
+	return hold, nil
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// requestForget records the request to forget the given held key, so that it's forgotten once released.
+func (e *KeyEngine) requestForget(ctx context.Context, x execer, namespace, keyID string) error {
+	d := e.Dialect
+	_, err := x.ExecContext(ctx, "UPDATE "+e.Table+" SET forget_requested = 1"+
+		" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2)+" AND held_at > 0",
+		namespace, keyID)
+	return err
+}
+
+// RewriteKeys implements core.KeyRewriter
+func (e *KeyEngine) RewriteKeys(ctx context.Context, namespace string, keyIDs []string, fn core.KeyRewriteFunc) error {
+	var (
+		rows map[string]row
+		err  error
+	)
+	if keyIDs == nil {
+		rows = make(map[string]row)
+		err = e.query(ctx, "SELECT key_id, key_value, state FROM "+e.Table+
+			" WHERE namespace = "+e.Dialect.Placeholder(1), []any{namespace}, func(rs *sql.Rows) error {
+			var r row
+			if err := rs.Scan(&r.id, &r.key, &r.state); err != nil {
+				return err
+			}
+			rows[r.id] = r
+			return nil
+		})
+	} else {
+		rows, err = e.selectKeys(ctx, namespace, keyIDs)
+	}
+	if err != nil {
+		return errors.Join(core.ErrRewriteKeyFailure, err)
+	}
+
+	ids := make([]string, 0, len(rows))
+	for keyID := range rows {
+		ids = append(ids, keyID)
+	}
+	versions, err := e.selectVersions(ctx, namespace, ids)
+	if err != nil {
+		return errors.Join(core.ErrRewriteKeyFailure, err)
+	}
+
+	rewritten := make(map[string][]byte)
+	rewrittenVersions := make(map[string]map[int][]byte)
+	for keyID, r := range rows {
+		if r.state == core.StateDeleted {
+			continue
+		}
+		newKey, err := fn(keyID, core.Key(r.key))
+		if err != nil {
+			return errors.Join(core.ErrRewriteKeyFailure, err)
+		}
+		if newKey != core.Key(r.key) {
+			rewritten[keyID] = []byte(newKey)
+		}
+		for version, key := range versions[keyID] {
+			newKey, err := fn(keyID, core.Key(key))
+			if err != nil {
+				return errors.Join(core.ErrRewriteKeyFailure, err)
+			}
+			if newKey == core.Key(key) {
+				continue
+			}
+			if _, ok := rewrittenVersions[keyID]; !ok {
+				rewrittenVersions[keyID] = make(map[int][]byte)
+			}
+			rewrittenVersions[keyID][version] = []byte(newKey)
+		}
+	}
+	if len(rewritten) == 0 && len(rewrittenVersions) == 0 {
+		return nil
+	}
+
+	d := e.Dialect
+	stmt := "UPDATE " + e.Table + " SET key_value = " + d.Placeholder(1) +
+		" WHERE namespace = " + d.Placeholder(2) + " AND key_id = " + d.Placeholder(3) + " AND state <> " + d.Placeholder(4)
+	versionStmt := "UPDATE " + versionsTable(e.Table) + " SET key_value = " + d.Placeholder(1) +
+		" WHERE namespace = " + d.Placeholder(2) + " AND key_id = " + d.Placeholder(3) + " AND version = " + d.Placeholder(4)
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		for keyID, newKey := range rewritten {
+			if _, err := tx.ExecContext(ctx, stmt, newKey, namespace, keyID, core.StateDeleted); err != nil {
+				return err
+			}
+		}
+		for keyID, versions := range rewrittenVersions {
+			for version, newKey := range versions {
+				if _, err := tx.ExecContext(ctx, versionStmt, newKey, namespace, keyID, version); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return errors.Join(core.ErrRewriteKeyFailure, err)
+	}
+	return nil
+}
+
+// GetOrCreateLatestKeys implements core.VersionedKeyEngine
+func (e *KeyEngine) GetOrCreateLatestKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	keys, err := e.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := e.selectVersions(ctx, namespace, keys.KeyIDs())
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	latest := make(core.VersionedKeyMap)
+	for keyID, k := range keys {
+		latest[keyID] = core.VersionedKey{Version: 1, Key: k}
+		for version, key := range versions[keyID] {
+			if version > latest[keyID].Version {
+				latest[keyID] = core.VersionedKey{Version: version, Key: core.Key(key)}
+			}
+		}
+	}
+	return latest, nil
+}
+
+// GetOrCreatePublicKeys implements core.PublicKeyEngine
+//
+// Only the public_key column of existing keys is read.
+func (e *KeyEngine) GetOrCreatePublicKeys(ctx context.Context, namespace string, keyIDs []string, keyPairGen core.KeyPairGen) (core.KeyMap, error) {
+	rows, err := e.selectPublicKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	missing := make([]string, 0)
+	newKeys := make(map[string][2]core.Key)
+	for _, keyID := range keyIDs {
+		if _, ok := rows[keyID]; ok {
+			continue
+		}
+		if _, ok := newKeys[keyID]; ok {
+			continue
+		}
+
+		newKey, pub, err := keyPairGen(ctx, namespace, keyID)
+		if err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+		newKeys[keyID] = [2]core.Key{newKey, pub}
+		missing = append(missing, keyID)
+	}
+
+	if len(missing) > 0 {
+		now := time.Now().UnixMilli()
+		stmt := e.Dialect.InsertIfAbsent(e.Table, "namespace", "key_id", "key_value", "public_key", "state", "created_at")
+		if err := e.inTx(ctx, func(tx *sql.Tx) error {
+			for _, keyID := range missing {
+				pair := newKeys[keyID]
+				if _, err := tx.ExecContext(ctx, stmt, namespace, keyID, []byte(pair[0]), []byte(pair[1]), core.StateActive, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Join(core.ErrPersistKeyFailure, err)
+		}
+
+		// Re-read the inserted public keys, a concurrent process may have won the race.
+		created, err := e.selectPublicKeys(ctx, namespace, missing)
+		if err != nil {
+			return nil, errors.Join(core.ErrGetKeyFailure, err)
+		}
+		for keyID, r := range created {
+			rows[keyID] = r
+		}
+	}
+
+	pubs := core.NewKeyMap()
+	for keyID, r := range rows {
+		// do not return public keys of disabled or deleted ones
+		if r.state != core.StateActive || len(r.key) == 0 {
+			continue
+		}
+		pubs[keyID] = core.Key(r.key)
+	}
+
+	return pubs, nil
+}
+
+// PutPublicKeys implements core.PublicKeyEngine
+func (e *KeyEngine) PutPublicKeys(ctx context.Context, namespace string, publicKeys core.KeyMap) error {
+	d := e.Dialect
+	stmt := "UPDATE " + e.Table + " SET public_key = " + d.Placeholder(1) +
+		" WHERE namespace = " + d.Placeholder(2) + " AND key_id = " + d.Placeholder(3) +
+		" AND state = " + d.Placeholder(4) + " AND public_key IS NULL"
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		for keyID, pub := range publicKeys {
+			if _, err := tx.ExecContext(ctx, stmt, []byte(pub), namespace, keyID, core.StateActive); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return errors.Join(core.ErrPersistKeyFailure, err)
+	}
+	return nil
+}
+
+// GetKeyVersions implements core.VersionedKeyEngine
+func (e *KeyEngine) GetKeyVersions(ctx context.Context, namespace string, keyIDs []string) (core.KeyVersionsMap, error) {
+	keys, err := e.GetKeys(ctx, namespace, keyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := e.selectVersions(ctx, namespace, keys.KeyIDs())
+	if err != nil {
+		return nil, errors.Join(core.ErrGetKeyFailure, err)
+	}
+
+	result := make(core.KeyVersionsMap)
+	for keyID, k := range keys {
+		result[keyID] = map[int]core.Key{1: k}
+		for version, key := range versions[keyID] {
+			result[keyID][version] = core.Key(key)
+		}
+	}
+	return result, nil
+}
+
+// RotateKeys implements core.VersionedKeyEngine
+//
+// Concurrent rotations of the same key conflict on the version's primary key,
+// in which case the rotation fails and can be retried.
+func (e *KeyEngine) RotateKeys(ctx context.Context, namespace string, keyIDs []string, keyGen core.KeyGen) (core.VersionedKeyMap, error) {
+	if keyGen == nil {
+		keyGen = aes.Key256GenFn
+	}
+
+	d := e.Dialect
+	latest := make(core.VersionedKeyMap)
+	if err := e.inTx(ctx, func(tx *sql.Tx) error {
+		for _, keyID := range keyIDs {
+			if _, ok := latest[keyID]; ok {
+				continue
+			}
+
+			var state core.KeyState
+			err := tx.QueryRowContext(ctx, "SELECT state FROM "+e.Table+
+				" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2), namespace, keyID).Scan(&state)
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && state != core.StateActive) {
+				return fmt.Errorf("%w: '%s'", core.ErrKeyNotFound, keyID)
+			}
+			if err != nil {
+				return err
+			}
+
+			version := 1
+			err = tx.QueryRowContext(ctx, "SELECT version FROM "+versionsTable(e.Table)+
+				" WHERE namespace = "+d.Placeholder(1)+" AND key_id = "+d.Placeholder(2)+
+				" ORDER BY version DESC LIMIT 1", namespace, keyID).Scan(&version)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			newKey, err := keyGen(ctx, namespace, keyID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, insert(d, "INSERT INTO", versionsTable(e.Table), []string{"namespace", "key_id", "version", "key_value", "created_at"}),
+				namespace, keyID, version+1, []byte(newKey), time.Now().UnixMilli()); err != nil {
+				return err
+			}
+			latest[keyID] = core.VersionedKey{Version: version + 1, Key: core.Key(newKey)}
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Join(core.ErrRotateKeyFailure, err)
+	}
+
+	return latest, nil
+}
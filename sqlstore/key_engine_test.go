@@ -0,0 +1,270 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/core"
+	"github.com/ln80/privacy-engine/privacytest"
+)
+
+func TestKeyEngine(t *testing.T) {
+	ctx := context.Background()
+
+	gracePeriod := 100 * time.Millisecond
+
+	for _, dialect := range []Dialect{Postgres(), MySQL(), SQLite()} {
+		t.Run(dialect.Name()+" engine", func(t *testing.T) {
+			db, err := openFakeDB(t.Name())
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			defer db.Close()
+
+			eng := NewKeyEngine(db, dialect, func(c *KeyEngineConfig) {
+				c.GracePeriod = gracePeriod
+				c.BatchSize = 2
+			})
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			// assert migration idempotency
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			privacytest.RunKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+				c.GracePeriod = gracePeriod
+			})
+
+			privacytest.RunVersionedKeyEngineTest(t, ctx, eng)
+
+			privacytest.RunNamespaceKeyEngineTest(t, ctx, eng)
+
+			privacytest.RunExpiringKeyEngineTest(t, ctx, eng)
+
+			privacytest.RunHoldKeyEngineTest(t, ctx, eng, func(c *privacytest.KeyEngineTestConfig) {
+				c.GracePeriod = gracePeriod
+			})
+
+			privacytest.RunPublicKeyEngineTest(t, ctx, eng)
+		})
+	}
+}
+
+func TestKeyEngine_ConcurrentCreate(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := openFakeDB(t.Name())
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	defer db.Close()
+
+	// simulate two processes, i.e. pods, sharing the same database
+	eng1 := NewKeyEngine(db, Postgres())
+	eng2 := NewKeyEngine(db, Postgres())
+	if err := eng1.Migrate(ctx); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	namespace := "tenant-s0q1l8"
+	keyIDs := []string{"sub-1", "sub-2", "sub-3"}
+
+	var keys2 core.KeyMap
+
+	// the second process creates the key of 'sub-2' while the first one
+	// is about to create it, after checking it doesn't exist.
+	keyGen := func(ctx context.Context, namespace, keyID string) (string, error) {
+		if keyID == "sub-2" {
+			var err error
+			if keys2, err = eng2.GetOrCreateKeys(ctx, namespace, []string{keyID}, nil); err != nil {
+				return "", err
+			}
+		}
+		return aes.Key256GenFn(ctx, namespace, keyID)
+	}
+
+	keys1, err := eng1.GetOrCreateKeys(ctx, namespace, keyIDs, keyGen)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := keys2["sub-2"], keys1["sub-2"]; want == "" || want != got {
+		t.Fatal("expect processes use the same key of 'sub-2'")
+	}
+
+	keys2, err = eng2.GetOrCreateKeys(ctx, namespace, keyIDs, nil)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := keys1, keys2; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestKeyEngine_ConcurrentHold(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := openFakeDB(t.Name())
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	defer db.Close()
+
+	eng := NewKeyEngine(db, Postgres())
+	if err := eng.Migrate(ctx); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	fdb := fakeDBOf(t.Name())
+	namespace := "tenant-h0ld"
+
+	for name, forget := range map[string]func(ctx context.Context, namespace, keyID string) error{
+		"disable": eng.DisableKey,
+		"delete":  eng.DeleteKey,
+	} {
+		t.Run(name, func(t *testing.T) {
+			keyID := "sub-" + name
+
+			if _, err := eng.GetOrCreateKeys(ctx, namespace, []string{keyID}, nil); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			// another process places a hold right before the key is forgotten
+			placed := false
+			fdb.hook = func(query string) {
+				if placed || !strings.HasPrefix(query, "UPDATE "+eng.Table+" SET state = ") {
+					return
+				}
+				placed = true
+				if err := eng.PlaceHold(ctx, namespace, keyID, "litigation"); err != nil {
+					t.Errorf("expect err be nil, got: %v", err)
+				}
+			}
+
+			if err := forget(ctx, namespace, keyID); !errors.Is(err, core.ErrKeyOnHold) {
+				t.Fatalf("expect err be %v, got %v", core.ErrKeyOnHold, err)
+			}
+			keys, err := eng.GetKeys(ctx, namespace, []string{keyID})
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if _, ok := keys[keyID]; !ok {
+				t.Fatal("expect held key remain active")
+			}
+
+			// assert the request to forget the key is recorded
+			hold, err := eng.ReleaseHold(ctx, namespace, keyID)
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			if !hold.ForgetRequested {
+				t.Fatal("expect forget be requested")
+			}
+		})
+	}
+}
+
+func TestKeyEngine_HoldReason(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := openFakeDB(t.Name())
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	defer db.Close()
+
+	eng := NewKeyEngine(db, Postgres())
+	if err := eng.Migrate(ctx); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	namespace := "tenant-h0ld"
+	keyID := "sub-1"
+
+	if _, err := eng.GetOrCreateKeys(ctx, namespace, []string{keyID}, nil); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	// assert reasons that don't fit in the column are refused
+	if err := eng.PlaceHold(ctx, namespace, keyID, strings.Repeat("é", holdReasonMaxLength+1)); !errors.Is(err, core.ErrHoldKeyFailure) {
+		t.Fatalf("expect err be %v, got %v", core.ErrHoldKeyFailure, err)
+	}
+	hold, err := eng.ReleaseHold(ctx, namespace, keyID)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !hold.PlacedAt.IsZero() {
+		t.Fatal("expect key not be held")
+	}
+
+	reason := strings.Repeat("é", holdReasonMaxLength)
+	if err := eng.PlaceHold(ctx, namespace, keyID, reason); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	hold, err = eng.ReleaseHold(ctx, namespace, keyID)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := reason, hold.Reason; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
+
+func TestKeyEngine_PartialMigration(t *testing.T) {
+	ctx := context.Background()
+
+	for _, dialect := range []Dialect{Postgres(), MySQL(), SQLite()} {
+		t.Run(dialect.Name()+" engine", func(t *testing.T) {
+			db, err := openFakeDB(t.Name())
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			defer db.Close()
+
+			eng := NewKeyEngine(db, dialect)
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			for column, want := range map[string]bool{"key_value": true, "unknown": false} {
+				got, err := eng.columnExists(ctx, eng.Table, column)
+				if err != nil {
+					t.Fatalf("expect err be nil, got: %v", err)
+				}
+				if want != got {
+					t.Fatalf("expect %v, %v be equals", want, got)
+				}
+			}
+
+			// simulate engines that implicitly commit DDL statements, i.e., schema changes are applied
+			// while the failed migrations versions are not recorded.
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+eng.migrationsTable()+
+				" WHERE version >= "+dialect.Placeholder(1), 1); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			if err := eng.Migrate(ctx); err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+
+			rows, err := db.QueryContext(ctx, "SELECT version FROM "+eng.migrationsTable())
+			if err != nil {
+				t.Fatalf("expect err be nil, got: %v", err)
+			}
+			defer rows.Close()
+			n := 0
+			for rows.Next() {
+				n++
+			}
+			if want, got := len(Migrations(dialect, eng.Table)), n; want != got {
+				t.Fatalf("expect %v, %v be equals", want, got)
+			}
+		})
+	}
+}
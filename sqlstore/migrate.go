@@ -0,0 +1,204 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMigrationFailure = errors.New("failed to migrate key engine schema")
+)
+
+// Migration presents a versioned change of the key engine schema.
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+
+	// Column is the column of the keys table added by the migration, if any.
+	// Migrate skips the migration's statements if the column already exists,
+	// e.g., when a previous attempt added it but failed to record the version.
+	Column string
+}
+
+// Migrations returns the ordered list of schema migrations of the given dialect and keys table.
+//
+// It allows using an external migration tool instead of KeyEngine.Migrate.
+func Migrations(d Dialect, table string) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "create keys table",
+			Statements: []string{
+				"CREATE TABLE IF NOT EXISTS " + table + " (" +
+					"namespace VARCHAR(255) NOT NULL, " +
+					"key_id VARCHAR(255) NOT NULL, " +
+					"key_value " + d.BinaryType() + ", " +
+					"state VARCHAR(16) NOT NULL, " +
+					"created_at BIGINT NOT NULL DEFAULT 0, " +
+					"disabled_at BIGINT NOT NULL DEFAULT 0, " +
+					"deleted_at BIGINT NOT NULL DEFAULT 0, " +
+					"PRIMARY KEY (namespace, key_id))",
+			},
+		},
+		{
+			Version:     2,
+			Description: "create key versions table",
+			Statements: []string{
+				"CREATE TABLE IF NOT EXISTS " + versionsTable(table) + " (" +
+					"namespace VARCHAR(255) NOT NULL, " +
+					"key_id VARCHAR(255) NOT NULL, " +
+					"version BIGINT NOT NULL, " +
+					"key_value " + d.BinaryType() + " NOT NULL, " +
+					"created_at BIGINT NOT NULL DEFAULT 0, " +
+					"PRIMARY KEY (namespace, key_id, version))",
+			},
+		},
+		{
+			Version:     3,
+			Description: "track keys disabled along with their namespace",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN ns_disabled SMALLINT NOT NULL DEFAULT 0",
+			},
+			Column: "ns_disabled",
+		},
+		{
+			Version:     4,
+			Description: "add keys expiry deadline",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN expires_at BIGINT NOT NULL DEFAULT 0",
+			},
+			Column: "expires_at",
+		},
+		{
+			Version:     5,
+			Description: "add keys hold reason",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN hold_reason VARCHAR(1024) NOT NULL DEFAULT ''",
+			},
+			Column: "hold_reason",
+		},
+		{
+			Version:     6,
+			Description: "add keys hold time",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN held_at BIGINT NOT NULL DEFAULT 0",
+			},
+			Column: "held_at",
+		},
+		{
+			Version:     7,
+			Description: "track keys requested to be forgotten while on hold",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN forget_requested SMALLINT NOT NULL DEFAULT 0",
+			},
+			Column: "forget_requested",
+		},
+		{
+			Version:     8,
+			Description: "add keys public key",
+			Statements: []string{
+				"ALTER TABLE " + table + " ADD COLUMN public_key " + d.BinaryType(),
+			},
+			Column: "public_key",
+		},
+	}
+}
+
+// Migrate applies the pending schema migrations.
+// The applied versions are tracked in a dedicated table suffixed by '_migrations'.
+//
+// Each migration is applied within a transaction. Note that some engines, e.g., MySQL,
+// implicitly commit DDL statements, that's why statements are idempotent whenever the SQL syntax allows it,
+// and migrations that add a column are skipped if the column already exists, see Migration.Column.
+func (e *KeyEngine) Migrate(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(ErrMigrationFailure, err)
+		}
+	}()
+
+	d, table := e.Dialect, e.migrationsTable()
+
+	if _, err = e.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+" ("+
+		"version BIGINT NOT NULL PRIMARY KEY, "+
+		"applied_at BIGINT NOT NULL)"); err != nil {
+		return
+	}
+
+	applied := make(map[int]bool)
+	rows, err := e.db.QueryContext(ctx, "SELECT version FROM "+table)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var v int
+		if err = rows.Scan(&v); err != nil {
+			rows.Close()
+			return
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	for _, m := range Migrations(d, e.Table) {
+		if applied[m.Version] {
+			continue
+		}
+		if err = e.migrate(ctx, m); err != nil {
+			return fmt.Errorf("version %d: %w", m.Version, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *KeyEngine) migrate(ctx context.Context, m Migration) error {
+	statements := m.Statements
+	if m.Column != "" {
+		exists, err := e.columnExists(ctx, e.Table, m.Column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			statements = nil
+		}
+	}
+
+	return e.inTx(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, e.Dialect.InsertIfAbsent(e.migrationsTable(), "version", "applied_at"),
+			m.Version, time.Now().UnixMilli())
+		return err
+	})
+}
+
+// columnExists reports whether the given table has the given column.
+func (e *KeyEngine) columnExists(ctx context.Context, table, column string) (bool, error) {
+	query, args := e.Dialect.ColumnExistsQuery(table, column)
+
+	var n int
+	if err := e.db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (e *KeyEngine) migrationsTable() string {
+	return e.Table + "_migrations"
+}
+
+// versionsTable returns the name of the table holding the versions created by key rotations.
+func versionsTable(table string) string {
+	return table + "_versions"
+}
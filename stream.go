@@ -0,0 +1,141 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ln80/privacy-engine/aes"
+)
+
+// StreamProtector is implemented by Protector services that encrypt and decrypt streams.
+type StreamProtector interface {
+
+	// EncryptStream returns a reader of the given reader's content encrypted using the given subject's key,
+	// which allows encrypting contents too large to fit in struct fields, e.g., documents and images.
+	// The content is encrypted in chunks using the STREAM construction, see aes.NewStreamEncrypter.
+	//
+	// Encryption materials are fetched on the first read, and errors are returned by the reader.
+	// Encrypted streams are crypto-erased along with the subject's Personal data.
+	EncryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader
+
+	// DecryptStream returns a reader of the given encrypted stream's plain text content, see EncryptStream.
+	//
+	// The reader fails with ErrSubjectForgotten error if the subject is forgotten. The stream's integrity
+	// is only guaranteed once the reader returns io.EOF; the content read so far must be discarded otherwise.
+	DecryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader
+}
+
+var _ StreamProtector = &protector{}
+
+// streamFormatVersion is the version of the encrypted streams' header.
+const streamFormatVersion byte = 1
+
+// streamHeaderSize is the size of the encrypted streams' header, i.e., the format version followed by the key version.
+const streamHeaderSize = 5
+
+// EncryptStream implements StreamProtector
+func (p *protector) EncryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader {
+	return p.lazyStream(subjectID, func() (io.Reader, error) {
+		if subjectID == "" {
+			return nil, errors.New("empty subject ID")
+		}
+		keys, err := p.getOrCreateLatestKeys(ctx, []string{subjectID})
+		if err != nil {
+			return nil, err
+		}
+		key, ok := keys[subjectID]
+		if !ok {
+			return nil, ErrSubjectForgotten.withSubject(subjectID)
+		}
+
+		header := make([]byte, streamHeaderSize)
+		header[0] = streamFormatVersion
+		binary.BigEndian.PutUint32(header[1:], uint32(key.Version))
+
+		return io.MultiReader(
+			bytes.NewReader(header),
+			aes.NewStreamEncrypter([]byte(key.Key), p.streamAD(subjectID, header), r),
+		), nil
+	})
+}
+
+// DecryptStream implements StreamProtector
+func (p *protector) DecryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader {
+	return p.lazyStream(subjectID, func() (io.Reader, error) {
+		header := make([]byte, streamHeaderSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, aes.ErrStreamTruncated
+			}
+			return nil, err
+		}
+		if header[0] != streamFormatVersion {
+			return nil, fmt.Errorf("unsupported stream format version %d", header[0])
+		}
+		keyVersion := int(binary.BigEndian.Uint32(header[1:]))
+
+		keys, err := p.getKeyVersions(ctx, []string{subjectID})
+		if err != nil {
+			return nil, err
+		}
+		versions, ok := keys[subjectID]
+		if !ok {
+			return nil, ErrSubjectForgotten.withSubject(subjectID)
+		}
+		key, ok := versions[keyVersion]
+		if !ok {
+			return nil, fmt.Errorf("key version %d not found", keyVersion)
+		}
+
+		return aes.NewStreamDecrypter([]byte(key), p.streamAD(subjectID, header), r), nil
+	})
+}
+
+// streamAD returns the additional data that binds an encrypted stream to its namespace, subject, and header.
+func (p *protector) streamAD(subjectID string, header []byte) []byte {
+	ad := fmt.Appendf(nil, "ns:%s|sub:%d:%s|hdr:", p.namespace, len(subjectID), subjectID)
+	return append(ad, header...)
+}
+
+// lazyStream returns a reader that calls the given function on the first read,
+// so that encryption materials are only fetched once the stream is consumed.
+// Errors are returned by the reader's Read method.
+func (p *protector) lazyStream(subjectID string, init func() (io.Reader, error)) io.Reader {
+	return &lazyReader{
+		init: init,
+		wrap: func(err error) error {
+			return ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace).withSubject(subjectID)
+		},
+	}
+}
+
+type lazyReader struct {
+	init func() (io.Reader, error)
+	wrap func(err error) error
+
+	r   io.Reader
+	err error
+}
+
+// Read implements io.Reader
+func (lr *lazyReader) Read(b []byte) (int, error) {
+	if lr.r == nil && lr.err == nil {
+		if lr.r, lr.err = lr.init(); lr.err != nil {
+			lr.err = lr.wrap(lr.err)
+		}
+	}
+	if lr.err != nil {
+		return 0, lr.err
+	}
+
+	n, err := lr.r.Read(b)
+	if err != nil && err != io.EOF {
+		lr.err = lr.wrap(err)
+		return n, lr.err
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+package privacy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ln80/privacy-engine/aes"
+	"github.com/ln80/privacy-engine/memory"
+)
+
+func TestProtector_Stream(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-str34m"
+
+	p := NewProtector(nspace, memory.NewKeyEngine())
+
+	subID := "kal5430"
+
+	blob := make([]byte, 3*aes.StreamChunkSize+42)
+	if _, err := rand.Read(blob); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	encrypted, err := io.ReadAll(p.(StreamProtector).EncryptStream(ctx, subID, bytes.NewReader(blob)))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if bytes.Contains(encrypted, blob[:64]) {
+		t.Fatal("expect content be encrypted")
+	}
+
+	decrypted, err := io.ReadAll(p.(StreamProtector).DecryptStream(ctx, subID, bytes.NewReader(encrypted)))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if !bytes.Equal(blob, decrypted) {
+		t.Fatal("expect decrypted content be equal to the original one")
+	}
+
+	// assert the stream is bound to its subject
+	_, err = io.ReadAll(p.(StreamProtector).DecryptStream(ctx, "kal5431", bytes.NewReader(encrypted)))
+	if !errors.Is(err, ErrEncryptDecryptFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrEncryptDecryptFailure, err)
+	}
+
+	// assert streams encrypted using a previous key version remain decryptable
+	if err := p.RotateKey(ctx, subID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	rotated, err := io.ReadAll(p.(StreamProtector).EncryptStream(ctx, subID, bytes.NewReader(blob)))
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := uint32(2), binary.BigEndian.Uint32(rotated[1:streamHeaderSize]); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	for _, enc := range [][]byte{encrypted, rotated} {
+		decrypted, err := io.ReadAll(p.(StreamProtector).DecryptStream(ctx, subID, bytes.NewReader(enc)))
+		if err != nil {
+			t.Fatalf("expect err be nil, got: %v", err)
+		}
+		if !bytes.Equal(blob, decrypted) {
+			t.Fatal("expect decrypted content be equal to the original one")
+		}
+	}
+
+	// assert the key version is authenticated
+	tampered := bytes.Clone(rotated)
+	binary.BigEndian.PutUint32(tampered[1:streamHeaderSize], 1)
+	if _, err := io.ReadAll(p.(StreamProtector).DecryptStream(ctx, subID, bytes.NewReader(tampered))); !errors.Is(err, aes.ErrStreamIntegrityCheck) {
+		t.Fatalf("expect err be %v, got %v", aes.ErrStreamIntegrityCheck, err)
+	}
+
+	// assert streams are crypto-erased along with the subject
+	if err := p.Forget(ctx, subID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if _, err := io.ReadAll(p.(StreamProtector).DecryptStream(ctx, subID, bytes.NewReader(encrypted))); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+	if _, err := io.ReadAll(p.(StreamProtector).EncryptStream(ctx, subID, bytes.NewReader(blob))); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+}
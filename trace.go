@@ -2,12 +2,18 @@ package privacy
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
+
+	"github.com/ln80/privacy-engine/core"
 )
 
 // traceable presents an internal Protector wrapper mainly used to trace last activity timestamp.
 // It's logic may involve in the future to fulfil audits and metrics collection requirements.
+//
+// It implements the optional interfaces of Protector services, and forwards their methods to the wrapped
+// Protector, which fail with core.ErrUnsupported error if the wrapped Protector doesn't implement them.
 type traceable struct {
 	Protector
 
@@ -16,6 +22,15 @@ type traceable struct {
 }
 
 var _ Protector = &traceable{}
+var _ Reencrypter = &traceable{}
+var _ NamespaceManager = &traceable{}
+var _ RetentionManager = &traceable{}
+var _ HoldManager = &traceable{}
+var _ BlindIndexer = &traceable{}
+var _ StreamProtector = &traceable{}
+var _ ValuesProtector = &traceable{}
+var _ JSONProtector = &traceable{}
+var _ PublicKeyPublisher = &traceable{}
 
 func (tp *traceable) markOp() {
 	tp.opsMu.Lock()
@@ -36,6 +51,98 @@ func (tp *traceable) Encrypt(ctx context.Context, structPts ...interface{}) erro
 	return tp.Protector.Encrypt(ctx, structPts...)
 }
 
+// Reencrypt implements Reencrypter
+func (tp *traceable) Reencrypt(ctx context.Context, structPts ...interface{}) error {
+	defer tp.markOp()
+	r, ok := tp.Protector.(Reencrypter)
+	if !ok {
+		return ErrEncryptDecryptFailure.withBase(core.ErrUnsupported)
+	}
+	return r.Reencrypt(ctx, structPts...)
+}
+
+// EncryptValues implements ValuesProtector
+func (tp *traceable) EncryptValues(ctx context.Context, subjectID string, values ...string) ([]string, error) {
+	defer tp.markOp()
+	vp, ok := tp.Protector.(ValuesProtector)
+	if !ok {
+		return nil, ErrEncryptDecryptFailure.withBase(core.ErrUnsupported).withSubject(subjectID)
+	}
+	return vp.EncryptValues(ctx, subjectID, values...)
+}
+
+// DecryptValues implements ValuesProtector
+func (tp *traceable) DecryptValues(ctx context.Context, wireValues ...string) ([]string, error) {
+	defer tp.markOp()
+	vp, ok := tp.Protector.(ValuesProtector)
+	if !ok {
+		return nil, ErrEncryptDecryptFailure.withBase(core.ErrUnsupported)
+	}
+	return vp.DecryptValues(ctx, wireValues...)
+}
+
+// EncryptJSON implements JSONProtector
+func (tp *traceable) EncryptJSON(ctx context.Context, doc []byte, spec JSONSpec) ([]byte, error) {
+	defer tp.markOp()
+	jp, ok := tp.Protector.(JSONProtector)
+	if !ok {
+		return nil, ErrEncryptDecryptFailure.withBase(core.ErrUnsupported)
+	}
+	return jp.EncryptJSON(ctx, doc, spec)
+}
+
+// DecryptJSON implements JSONProtector
+func (tp *traceable) DecryptJSON(ctx context.Context, doc []byte, spec JSONSpec) ([]byte, error) {
+	defer tp.markOp()
+	jp, ok := tp.Protector.(JSONProtector)
+	if !ok {
+		return nil, ErrEncryptDecryptFailure.withBase(core.ErrUnsupported)
+	}
+	return jp.DecryptJSON(ctx, doc, spec)
+}
+
+// DecryptFieldValues implements ValuesProtector
+func (tp *traceable) DecryptFieldValues(ctx context.Context, field string, wireValues ...string) ([]string, error) {
+	defer tp.markOp()
+	vp, ok := tp.Protector.(ValuesProtector)
+	if !ok {
+		return nil, ErrEncryptDecryptFailure.withBase(core.ErrUnsupported)
+	}
+	return vp.DecryptFieldValues(ctx, field, wireValues...)
+}
+
+// EncryptStream implements StreamProtector
+func (tp *traceable) EncryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader {
+	defer tp.markOp()
+	sp, ok := tp.Protector.(StreamProtector)
+	if !ok {
+		return unsupportedStream(subjectID)
+	}
+	return sp.EncryptStream(ctx, subjectID, r)
+}
+
+// DecryptStream implements StreamProtector
+func (tp *traceable) DecryptStream(ctx context.Context, subjectID string, r io.Reader) io.Reader {
+	defer tp.markOp()
+	sp, ok := tp.Protector.(StreamProtector)
+	if !ok {
+		return unsupportedStream(subjectID)
+	}
+	return sp.DecryptStream(ctx, subjectID, r)
+}
+
+// unsupportedStream returns a reader that fails with core.ErrUnsupported error, see lazyReader.
+func unsupportedStream(subjectID string) io.Reader {
+	return &lazyReader{
+		init: func() (io.Reader, error) {
+			return nil, core.ErrUnsupported
+		},
+		wrap: func(err error) error {
+			return ErrEncryptDecryptFailure.withBase(err).withSubject(subjectID)
+		},
+	}
+}
+
 // Forget implements Protector
 func (tp *traceable) Forget(ctx context.Context, subID string) error {
 	defer tp.markOp()
@@ -48,6 +155,93 @@ func (tp *traceable) Recover(ctx context.Context, subID string) error {
 	return tp.Protector.Recover(ctx, subID)
 }
 
+// RotateKey implements Protector
+func (tp *traceable) RotateKey(ctx context.Context, subID string) error {
+	defer tp.markOp()
+	return tp.Protector.RotateKey(ctx, subID)
+}
+
+// ForgetAt implements RetentionManager
+func (tp *traceable) ForgetAt(ctx context.Context, subID string, at time.Time) error {
+	defer tp.markOp()
+	rm, ok := tp.Protector.(RetentionManager)
+	if !ok {
+		return ErrForgetSubjectFailure.withBase(core.ErrUnsupported).withSubject(subID)
+	}
+	return rm.ForgetAt(ctx, subID, at)
+}
+
+// ForgetExpired implements RetentionManager
+//
+// It's not traced, as it's regularly called by Factory.Monitor.
+func (tp *traceable) ForgetExpired(ctx context.Context) ([]string, error) {
+	rm, ok := tp.Protector.(RetentionManager)
+	if !ok {
+		return nil, ErrForgetSubjectFailure.withBase(core.ErrUnsupported)
+	}
+	return rm.ForgetExpired(ctx)
+}
+
+// PlaceHold implements HoldManager
+func (tp *traceable) PlaceHold(ctx context.Context, subID, reason string) error {
+	defer tp.markOp()
+	hm, ok := tp.Protector.(HoldManager)
+	if !ok {
+		return ErrHoldSubjectFailure.withBase(core.ErrUnsupported).withSubject(subID)
+	}
+	return hm.PlaceHold(ctx, subID, reason)
+}
+
+// ReleaseHold implements HoldManager
+func (tp *traceable) ReleaseHold(ctx context.Context, subID string) error {
+	defer tp.markOp()
+	hm, ok := tp.Protector.(HoldManager)
+	if !ok {
+		return ErrHoldSubjectFailure.withBase(core.ErrUnsupported).withSubject(subID)
+	}
+	return hm.ReleaseHold(ctx, subID)
+}
+
+// ForgetNamespace implements NamespaceManager
+func (tp *traceable) ForgetNamespace(ctx context.Context) error {
+	defer tp.markOp()
+	nm, ok := tp.Protector.(NamespaceManager)
+	if !ok {
+		return ErrForgetNamespaceFailure.withBase(core.ErrUnsupported)
+	}
+	return nm.ForgetNamespace(ctx)
+}
+
+// RecoverNamespace implements NamespaceManager
+func (tp *traceable) RecoverNamespace(ctx context.Context) error {
+	defer tp.markOp()
+	nm, ok := tp.Protector.(NamespaceManager)
+	if !ok {
+		return ErrRecoverNamespaceFailure.withBase(core.ErrUnsupported)
+	}
+	return nm.RecoverNamespace(ctx)
+}
+
+// BlindIndex implements BlindIndexer
+func (tp *traceable) BlindIndex(ctx context.Context, value, normalize string) (string, error) {
+	defer tp.markOp()
+	bi, ok := tp.Protector.(BlindIndexer)
+	if !ok {
+		return "", ErrBlindIndexFailure.withBase(core.ErrUnsupported)
+	}
+	return bi.BlindIndex(ctx, value, normalize)
+}
+
+// PublishPublicKeys implements PublicKeyPublisher
+func (tp *traceable) PublishPublicKeys(ctx context.Context, subjectIDs ...string) error {
+	defer tp.markOp()
+	pp, ok := tp.Protector.(PublicKeyPublisher)
+	if !ok {
+		return ErrPublishPublicKeyFailure.withBase(core.ErrUnsupported)
+	}
+	return pp.PublishPublicKeys(ctx, subjectIDs...)
+}
+
 // Clear implements Protector
 // func (tp *traceable) Clear(ctx context.Context, force bool) error {
 // 	return tp.Protector.Clear(ctx, force)
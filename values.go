@@ -0,0 +1,142 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/ln80/privacy-engine/core"
+	sensitive "github.com/ln80/struct-sensitive"
+)
+
+var (
+	ErrFieldPathRequired = errors.New("field path required to decrypt field bound value")
+)
+
+// ValuesProtector is implemented by Protector services that encrypt and decrypt standalone values.
+type ValuesProtector interface {
+
+	// EncryptValues encrypts the given standalone values, e.g., an email in a query parameter, using the given subject's key,
+	// and returns their wire formats in the same order. Empty and already encrypted values are returned as is.
+	//
+	// Values are encrypted the same way as fields, except that they have no field path or tag options.
+	// Therefore, they are bound to their subject only if BindField is configured, and can be decrypted in any field.
+	// Similarly to Protector.Encrypt, failures are returned as FieldErrors error according to the configured ErrorPolicy,
+	// where the index of each failure is the value's position.
+	EncryptValues(ctx context.Context, subjectID string, values ...string) ([]string, error)
+
+	// DecryptValues decrypts the given wire formatted values, produced by either EncryptValues or Protector.Encrypt,
+	// and returns their plain texts in the same order. Values that are not wire formatted are returned as is,
+	// while values of forgotten subjects are returned empty.
+	//
+	// Values bound to their field, i.e., encrypted by Protector.Encrypt using BindField, fail with ErrFieldPathRequired error,
+	// see DecryptFieldValues.
+	// Similarly to Protector.Decrypt, failures are returned as FieldErrors error according to the configured ErrorPolicy.
+	DecryptValues(ctx context.Context, wireValues ...string) ([]string, error)
+
+	// DecryptFieldValues is similar to DecryptValues, and decrypts values bound to the given field path,
+	// e.g., "Addresses.Street", in addition to the values that are not bound to their field.
+	DecryptFieldValues(ctx context.Context, field string, wireValues ...string) ([]string, error)
+}
+
+var _ ValuesProtector = &protector{}
+
+// EncryptValues implements ValuesProtector
+func (p *protector) EncryptValues(ctx context.Context, subjectID string, values ...string) (encrypted []string, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.
+				withBase(err).
+				withNamespace(p.namespace).
+				withSubject(subjectID)
+		}
+	}()
+
+	if subjectID == "" {
+		return nil, errors.New("empty subject ID")
+	}
+	if len(values) == 0 {
+		return []string{}, nil
+	}
+
+	var keys core.VersionedKeyMap
+	if p.encryptOnly {
+		keys, err = p.getOrCreatePublicKeys(ctx, []string{subjectID})
+	} else {
+		keys, err = p.getOrCreateLatestKeys(ctx, []string{subjectID})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p.replaceValues(subjectID, "", values, p.encryptFunc(keys))
+}
+
+// DecryptValues implements ValuesProtector
+func (p *protector) DecryptValues(ctx context.Context, wireValues ...string) ([]string, error) {
+	return p.decryptValues(ctx, "", wireValues)
+}
+
+// DecryptFieldValues implements ValuesProtector
+func (p *protector) DecryptFieldValues(ctx context.Context, field string, wireValues ...string) ([]string, error) {
+	return p.decryptValues(ctx, field, wireValues)
+}
+
+func (p *protector) decryptValues(ctx context.Context, field string, wireValues []string) (decrypted []string, err error) {
+	defer func() {
+		if err != nil {
+			err = ErrEncryptDecryptFailure.withBase(err).withNamespace(p.namespace)
+		}
+	}()
+
+	if len(wireValues) == 0 {
+		return []string{}, nil
+	}
+
+	subjectIDs := make([]string, 0)
+	for _, val := range wireValues {
+		if _, subjectID, _, err := parseWireFormat(val); err == nil {
+			subjectIDs = append(subjectIDs, subjectID)
+		}
+	}
+	slices.Sort(subjectIDs)
+	subjectIDs = slices.Compact(subjectIDs)
+
+	keys, err := p.getKeyVersions(ctx, subjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.replaceValues("", field, wireValues, p.decryptFunc(keys))
+}
+
+// replaceValues replaces the given standalone values of the given field path, if any, using the given function
+// according to the ErrorPolicy, similarly to replaceAll. Values have no tag options, and empty ones are left untouched.
+//
+// It returns FieldErrors error in case of failure, along with the partial results if allowed by the policy.
+func (p *protector) replaceValues(subjectID, field string, values []string, fn sensitive.ReplaceFunc) ([]string, error) {
+	var ferrs FieldErrors
+	newValues := make([]string, len(values))
+	for idx, val := range values {
+		if val == "" {
+			continue
+		}
+		newVal, err := p.policyFunc(idx, fn, &ferrs)(sensitive.FieldReplace{
+			SubjectID: subjectID,
+			Name:      field,
+			Options:   sensitive.TagOptions{},
+		}, val)
+		if err != nil {
+			var ferr FieldError
+			if errors.As(err, &ferr) {
+				return nil, FieldErrors{ferr}
+			}
+			return nil, err
+		}
+		newValues[idx] = newVal
+	}
+	if len(ferrs) == 0 {
+		return newValues, nil
+	}
+	return newValues, ferrs
+}
@@ -0,0 +1,163 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ln80/privacy-engine/memory"
+)
+
+func TestProtector_Values(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-v4lu3s"
+
+	engine := memory.NewKeyEngine()
+
+	p := NewProtector(nspace, engine)
+
+	subID := "kal5430"
+
+	if _, err := p.(ValuesProtector).EncryptValues(ctx, "", "idir@example.com"); !errors.Is(err, ErrEncryptDecryptFailure) {
+		t.Fatalf("expect err be %v, got %v", ErrEncryptDecryptFailure, err)
+	}
+
+	values := []string{"idir@example.com", "", "Idir Moore"}
+	encrypted, err := p.(ValuesProtector).EncryptValues(ctx, subID, values...)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := len(values), len(encrypted); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if !isWireFormatted(encrypted[0]) || encrypted[1] != "" || !isWireFormatted(encrypted[2]) {
+		t.Fatalf("expect values be encrypted, got %v", encrypted)
+	}
+
+	// assert idempotency
+	reencrypted, err := p.(ValuesProtector).EncryptValues(ctx, subID, encrypted...)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := encrypted, reencrypted; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert values interoperate with struct fields, including values encrypted by encrypt-only Protectors
+	pf := Profile{UserID: subID, Fullname: "Idir Moore"}
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if err := p.(PublicKeyPublisher).PublishPublicKeys(ctx, subID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	published, err := NewEncryptOnlyProtector(nspace, engine).EncryptValues(ctx, subID, "Baker Street")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	decrypted, err := p.(ValuesProtector).DecryptValues(ctx, append(encrypted, pf.Fullname, published[0], "not encrypted")...)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := append(values, "Idir Moore", "Baker Street", "not encrypted"), decrypted; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	pf = Profile{UserID: subID, Fullname: encrypted[2], Address: Address{Street: published[0]}}
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := (Profile{UserID: subID, Fullname: "Idir Moore", Address: Address{Street: "Baker Street"}}), pf; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert failures are reported by value index
+	_, otherSubID, params, cipherText, err := parseWireFormatWithParams(encrypted[2])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	cipherText[len(cipherText)-1] ^= 1
+	tampered := wireFormatWithParams(otherSubID, params, cipherText)
+
+	_, err = p.(ValuesProtector).DecryptValues(ctx, encrypted[0], tampered)
+	var ferrs FieldErrors
+	if !errors.As(err, &ferrs) {
+		t.Fatalf("expect err be %T, got %v", ferrs, err)
+	}
+	if want, got := 1, ferrs[0].Index; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert values are crypto-erased along with the subject
+	if err := p.Forget(ctx, subID); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	decrypted, err = p.(ValuesProtector).DecryptValues(ctx, encrypted...)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []string{"", "", ""}, decrypted; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if _, err := p.(ValuesProtector).EncryptValues(ctx, subID, "idir@example.com"); !errors.Is(err, ErrSubjectForgotten) {
+		t.Fatalf("expect err be %v, got %v", ErrSubjectForgotten, err)
+	}
+}
+
+func TestProtector_Values_BindField(t *testing.T) {
+	ctx := context.Background()
+
+	nspace := "tenant-v4lu3s-b1nd"
+
+	p := NewProtector(nspace, memory.NewKeyEngine(), func(pc *ProtectorConfig) {
+		pc.Binding = BindField
+		pc.KeyDerivation = DeriveField
+	})
+
+	subID := "kal5430"
+
+	// assert standalone values are bound to their subject only, and are decryptable in struct fields
+	encrypted, err := p.(ValuesProtector).EncryptValues(ctx, subID, "Idir Moore")
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	_, _, params, _, err := parseWireFormatWithParams(encrypted[0])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := bindingSubject, params[paramBinding]; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	pf := Profile{UserID: subID, Fullname: encrypted[0]}
+	if err := p.Decrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := "Idir Moore", pf.Fullname; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert field bound values require their field path
+	pf = Profile{UserID: subID, Fullname: "Idir Moore", Address: Address{Street: "Baker Street"}}
+	if err := p.Encrypt(ctx, &pf); err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+
+	if _, err := p.(ValuesProtector).DecryptValues(ctx, pf.Address.Street); !errors.Is(err, ErrFieldPathRequired) {
+		t.Fatalf("expect err be %v, got %v", ErrFieldPathRequired, err)
+	}
+	if _, err := p.(ValuesProtector).DecryptFieldValues(ctx, "Fullname", pf.Address.Street); err == nil {
+		t.Fatal("expect err be not nil")
+	}
+
+	decrypted, err := p.(ValuesProtector).DecryptFieldValues(ctx, "Address.Street", pf.Address.Street, encrypted[0])
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := []string{"Baker Street", "Idir Moore"}, decrypted; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+}
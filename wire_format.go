@@ -3,7 +3,9 @@ package privacy
 import (
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -13,9 +15,54 @@ var (
 )
 
 var (
-	wireFormatRegex = regexp.MustCompile(`^<pii:\d*:[A-Za-z0-9+/]+={0,2}:[A-Za-z0-9+/]+={0,2}$`)
+	wireFormatRegex = regexp.MustCompile(`^<pii:\d*:[A-Za-z0-9+/]+={0,2}(:` + wireParamRegex + `(,` + wireParamRegex + `)*)?:[A-Za-z0-9+/]+={0,2}$`)
+
+	derivationLabelRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
 )
 
+const (
+	wireParamRegex = `[A-Za-z0-9._-]+=[A-Za-z0-9._-]*`
+
+	// wireFormatParamsVersion is the wire format version that carries parameters, e.g., the key version.
+	wireFormatParamsVersion = 2
+
+	// paramKeyVersion is the wire format parameter of the key version used to encrypt the value.
+	paramKeyVersion = "kv"
+
+	// paramAlgorithm is the wire format parameter of the identifier of the Encryptor used to encrypt the value.
+	paramAlgorithm = "alg"
+
+	// paramBinding is the wire format parameter of the additional data the value is bound to, see Binding.
+	paramBinding = "bd"
+
+	bindingSubject = "s"
+	bindingField   = "sf"
+
+	// paramDerivation is the wire format parameter of the label of the key derived from the subject key
+	// to encrypt the value, see KeyDerivation.
+	paramDerivation = "kd"
+
+	// paramPadding is the wire format parameter of the padding scheme of the plain text, see Padding.
+	paramPadding = "pd"
+)
+
+// wireParams presents the parameters of the wire format required to decrypt the value.
+type wireParams map[string]string
+
+func (wp wireParams) String() string {
+	keys := make([]string, 0, len(wp))
+	for k := range wp {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+wp[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
 func CheckFormat(str string) error {
 	if valid := isWireFormatted(str); !valid {
 		return ErrInvalidWireFormat
@@ -43,13 +90,37 @@ func wireFormat(subjectID string, cipherText []byte, version ...int) string {
 	return "<pii:" + v + ":" + base64SubjectID + ":" + base64CipherText
 }
 
+// wireFormatWithParams returns the wire format of the given cipher text along with its parameters.
+// It falls back to the first version of the wire format if there are no parameters.
+func wireFormatWithParams(subjectID string, params wireParams, cipherText []byte) string {
+	if len(params) == 0 {
+		return wireFormat(subjectID, cipherText)
+	}
+
+	base64SubjectID := base64.StdEncoding.EncodeToString([]byte(subjectID))
+	base64CipherText := base64.StdEncoding.EncodeToString(cipherText)
+
+	return "<pii:" + strconv.Itoa(wireFormatParamsVersion) + ":" + base64SubjectID + ":" + params.String() + ":" + base64CipherText
+}
+
 func parseWireFormat(str string) (version int, subjectID string, cipherText []byte, err error) {
+	version, subjectID, _, cipherText, err = parseWireFormatWithParams(str)
+	return
+}
+
+func parseWireFormatWithParams(str string) (version int, subjectID string, params wireParams, cipherText []byte, err error) {
 	if err = CheckFormat(str); err != nil {
-		// err = fmt.Errorf("%w: %s", err, str)
-		// err = fmt.Errorf("%w: %s", err, str)
 		return
 	}
-	parts := strings.SplitN(strings.TrimPrefix(str, "<pii:"), ":", 3)
+	parts := strings.Split(strings.TrimPrefix(str, "<pii:"), ":")
+	if len(parts) == 4 {
+		params = make(wireParams)
+		for _, pair := range strings.Split(parts[2], ",") {
+			k, v, _ := strings.Cut(pair, "=")
+			params[k] = v
+		}
+		parts = []string{parts[0], parts[1], parts[3]}
+	}
 
 	version = 1
 	if len(parts[0]) > 0 {
@@ -59,6 +130,10 @@ func parseWireFormat(str string) (version int, subjectID string, cipherText []by
 			return
 		}
 	}
+	if params != nil && version != wireFormatParamsVersion {
+		err = fmt.Errorf("%w: unexpected parameters for version %d", ErrInvalidWireFormat, version)
+		return
+	}
 	var subjectBytes []byte
 	subjectBytes, err = base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
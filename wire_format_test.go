@@ -2,6 +2,8 @@ package privacy
 
 import (
 	"encoding/base64"
+	"errors"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -84,3 +86,56 @@ func TestWireFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestWireFormat_Params(t *testing.T) {
+	cipher := []byte("cipher text")
+
+	str := wireFormatWithParams("abc", wireParams{paramKeyVersion: "3", "b": ""}, cipher)
+	if !isWireFormatted(str) {
+		t.Fatal("expect input be wire formatted", str)
+	}
+
+	version, subjectID, params, got, err := parseWireFormatWithParams(str)
+	if err != nil {
+		t.Fatalf("expect err be nil, got: %v", err)
+	}
+	if want, got := wireFormatParamsVersion, version; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := "abc", subjectID; want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := (wireParams{paramKeyVersion: "3", "b": ""}), params; !reflect.DeepEqual(want, got) {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+	if want, got := string(cipher), string(got); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	// assert empty params fall back to the legacy format
+	if want, got := wireFormat("abc", cipher), wireFormatWithParams("abc", nil, cipher); want != got {
+		t.Fatalf("expect %v, %v be equals", want, got)
+	}
+
+	for _, invalid := range []string{
+		"<pii:2:YWJj::Y2lwaGVy",
+		"<pii:2:YWJj:kv:Y2lwaGVy",
+		"<pii:2:YWJj:kv=2,:Y2lwaGVy",
+		"<pii:2:YWJj:kv=2:x:Y2lwaGVy",
+	} {
+		if isWireFormatted(invalid) {
+			t.Fatal("expect input not be wire formatted", invalid)
+		}
+	}
+
+	// assert params are only accepted by the params version
+	for _, invalid := range []string{
+		"<pii::YWJj:kv=2:Y2lwaGVy",
+		"<pii:1:YWJj:kv=2:Y2lwaGVy",
+		"<pii:3:YWJj:kv=2:Y2lwaGVy",
+	} {
+		if _, _, _, _, err := parseWireFormatWithParams(invalid); !errors.Is(err, ErrInvalidWireFormat) {
+			t.Fatalf("expect err be %v, got %v", ErrInvalidWireFormat, err)
+		}
+	}
+}